@@ -22,13 +22,28 @@ type Model struct {
 	Procedural   *modules.Procedural        // procedural is always present
 	Initializers []*Initializer
 	Productions  []*Production
+	Measures     []*Measure
 	LogLevel     ACTRLogLevel
+
+	// RandomSeed is used to seed the framework's random number generators so runs are
+	// reproducible. If it is nil, they are not seeded.
+	RandomSeed *uint32
+
+	// DumpState makes the frameworks output the contents of the buffers & declarative memory at
+	// the end of each run.
+	DumpState bool
+
+	// SourceFiles are the files other than the amod file the model was generated from (i.e. the
+	// data files loaded into memory) so they can be watched for changes.
+	SourceFiles []string
 }
 
 type Initializer struct {
 	Module         modules.ModuleInterface
 	Pattern        *Pattern
-	AMODLineNumber int // line number in the amod file of this initialization
+	ChunkName      string         // name of a chunk in memory so other chunks can refer to it (empty if not named)
+	Metadata       *ChunkMetadata // initial activation of a chunk in memory (nil if not set)
+	AMODLineNumber int            // line number in the amod file of this initialization
 }
 
 func (model *Model) Initialize() {
@@ -55,6 +70,13 @@ func (model *Model) Initialize() {
 	model.LogLevel = "info"
 }
 
+// WithDumpState returns a copy of the model which outputs its final state after each run.
+// Everything else is shared with the original model, so the copy must not be modified.
+func (model Model) WithDumpState() *Model {
+	model.DumpState = true
+	return &model
+}
+
 // LookupInitializer returns an initializer or nil if the buffer does not have one.
 func (model Model) LookupInitializer(buffer string) *Initializer {
 	for _, init := range model.Initializers {
@@ -66,6 +88,17 @@ func (model Model) LookupInitializer(buffer string) *Initializer {
 	return nil
 }
 
+// LookupNamedChunk returns the initializer of the named chunk in memory or nil if there isn't one.
+func (model Model) LookupNamedChunk(chunkName string) *Initializer {
+	for _, init := range model.Initializers {
+		if init.ChunkName == chunkName {
+			return init
+		}
+	}
+
+	return nil
+}
+
 // HasPrintStatement checks if this model uses the print statement.
 // This is used to include extra code to handle printing in some frameworks.
 func (model Model) HasPrintStatement() bool {
@@ -137,3 +170,14 @@ func (model Model) LookupBuffer(bufferName string) buffer.BufferInterface {
 
 	return nil
 }
+
+// LookupProduction looks up the named production in the model and returns it (or nil if it does not exist).
+func (model Model) LookupProduction(name string) *Production {
+	for _, production := range model.Productions {
+		if production.Name == name {
+			return production
+		}
+	}
+
+	return nil
+}
@@ -1,12 +1,21 @@
 package actr
 
-import "github.com/asmaloney/gactar/util/container"
+import (
+	"strings"
+
+	"github.com/asmaloney/gactar/util/container"
+)
 
 type Chunk struct {
 	Name      string
-	SlotNames []string
+	Parent    *Chunk   // chunk this one inherits its slots from (nil if it doesn't)
+	SlotNames []string // includes the parent's slots first
 	NumSlots  int
 
+	// Defaults are the values of slots used when they are left out of chunks created in the
+	// init section. They include the parent's defaults and are IDs, numbers, or nil.
+	Defaults map[string]*PatternSlotItem
+
 	AMODLineNumber int // line number in the amod file of the this chunk declaration
 }
 
@@ -25,6 +34,50 @@ func (model Model) LookupChunk(chunkName string) *Chunk {
 	return nil
 }
 
+// HasSubtypes checks if any chunks in the model inherit from this one.
+func (model Model) HasSubtypes(chunk *Chunk) bool {
+	return len(model.Subtypes(chunk)) > 1
+}
+
+// Subtypes returns the chunk followed by the chunks in the model which inherit from it (in the
+// order they were declared).
+func (model Model) Subtypes(chunk *Chunk) (chunks []*Chunk) {
+	chunks = []*Chunk{chunk}
+
+	for _, c := range model.Chunks {
+		if c != chunk && c.IsA(chunk) {
+			chunks = append(chunks, c)
+		}
+	}
+
+	return
+}
+
+// IsA checks if this chunk is the other chunk or inherits from it.
+func (c *Chunk) IsA(other *Chunk) bool {
+	for chunk := c; chunk != nil; chunk = chunk.Parent {
+		if chunk == other {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OwnSlotNames returns the names of the slots declared by this chunk (i.e. not inherited).
+func (c Chunk) OwnSlotNames() []string {
+	if c.Parent == nil {
+		return c.SlotNames
+	}
+
+	return c.SlotNames[c.Parent.NumSlots:]
+}
+
+// DefaultValue returns the default value of the slot (or nil if it doesn't have one).
+func (c Chunk) DefaultValue(slot string) *PatternSlotItem {
+	return c.Defaults[slot]
+}
+
 // SlotName returns the name of the slot given the index.
 func (c Chunk) SlotName(index int) (str string) {
 	return c.SlotNames[index]
@@ -43,3 +96,27 @@ func (chunk Chunk) HasSlot(slot string) bool {
 func (chunk Chunk) SlotIndex(slot string) int {
 	return container.GetIndex1(slot, chunk.SlotNames)
 }
+
+// String returns the chunk declaration in amod format.
+// e.g. [count: first second] or [digit(number): name status=new]
+func (c Chunk) String() string {
+	name := c.Name
+	if c.Parent != nil {
+		name += "(" + c.Parent.Name + ")"
+	}
+
+	slots := []string{}
+	for _, slot := range c.OwnSlotNames() {
+		if value := c.DefaultValue(slot); value != nil {
+			slot += "=" + value.String()
+		}
+
+		slots = append(slots, slot)
+	}
+
+	if len(slots) == 0 {
+		return "[" + name + "]"
+	}
+
+	return "[" + name + ": " + strings.Join(slots, " ") + "]"
+}
@@ -0,0 +1,59 @@
+package actr
+
+import (
+	"github.com/asmaloney/gactar/actr/buffer"
+)
+
+// MeasureKind is the kind of value a measure collects.
+type MeasureKind string
+
+const (
+	MeasureTimeOf     MeasureKind = "time_of"    // time a production first fired
+	MeasureCountOf    MeasureKind = "count_of"   // number of times a production fired
+	MeasureRetrievals MeasureKind = "retrievals" // number of retrieval requests
+	MeasureSlotAtEnd  MeasureKind = "slot"       // value of a buffer's slot at the end of the run
+)
+
+// Measure is a value collected from each run of the model. The frameworks collect them by adding
+// code to the productions and after the run.
+type Measure struct {
+	Name string
+	Kind MeasureKind
+
+	Production *Production // production to time or count (time_of & count_of)
+
+	Buffer   buffer.BufferInterface // buffer & slot to output at the end of the run (slot)
+	SlotName string
+
+	AMODLineNumber int // line number in the amod file of this measure
+}
+
+// MeasuresFiredBy returns the measures which record each firing of the production.
+func (model Model) MeasuresFiredBy(production *Production) (measures []*Measure) {
+	for _, measure := range model.Measures {
+		switch measure.Kind {
+		case MeasureTimeOf, MeasureCountOf:
+			if measure.Production == production {
+				measures = append(measures, measure)
+			}
+
+		case MeasureRetrievals:
+			if production.HasRecall() {
+				measures = append(measures, measure)
+			}
+		}
+	}
+
+	return
+}
+
+// SlotMeasures returns the measures which are output at the end of the run.
+func (model Model) SlotMeasures() (measures []*Measure) {
+	for _, measure := range model.Measures {
+		if measure.Kind == MeasureSlotAtEnd {
+			measures = append(measures, measure)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,103 @@
+package actr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+// Names of the chunk metadata which may be set on chunks in memory.
+const (
+	MetadataBaseLevel  = "base_level"
+	MetadataReferences = "references"
+	MetadataCreated    = "created"
+)
+
+// MetadataNames lists the names of the chunk metadata in the order they are output.
+var MetadataNames = []string{MetadataBaseLevel, MetadataReferences, MetadataCreated}
+
+var (
+	ErrUnrecognizedMetadata = errors.New("unrecognized")
+	ErrReferencesInvalid    = errors.New("must be a whole number greater than 0")
+	ErrCreatedInvalid       = errors.New("must not be after the start of the run (0)")
+)
+
+// ChunkMetadata sets the initial activation of a chunk in memory so facts may start with
+// different strengths.
+type ChunkMetadata struct {
+	BaseLevel  *float64 // fixed base-level activation
+	References *int     // number of times the chunk was used before the run
+	Created    *float64 // time the chunk was created relative to the start of the run (0 or less)
+}
+
+// IsMetadataName checks if the name is one of the chunk metadata.
+func IsMetadataName(name string) bool {
+	for _, metadataName := range MetadataNames {
+		if name == metadataName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Set sets one of the metadata values by name.
+func (m *ChunkMetadata) Set(name string, value float64) (err error) {
+	switch name {
+	case MetadataBaseLevel:
+		m.BaseLevel = &value
+
+	case MetadataReferences:
+		if value < 1 || value != math.Trunc(value) {
+			return ErrReferencesInvalid
+		}
+
+		references := int(value)
+		m.References = &references
+
+	case MetadataCreated:
+		if value > 0 {
+			return ErrCreatedInvalid
+		}
+
+		m.Created = &value
+
+	default:
+		return ErrUnrecognizedMetadata
+	}
+
+	return
+}
+
+// String returns the metadata as it is written in amod (e.g. "{ base_level: 0.5 }").
+func (m ChunkMetadata) String() string {
+	fields := []string{}
+
+	if m.BaseLevel != nil {
+		fields = append(fields, fmt.Sprintf("%s: %s", MetadataBaseLevel, numbers.Float64Str(*m.BaseLevel)))
+	}
+
+	if m.References != nil {
+		fields = append(fields, fmt.Sprintf("%s: %d", MetadataReferences, *m.References))
+	}
+
+	if m.Created != nil {
+		fields = append(fields, fmt.Sprintf("%s: %s", MetadataCreated, numbers.Float64Str(*m.Created)))
+	}
+
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// HasMetadata checks if any of the model's initializers set chunk metadata.
+func (model Model) HasMetadata() bool {
+	for _, init := range model.Initializers {
+		if init.Metadata != nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -66,6 +66,48 @@ func (d DeclarativeMemory) ModuleName() string {
 	return "memory"
 }
 
+// Params returns info about each of the parameters for this module.
+func (d DeclarativeMemory) Params() []ParamInfo {
+	return []ParamInfo{
+		{
+			Name:        "latency_factor",
+			Description: "latency factor (F)",
+			Value:       floatParamValue(d.LatencyFactor),
+			Defaults:    map[string]string{"ccm": "0.05", "pyactr": "0.1", "vanilla": "1.0"},
+		},
+		{
+			Name:        "latency_exponent",
+			Description: "latency exponent (f)",
+			Value:       floatParamValue(d.LatencyExponent),
+			Defaults:    map[string]string{"ccm": "", "pyactr": "1.0", "vanilla": "1.0"},
+		},
+		{
+			Name:        "retrieval_threshold",
+			Description: "retrieval threshold (τ)",
+			Value:       floatParamValue(d.RetrievalThreshold),
+			Defaults:    map[string]string{"ccm": "0.0", "pyactr": "0.0", "vanilla": "0.0"},
+		},
+		{
+			Name:        "finst_size",
+			Description: "how many chunks are retained in memory",
+			Value:       intParamValue(d.FinstSize),
+			Defaults:    map[string]string{"ccm": "4", "pyactr": "0", "vanilla": "4"},
+		},
+		{
+			Name:        "finst_time",
+			Description: "how long the finst lasts in memory",
+			Value:       floatParamValue(d.FinstTime),
+			Defaults:    map[string]string{"ccm": "3.0", "pyactr": "", "vanilla": "3.0"},
+		},
+		{
+			Name:        "max_spread_strength",
+			Description: "turns on spreading activation & sets the maximum associative strength",
+			Value:       floatParamValue(d.MaxSpreadStrength),
+			Defaults:    map[string]string{"ccm": "off", "pyactr": "off", "vanilla": "off"},
+		},
+	}
+}
+
 func (d *DeclarativeMemory) SetParam(param *Param) (err ParamError) {
 	value := param.Value
 
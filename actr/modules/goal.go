@@ -23,6 +23,18 @@ func (g Goal) ModuleName() string {
 	return "goal"
 }
 
+// Params returns info about each of the parameters for this module.
+func (g Goal) Params() []ParamInfo {
+	return []ParamInfo{
+		{
+			Name:        "spreading_activation",
+			Description: "spreading activation weight of the goal buffer",
+			Value:       floatParamValue(g.SpreadingActivation),
+			Defaults:    map[string]string{"ccm": "1.0", "pyactr": "1.0", "vanilla": "1.0"},
+		},
+	}
+}
+
 func (g *Goal) SetParam(param *Param) (err ParamError) {
 	value := param.Value
 
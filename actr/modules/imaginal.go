@@ -23,6 +23,18 @@ func (i Imaginal) ModuleName() string {
 	return "imaginal"
 }
 
+// Params returns info about each of the parameters for this module.
+func (i Imaginal) Params() []ParamInfo {
+	return []ParamInfo{
+		{
+			Name:        "delay",
+			Description: "how long it takes a request to the buffer to complete (seconds)",
+			Value:       floatParamValue(i.Delay),
+			Defaults:    map[string]string{"ccm": "0.2", "pyactr": "0.2", "vanilla": "0.2"},
+		},
+	}
+}
+
 func (i *Imaginal) SetParam(param *Param) (err ParamError) {
 	value := param.Value
 
@@ -1,7 +1,13 @@
 // Package modules implements several ACT-R modules.
 package modules
 
-import "github.com/asmaloney/gactar/actr/buffer"
+import (
+	"strconv"
+
+	"github.com/asmaloney/gactar/actr/buffer"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
 
 // Value mimics amod.fieldValue but without tokens.
 type Value struct {
@@ -15,6 +21,14 @@ type Param struct {
 	Value Value
 }
 
+// ParamInfo describes one of the parameters a module accepts.
+type ParamInfo struct {
+	Name        string
+	Description string
+	Value       string            // current value ("" if it has not been set)
+	Defaults    map[string]string // default value for each framework ("" if the framework does not support it)
+}
+
 type ParamError = int
 
 const (
@@ -32,4 +46,21 @@ type ModuleInterface interface {
 	ModuleName() string
 
 	SetParam(param *Param) (err ParamError)
+	Params() []ParamInfo
+}
+
+func floatParamValue(f *float64) string {
+	if f == nil {
+		return ""
+	}
+
+	return numbers.Float64Str(*f)
+}
+
+func intParamValue(i *int) string {
+	if i == nil {
+		return ""
+	}
+
+	return strconv.Itoa(*i)
 }
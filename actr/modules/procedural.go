@@ -20,6 +20,18 @@ func (Procedural) ModuleName() string {
 	return "procedural"
 }
 
+// Params returns info about each of the parameters for this module.
+func (p Procedural) Params() []ParamInfo {
+	return []ParamInfo{
+		{
+			Name:        "default_action_time",
+			Description: "time that it takes to fire a production (seconds)",
+			Value:       floatParamValue(p.DefaultActionTime),
+			Defaults:    map[string]string{"ccm": "0.05", "pyactr": "0.05", "vanilla": "0.05"},
+		},
+	}
+}
+
 func (p *Procedural) SetParam(param *Param) (err ParamError) {
 	value := param.Value
 
@@ -19,27 +19,36 @@ type PatternSlotItem struct {
 	ID       *string
 	Var      *string
 	Num      *string // we don't need to treat this as a number anywhere, so keep as a string
+	ChunkRef *string // name of a chunk in memory
 
 	Negated bool // this item is negated
 }
 
+func (item PatternSlotItem) String() (str string) {
+	if item.Negated {
+		str += "!"
+	}
+
+	if item.Wildcard {
+		str += "*"
+	} else if item.Nil {
+		str += "nil"
+	} else if item.ID != nil {
+		str += *item.ID
+	} else if item.Var != nil {
+		str += *item.Var
+	} else if item.Num != nil {
+		str += *item.Num
+	} else if item.ChunkRef != nil {
+		str += "&" + *item.ChunkRef
+	}
+
+	return
+}
+
 func (p PatternSlot) String() (str string) {
 	for _, item := range p.Items {
-		if item.Negated {
-			str += "!"
-		}
-
-		if item.Wildcard {
-			str += "*"
-		} else if item.Nil {
-			str += "nil"
-		} else if item.ID != nil {
-			str += *item.ID
-		} else if item.Var != nil {
-			str += *item.Var
-		} else if item.Num != nil {
-			str += *item.Num
-		}
+		str += item.String()
 	}
 
 	return
@@ -66,6 +75,26 @@ func (p Pattern) String() (str string) {
 	return
 }
 
+// AsSubtype returns a copy of the pattern which matches chunks of a subtype of the pattern's chunk
+// instead. A subtype's slots start with its parent's, so the slots it adds match anything. It
+// returns nil if the chunk isn't a subtype of the pattern's chunk.
+func (p Pattern) AsSubtype(chunk *Chunk) *Pattern {
+	if !chunk.IsA(p.Chunk) {
+		return nil
+	}
+
+	subtype := &Pattern{
+		Chunk: chunk,
+		Slots: append([]*PatternSlot{}, p.Slots...),
+	}
+
+	for len(subtype.Slots) < chunk.NumSlots {
+		subtype.AddSlot(&PatternSlot{Items: []*PatternSlotItem{{Wildcard: true}}})
+	}
+
+	return subtype
+}
+
 func (p *Pattern) AddSlot(slot *PatternSlot) {
 	p.Slots = append(p.Slots, slot)
 }
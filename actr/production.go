@@ -1,6 +1,11 @@
 package actr
 
-import "github.com/asmaloney/gactar/actr/buffer"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr/buffer"
+)
 
 // Production stores information on how to match buffers and perform some operations.
 // It uses a small language to modify states upon successful matches.
@@ -85,6 +90,17 @@ type SetStatement struct {
 	Pattern *Pattern // (2) pattern if we are setting the whole buffer
 }
 
+// HasRecall checks if the production makes a retrieval request.
+func (p Production) HasRecall() bool {
+	for _, statement := range p.DoStatements {
+		if statement.Recall != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p Production) LookupMatchByBuffer(bufferName string) *Match {
 	for _, m := range p.Matches {
 		if m.Buffer.BufferName() == bufferName {
@@ -147,3 +163,109 @@ func (s *SetStatement) AddSlot(slot *SetSlot) {
 
 	*s.Slots = append(*s.Slots, *slot)
 }
+
+// String returns the production in amod format.
+func (p Production) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s {\n", p.Name)
+
+	if p.Description != nil {
+		fmt.Fprintf(&b, "    description: '%s'\n", *p.Description)
+	}
+
+	b.WriteString("    match {\n")
+	for _, match := range p.Matches {
+		fmt.Fprintf(&b, "        %s\n", match)
+	}
+	b.WriteString("    }\n")
+
+	b.WriteString("    do {\n")
+	for _, statement := range p.DoStatements {
+		for _, line := range strings.Split(statement.String(), "\n") {
+			fmt.Fprintf(&b, "        %s\n", line)
+		}
+	}
+	b.WriteString("    }\n")
+
+	b.WriteString("}")
+
+	return b.String()
+}
+
+// String returns the match in amod format.
+func (m Match) String() string {
+	name := ""
+	if m.Buffer != nil {
+		name = m.Buffer.BufferName()
+	}
+
+	return fmt.Sprintf("%s %s", name, m.Pattern)
+}
+
+// String returns the statement in amod format. Consecutive "set" statements on the same buffer
+// are combined when parsing, so this may return more than one line.
+func (s Statement) String() string {
+	switch {
+	case s.Clear != nil:
+		return "clear " + strings.Join(s.Clear.BufferNames, ", ")
+
+	case s.Print != nil:
+		values := []string{}
+		if s.Print.Values != nil {
+			for _, v := range *s.Print.Values {
+				values = append(values, v.String())
+			}
+		}
+		return "print " + strings.Join(values, ", ")
+
+	case s.Recall != nil:
+		return "recall " + s.Recall.Pattern.String()
+
+	case s.Set != nil:
+		bufferName := s.Set.Buffer.BufferName()
+
+		if s.Set.Slots == nil {
+			return fmt.Sprintf("set %s to %s", bufferName, s.Set.Pattern)
+		}
+
+		lines := []string{}
+		for _, slot := range *s.Set.Slots {
+			lines = append(lines, fmt.Sprintf("set %s.%s to %s", bufferName, slot.Name, slot.Value))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	return ""
+}
+
+func (v Value) String() string {
+	switch {
+	case v.Var != nil:
+		return *v.Var
+	case v.ID != nil:
+		return *v.ID
+	case v.Str != nil:
+		return "'" + *v.Str + "'"
+	case v.Number != nil:
+		return *v.Number
+	}
+
+	return ""
+}
+
+func (v SetValue) String() string {
+	switch {
+	case v.Nil:
+		return "nil"
+	case v.Var != nil:
+		// the '?' is removed when parsing
+		return "?" + *v.Var
+	case v.Number != nil:
+		return *v.Number
+	case v.Str != nil:
+		return "'" + *v.Str + "'"
+	}
+
+	return ""
+}
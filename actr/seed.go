@@ -0,0 +1,27 @@
+package actr
+
+// WithRandomSeed returns a copy of the model which uses the given random seed. Everything else is
+// shared with the original model, so the copy must not be modified.
+func (model Model) WithRandomSeed(seed uint32) *Model {
+	model.RandomSeed = &seed
+	return &model
+}
+
+// DeriveSeed returns the seed for one of a batch of runs (numbered from 1) using a base seed.
+// The first run uses the base seed itself so a batch of one is the same as a single run. The
+// others are mixed so batches using nearby base seeds don't share runs.
+func DeriveSeed(base uint32, run int) uint32 {
+	if run <= 1 {
+		return base
+	}
+
+	// This is the finalizer from MurmurHash3.
+	x := base + uint32(run-1)*0x9e3779b9
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+
+	return x
+}
@@ -3,6 +3,9 @@ package amod
 import (
 	"errors"
 	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/alecthomas/participle/v2"
@@ -11,6 +14,7 @@ import (
 	"github.com/asmaloney/gactar/actr/modules"
 
 	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/numbers"
 )
 
 var debugging bool = false
@@ -38,8 +42,15 @@ func OutputEBNF() {
 	fmt.Println(amodParser.String())
 }
 
-// GenerateModel generates a model from the text in the buffer.
+// GenerateModel generates a model from the text in the buffer. Since it isn't from a file, the
+// model may not load data files.
 func GenerateModel(buffer string) (model *actr.Model, iLog *issues.Log, err error) {
+	return GenerateModelInDir(buffer, "")
+}
+
+// GenerateModelInDir generates a model from the text in the buffer. Data files the model loads
+// are relative to dir (the directory of the amod file the text was read from).
+func GenerateModelInDir(buffer, dir string) (model *actr.Model, iLog *issues.Log, err error) {
 	r := strings.NewReader(buffer)
 
 	log := newLog()
@@ -63,7 +74,7 @@ func GenerateModel(buffer string) (model *actr.Model, iLog *issues.Log, err erro
 		return
 	}
 
-	model, err = generateModel(amod, log)
+	model, err = generateModel(amod, log, dir)
 	return
 }
 
@@ -90,7 +101,35 @@ func GenerateModelFromFile(fileName string) (model *actr.Model, iLog *issues.Log
 		return
 	}
 
-	model, err = generateModel(amod, log)
+	model, err = generateModel(amod, log, filepath.Dir(fileName))
+	return
+}
+
+// SetParam sets a module's parameter using the same validation as the config section.
+// This is used to change parameters after a model has been generated (e.g. from the shell).
+// The value may be a number, a string in single quotes, or an identifier.
+func SetParam(model *actr.Model, moduleName, key, value string) (err error) {
+	module := model.LookupModule(moduleName)
+	if module == nil {
+		err = fmt.Errorf("module '%s' not found in model '%s'", moduleName, model.Name)
+		return
+	}
+
+	param := modules.Param{Key: key}
+
+	if number, parseErr := strconv.ParseFloat(value, 64); parseErr == nil {
+		param.Value.Number = &number
+	} else if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		str := value[1 : len(value)-1]
+		param.Value.Str = &str
+	} else {
+		param.Value.ID = &value
+	}
+
+	if msg := paramErrorString(module.SetParam(&param), moduleName, key, value); msg != "" {
+		err = errors.New(msg)
+	}
+
 	return
 }
 
@@ -124,6 +163,8 @@ func ParseChunk(model *actr.Model, chunk string) (*actr.Pattern, error) {
 		return nil, err
 	}
 
+	addDefaultSlots(model, &p)
+
 	err = validatePattern(model, log, &p)
 	if err != nil {
 		err = errors.New(log.FirstEntry())
@@ -133,8 +174,9 @@ func ParseChunk(model *actr.Model, chunk string) (*actr.Pattern, error) {
 	return createChunkPattern(model, log, &p)
 }
 
-// generateModel runs through the parsed structures and creates an actr.Model from them
-func generateModel(amod *amodFile, log *issueLog) (model *actr.Model, err error) {
+// generateModel runs through the parsed structures and creates an actr.Model from them. Data files
+// are loaded relative to dir (if it is empty, they may not be loaded).
+func generateModel(amod *amodFile, log *issueLog, dir string) (model *actr.Model, err error) {
 	model = &actr.Model{
 		Name:        amod.Model.Name,
 		Description: amod.Model.Description,
@@ -144,10 +186,17 @@ func generateModel(amod *amodFile, log *issueLog) (model *actr.Model, err error)
 	model.Initialize()
 
 	addConfig(model, log, amod.Config)
+
+	// Examples may refer to named chunks, so add them after the initializers.
+	addInit(model, log, amod.Init, dir)
 	addExamples(model, log, amod.Model.Examples)
-	addInit(model, log, amod.Init)
 	addProductions(model, log, amod.Productions)
 
+	// Measures refer to productions, so add them last.
+	if amod.Config != nil {
+		addMeasures(model, log, amod.Config.Measures)
+	}
+
 	if log.HasError() {
 		return nil, CompileError{}
 	}
@@ -202,6 +251,15 @@ func addGACTAR(model *actr.Model, log *issueLog, list []*field) {
 
 			model.LogLevel = actr.ACTRLogLevel(*value.Str)
 
+		case "random_seed":
+			if (value.Number == nil) || (*value.Number < 0) || (*value.Number > math.MaxUint32) || (*value.Number != math.Trunc(*value.Number)) {
+				log.errorT(value.Tokens, "random_seed '%s' must be a whole number from 0 to %d", value.String(), uint32(math.MaxUint32))
+				continue
+			}
+
+			seed := uint32(*value.Number)
+			model.RandomSeed = &seed
+
 		default:
 			log.errorTR(field.Tokens, 0, 1, "unrecognized field in gactar section: '%s'", field.Key)
 		}
@@ -249,21 +307,33 @@ func setModuleParams(module modules.ModuleInterface, log *issueLog, fields []*fi
 		})
 
 		switch err {
-		case modules.NumberRequired:
-			log.errorT(value.Tokens, "%s %s '%s' must be a number", moduleName, field.Key, value.String())
-			continue
-
-		case modules.NumberMustBePositive:
-			log.errorT(value.Tokens, "%s %s '%s' must be a positive number", moduleName, field.Key, value.String())
+		case modules.NumberRequired, modules.NumberMustBePositive:
+			log.errorT(value.Tokens, "%s", paramErrorString(err, moduleName, field.Key, value.String()))
 			continue
 
 		case modules.UnrecognizedParam:
-			log.errorTR(field.Tokens, 0, 1, "unrecognized field '%s' in %s config", field.Key, moduleName)
+			log.errorTR(field.Tokens, 0, 1, "%s", paramErrorString(err, moduleName, field.Key, value.String()))
 			continue
 		}
 	}
 }
 
+// paramErrorString returns the message for an error from a module's SetParam().
+func paramErrorString(err modules.ParamError, moduleName, key, value string) string {
+	switch err {
+	case modules.NumberRequired:
+		return fmt.Sprintf("%s %s '%s' must be a number", moduleName, key, value)
+
+	case modules.NumberMustBePositive:
+		return fmt.Sprintf("%s %s '%s' must be a positive number", moduleName, key, value)
+
+	case modules.UnrecognizedParam:
+		return fmt.Sprintf("unrecognized field '%s' in %s config", key, moduleName)
+	}
+
+	return ""
+}
+
 func addGoal(model *actr.Model, log *issueLog, fields []*field) {
 	setModuleParams(model.Goal, log, fields)
 }
@@ -293,15 +363,33 @@ func addChunks(model *actr.Model, log *issueLog, chunks []*chunkDecl) {
 			continue
 		}
 
+		var parent *actr.Chunk
 		slotNames := []string{}
+		defaults := map[string]*actr.PatternSlotItem{}
+
+		if chunk.Parent != nil {
+			parent = model.LookupChunk(*chunk.Parent)
+			slotNames = append(slotNames, parent.SlotNames...)
+
+			for slot, value := range parent.Defaults {
+				defaults[slot] = value
+			}
+		}
+
 		for _, slot := range chunk.Slots {
 			slotNames = append(slotNames, slot.Slot)
+
+			if slot.Default != nil {
+				defaults[slot.Slot] = createDefaultValue(slot.Default)
+			}
 		}
 
 		aChunk := actr.Chunk{
 			Name:           chunk.Name,
+			Parent:         parent,
 			SlotNames:      slotNames,
-			NumSlots:       len(chunk.Slots),
+			NumSlots:       len(slotNames),
+			Defaults:       defaults,
 			AMODLineNumber: chunk.Tokens[0].Pos.Line,
 		}
 
@@ -309,12 +397,53 @@ func addChunks(model *actr.Model, log *issueLog, chunks []*chunkDecl) {
 	}
 }
 
-func addInit(model *actr.Model, log *issueLog, init *initSection) {
+// createDefaultValue creates the default value of a slot from its declaration.
+func createDefaultValue(value *chunkSlotDefault) *actr.PatternSlotItem {
+	return &actr.PatternSlotItem{
+		Nil: value.Nil != nil,
+		ID:  value.ID,
+		Num: value.Num,
+	}
+}
+
+// addDefaultSlots adds the default values of the slots left out at the end of a pattern which
+// creates a chunk. If the chunk doesn't exist or the slots don't have defaults, the pattern is
+// left alone so validation can report it.
+func addDefaultSlots(model *actr.Model, p *pattern) {
+	chunk := model.LookupChunk(p.ChunkName)
+	if chunk == nil || len(p.Slots) >= chunk.NumSlots {
+		return
+	}
+
+	for _, slot := range chunk.SlotNames[len(p.Slots):] {
+		if chunk.DefaultValue(slot) == nil {
+			return
+		}
+	}
+
+	for _, slot := range chunk.SlotNames[len(p.Slots):] {
+		value := chunk.DefaultValue(slot)
+
+		item := &patternSlotItem{ID: value.ID, Num: value.Num}
+		if value.Nil {
+			isNil := true
+			item.Nil = &isNil
+		}
+
+		p.Slots = append(p.Slots, &patternSlot{Items: []*patternSlotItem{item}})
+	}
+}
+
+func addInit(model *actr.Model, log *issueLog, init *initSection, dir string) {
 	if init == nil {
 		return
 	}
 
 	for _, initialization := range init.Initializations {
+		for _, init := range initialization.InitPatterns {
+			addDefaultSlots(model, init.Pattern)
+		}
+
 		err := validateInitialization(model, log, initialization)
 		if err != nil {
 			continue
@@ -323,15 +452,37 @@ func addInit(model *actr.Model, log *issueLog, init *initSection) {
 		name := initialization.Name
 		moduleInterface := model.LookupModule(name)
 
+		if initialization.DataFile != nil {
+			addDataFile(model, log, moduleInterface, initialization.DataFile, dir)
+			continue
+		}
+
 		for _, init := range initialization.InitPatterns {
-			pattern, err := createChunkPattern(model, log, init)
+			pattern, err := createChunkPattern(model, log, init.Pattern)
+			if err != nil {
+				continue
+			}
+
+			metadata, err := createChunkMetadata(log, init.Metadata)
 			if err != nil {
 				continue
 			}
 
+			chunkName := ""
+			if init.ChunkName != nil {
+				err = validateChunkName(model, log, moduleInterface, init)
+				if err != nil {
+					continue
+				}
+
+				chunkName = *init.ChunkName
+			}
+
 			init := actr.Initializer{
 				Module:         moduleInterface,
 				Pattern:        pattern,
+				ChunkName:      chunkName,
+				Metadata:       metadata,
 				AMODLineNumber: init.Tokens[0].Pos.Line,
 			}
 
@@ -340,6 +491,40 @@ func addInit(model *actr.Model, log *issueLog, init *initSection) {
 	}
 }
 
+// createChunkMetadata creates the metadata for a chunk in memory from its fields. It returns nil
+// if there aren't any.
+func createChunkMetadata(log *issueLog, fields []*field) (metadata *actr.ChunkMetadata, err error) {
+	if len(fields) == 0 {
+		return
+	}
+
+	metadata = &actr.ChunkMetadata{}
+
+	for _, field := range fields {
+		value := field.Value
+
+		if !actr.IsMetadataName(field.Key) {
+			log.errorTR(field.Tokens, 0, 1, "unrecognized chunk metadata '%s' (should be %s)", field.Key, strings.Join(actr.MetadataNames, ", "))
+			err = CompileError{}
+			continue
+		}
+
+		if value.Number == nil {
+			log.errorT(value.Tokens, "chunk metadata %s '%s' must be a number", field.Key, value.String())
+			err = CompileError{}
+			continue
+		}
+
+		setErr := metadata.Set(field.Key, *value.Number)
+		if setErr != nil {
+			log.errorT(value.Tokens, "chunk metadata %s '%s' %s", field.Key, numbers.Float64Str(*value.Number), setErr)
+			err = CompileError{}
+		}
+	}
+
+	return
+}
+
 func addProductions(model *actr.Model, log *issueLog, productions *productionSection) {
 	if productions == nil {
 		return
@@ -406,6 +591,40 @@ func addProductions(model *actr.Model, log *issueLog, productions *productionSec
 	}
 }
 
+func addMeasures(model *actr.Model, log *issueLog, measures []*measure) {
+	if measures == nil {
+		return
+	}
+
+	for _, m := range measures {
+		err := validateMeasure(model, log, m)
+		if err != nil {
+			continue
+		}
+
+		aMeasure := actr.Measure{
+			Name:           m.Name,
+			AMODLineNumber: m.Tokens[0].Pos.Line,
+		}
+
+		switch {
+		case m.Arg != nil:
+			aMeasure.Kind = actr.MeasureKind(m.Function)
+			aMeasure.Production = model.LookupProduction(*m.Arg)
+
+		case m.Slot != nil:
+			aMeasure.Kind = actr.MeasureSlotAtEnd
+			aMeasure.Buffer = model.LookupBuffer(m.Function)
+			aMeasure.SlotName = *m.Slot
+
+		default:
+			aMeasure.Kind = actr.MeasureRetrievals
+		}
+
+		model.Measures = append(model.Measures, &aMeasure)
+	}
+}
+
 func createChunkPattern(model *actr.Model, log *issueLog, cp *pattern) (*actr.Pattern, error) {
 	chunk := model.LookupChunk(cp.ChunkName)
 	if chunk == nil {
@@ -433,6 +652,13 @@ func createChunkPattern(model *actr.Model, log *issueLog, cp *pattern) (*actr.Pa
 				newItem.ID = item.ID
 			} else if item.Num != nil {
 				newItem.Num = item.Num
+			} else if item.ChunkRef != nil {
+				if model.LookupNamedChunk(*item.ChunkRef) == nil {
+					log.errorT(item.Tokens, "could not find a chunk in memory named '%s' (chunks must be named before they are referred to)", *item.ChunkRef)
+					return nil, CompileError{}
+				}
+
+				newItem.ChunkRef = item.ChunkRef
 			} else if item.Var != nil {
 				newItem.Var = item.Var
 			}
@@ -1,5 +1,10 @@
 package amod
 
+import (
+	"fmt"
+	"os"
+)
+
 func Example_gactarUnrecognizedField() {
 	generateToStdout(`
 	==model==
@@ -42,6 +47,61 @@ func Example_chunkDuplicateName() {
 	// ERROR: duplicate chunk name: 'something' (line 7, col 6)
 }
 
+func Example_chunkInheritance() {
+	model, log, _ := GenerateModel(`
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(number): name status=new]
+		[teen(digit): tens=1]
+		[marker(number)]
+	}
+	==init==
+	==productions==`)
+
+	log.Write(os.Stdout)
+
+	for _, chunk := range model.Chunks {
+		if chunk.IsInternal() {
+			continue
+		}
+
+		fmt.Println(chunk, chunk.SlotNames, chunk.IsA(model.LookupChunk("number")), model.HasSubtypes(chunk))
+	}
+
+	// Output:
+	// [number: value] [value] true true
+	// [digit(number): name status=new] [value name status] true true
+	// [teen(digit): tens=1] [value name status tens] true false
+	// [marker(number)] [value] true false
+}
+
+func Example_chunkInheritanceErrors() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(numeral): name]
+		[teen(number): value tens]
+		[count: first second first]
+		[empty]
+		[status(_status): text]
+	}
+	==init==
+	==productions==`)
+
+	// Output:
+	// ERROR: could not find parent chunk named 'numeral' for chunk 'digit' (line 7, col 9)
+	// ERROR: duplicate slot name 'value' in chunk 'teen' (line 8, col 17)
+	// ERROR: duplicate slot name 'first' in chunk 'count' (line 9, col 23)
+	// ERROR: chunk 'empty' must have at least one slot (line 10, col 3)
+	// ERROR: could not find parent chunk named '_status' for chunk 'status' (line 11, col 10)
+}
+
 func Example_modules() {
 	generateToStdout(`
 	==model==
@@ -160,3 +220,112 @@ func Example_proceduralFieldUnrecognized() {
 	// Output:
 	// ERROR: unrecognized field 'foo' in procedural config (line 6, col 15)
 }
+
+func Example_setParam() {
+	model, _, _ := GenerateModel(`
+	==model==
+	name: Test
+	==config==
+	==init==
+	==productions==`)
+
+	for _, param := range []struct{ module, key, value string }{
+		{"memory", "retrieval_threshold", "-0.5"},
+		{"memory", "latency_factor", "-1"},
+		{"memory", "finst_time", "'foo'"},
+		{"memory", "foo", "1"},
+		{"imaginal", "delay", "0.2"},
+	} {
+		err := SetParam(model, param.module, param.key, param.value)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	fmt.Println(*model.Memory.RetrievalThreshold)
+
+	// Output:
+	// memory latency_factor '-1' must be a positive number
+	// memory finst_time ''foo'' must be a number
+	// unrecognized field 'foo' in memory config
+	// module 'imaginal' not found in model 'Test'
+	// -0.5
+}
+
+func Example_gactarRandomSeed() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	gactar { random_seed: 42 }
+	==init==
+	==productions==`)
+
+	// Output:
+}
+
+func Example_gactarRandomSeedInvalid() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	gactar { random_seed: foo }
+	==init==
+	==productions==`)
+
+	// Output:
+	// ERROR: random_seed 'foo' must be a whole number from 0 to 4294967295 (line 5, col 23)
+}
+
+func Example_measures() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [question: judgment] }
+	measures {
+		rt: time_of(respond);
+		count: count_of(respond)
+		requests: retrievals
+		answer: goal.judgment at end
+	}
+	==init==
+	==productions==
+	respond {
+		match { goal [question: *] }
+		do { set goal.judgment to 'yes' }
+	}`)
+
+	// Output:
+}
+
+func Example_measureErrors() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [question: judgment] }
+	measures {
+		rt: latency_of(respond)
+		rt2: time_of(answer)
+		answer: foo.judgment at end
+		answer2: goal.bar at end
+		other: accuracy
+		count: count_of(respond)
+		count: retrievals
+	}
+	==init==
+	==productions==
+	respond {
+		match { goal [question: *] }
+		do { set goal.judgment to 'yes' }
+	}`)
+
+	// Output:
+	// ERROR: unrecognized function in measure 'rt': 'latency_of' (should be time_of or count_of) (line 7, col 6)
+	// ERROR: production 'answer' not found in measure 'rt2' (line 8, col 15)
+	// ERROR: buffer 'foo' not found in measure 'answer' (line 9, col 10)
+	// ERROR: no chunk has a slot named 'bar' in measure 'answer2' (line 10, col 16)
+	// ERROR: unrecognized measure 'other': 'accuracy' (should be time_of(production), count_of(production), retrievals, or buffer.slot at end) (line 11, col 9)
+	// ERROR: duplicate measure name: 'count' (line 13, col 2)
+}
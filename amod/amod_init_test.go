@@ -1,5 +1,10 @@
 package amod
 
+import (
+	"fmt"
+	"os"
+)
+
 func Example_initializer1() {
 	generateToStdout(`
 	==model==
@@ -118,3 +123,299 @@ func Example_initializerMultipleInits() {
 	// Output:
 	// ERROR: module 'goal' should only have one pattern in initialization (line 7, col 1)
 }
+
+func Example_initializerDataFiles() {
+	model, log, _ := GenerateModelInDir(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory load count from 'count.csv'
+	memory load count from 'count.json'
+	memory { [count: 5 6] }
+	==productions==`, "testdata")
+
+	log.Write(os.Stdout)
+
+	for _, init := range model.Initializers {
+		fmt.Println(init.Pattern)
+	}
+
+	fmt.Println(model.SourceFiles)
+
+	// Output:
+	// [count: 0 1]
+	// [count: 1 2]
+	// [count: 2 nil]
+	// [count: 0 1]
+	// [count: one nil]
+	// [count: 5 6]
+	// [testdata/count.csv testdata/count.json]
+}
+
+func Example_initializerDataFileErrors() {
+	generateInDirToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory load count from 'bad.csv'
+	memory load count from 'bad.json'
+	memory load count from 'badcolumns.csv'
+	memory load count from 'missing.csv'
+	memory load count from 'count.txt'
+	memory load other from 'count.csv'
+	goal load count from 'count.csv'
+	==productions==`, "testdata")
+
+	// Output:
+	// ERROR: 'bad.csv' line 3: expected 2 columns (line 7, col 8)
+	// ERROR: 'bad.csv' line 4: invalid value for slot 'second': ''two'' (should be an identifier or a number) (line 7, col 8)
+	// ERROR: 'bad.csv' line 5: invalid value for slot 'first': '5 6' (should be an identifier or a number) (line 7, col 8)
+	// ERROR: 'bad.json' line 3: missing value for slot 'second' (line 8, col 8)
+	// ERROR: 'bad.json' line 4: chunk 'count' does not have a slot named 'third' (line 8, col 8)
+	// ERROR: 'bad.json' line 5: invalid value for slot 'first' (should be a string, number, or null) (line 8, col 8)
+	// ERROR: cannot load 'badcolumns.csv': chunk 'count' does not have a slot named 'third' (line 9, col 24)
+	// ERROR: cannot load 'missing.csv': no such file or directory (line 10, col 24)
+	// ERROR: cannot load 'count.txt': data files must be .csv or .json (line 11, col 24)
+	// ERROR: could not find chunk named 'other' (line 12, col 13)
+	// ERROR: module 'goal' cannot load chunks from a file (line 13, col 6)
+}
+
+func Example_initializerDataFileNotFromFile() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory load count from 'count.csv'
+	==productions==`)
+
+	// Output:
+	// ERROR: cannot load 'count.csv': data files may only be loaded by amod files on disk (line 7, col 24)
+}
+
+func Example_initializerMetadata() {
+	model, log, _ := GenerateModel(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory {
+		[count: 0 1] { base_level: 0.5 }
+		[count: 1 2] { references: 10, created: -100 }
+		[count: 2 3]
+	}
+	==productions==`)
+
+	log.Write(os.Stdout)
+
+	for _, init := range model.Initializers {
+		if init.Metadata == nil {
+			fmt.Println(init.Pattern, "no metadata")
+			continue
+		}
+
+		fmt.Println(init.Pattern, init.Metadata.BaseLevel != nil, init.Metadata.References != nil, init.Metadata.Created != nil)
+	}
+
+	// Output:
+	// [count: 0 1] true false false
+	// [count: 1 2] false true true
+	// [count: 2 3] no metadata
+}
+
+func Example_initializerMetadataValues() {
+	model, log, _ := GenerateModel(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory {
+		[count: 0 1] { base_level: 0.5 }
+		[count: 1 2] { references: 10, created: -100 }
+	}
+	==productions==`)
+
+	log.Write(os.Stdout)
+
+	base := model.Initializers[0].Metadata
+	fmt.Println(*base.BaseLevel)
+
+	usage := model.Initializers[1].Metadata
+	fmt.Println(*usage.References, *usage.Created)
+
+	// Output:
+	// 0.5
+	// 10 -100
+}
+
+func Example_initializerMetadataErrors() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory {
+		[count: 0 1] { strength: 0.5 }
+		[count: 1 2] { references: 1.5 }
+		[count: 2 3] { created: 10 }
+		[count: 3 4] { base_level: high }
+	}
+	goal [count: 5 6] { base_level: 0.5 }
+	==productions==`)
+
+	// Output:
+	// ERROR: unrecognized chunk metadata 'strength' (should be base_level, references, created) (line 8, col 17)
+	// ERROR: chunk metadata references '1.5' must be a whole number greater than 0 (line 9, col 29)
+	// ERROR: chunk metadata created '10' must not be after the start of the run (0) (line 10, col 26)
+	// ERROR: chunk metadata base_level 'high' must be a number (line 11, col 29)
+	// ERROR: only chunks in memory may have metadata (line 13, col 19)
+}
+
+func Example_initializerDefaultSlots() {
+	model, log, _ := GenerateModelInDir(`
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(number): name status=new count=0]
+	}
+	==init==
+	memory {
+		[digit: 1 one]
+		[digit: 2 two old]
+		[digit: 3 three old 5]
+	}
+	goal [number: 4]
+	memory load digit from 'digits.csv'
+	memory load digit from 'digits.json'
+	==productions==`, "testdata")
+
+	log.Write(os.Stdout)
+
+	for _, init := range model.Initializers {
+		fmt.Println(init.Pattern)
+	}
+
+	// Output:
+	// [digit: 1 one new 0]
+	// [digit: 2 two old 0]
+	// [digit: 3 three old 5]
+	// [number: 4]
+	// [digit: 1 one new 0]
+	// [digit: 2 two new 0]
+	// [digit: 3 three old 0]
+}
+
+func Example_initializerDefaultSlotErrors() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(number): name status=new]
+	}
+	==init==
+	memory {
+		[digit: 1]
+		[number: 1 2]
+	}
+	==productions==`)
+
+	// Output:
+	// ERROR: invalid chunk - 'digit' expects 3 slots (line 11, col 2)
+	// ERROR: invalid chunk - 'number' expects 1 slot (line 12, col 2)
+}
+
+func Example_initializerNamedChunks() {
+	model, log, _ := GenerateModel(`
+	==model==
+	name: Test
+	==config==
+	chunks { [node: value next] }
+	==init==
+	memory {
+		three: [node: c nil]
+		two: [node: b &three]
+		one: [node: a &two] { base_level: 0.5 }
+		[node: start &one]
+	}
+	==productions==
+	follow {
+		match { retrieval [node: ?value !&three] }
+		do { recall [node: ?value *] }
+	}`)
+
+	log.Write(os.Stdout)
+
+	for _, init := range model.Initializers {
+		fmt.Printf("%q %s\n", init.ChunkName, init.Pattern)
+	}
+
+	fmt.Println(model.LookupNamedChunk("two").Pattern)
+	fmt.Println(model.Productions[0].Matches[0].Pattern)
+
+	// Output:
+	// "three" [node: c nil]
+	// "two" [node: b &three]
+	// "one" [node: a &two]
+	// "" [node: start &one]
+	// [node: b &three]
+	// [node: ?value !&three]
+}
+
+func Example_initializerNamedChunkErrors() {
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [node: value next] }
+	==init==
+	memory {
+		one: [node: a &two]
+		two: [node: b nil]
+		two: [node: c nil]
+		_three: [node: d nil]
+		goal: [node: e nil]
+		four: [node: f &four]
+	}
+	goal five: [node: g nil]
+	==productions==
+	follow {
+		match { retrieval [node: * &six] }
+		do { clear retrieval }
+	}`)
+
+	// Output:
+	// ERROR: could not find a chunk in memory named 'two' (chunks must be named before they are referred to) (line 8, col 16)
+	// ERROR: duplicate chunk name: 'two' (line 10, col 2)
+	// ERROR: cannot use reserved chunk name '_three' (chunks beginning with '_' are reserved) (line 11, col 2)
+	// ERROR: cannot use reserved chunk name 'goal' (module and buffer names are reserved) (line 12, col 2)
+	// ERROR: could not find a chunk in memory named 'four' (chunks must be named before they are referred to) (line 13, col 17)
+	// ERROR: only chunks in memory may be named (line 15, col 6)
+	// ERROR: could not find a chunk in memory named 'six' (chunks must be named before they are referred to) (line 18, col 29)
+}
+
+func Example_initializerDataFileMetadata() {
+	generateInDirToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory load count from 'metadata.csv'
+	==productions==`, "testdata")
+
+	// Output:
+	// ERROR: 'metadata.csv' line 4: chunk metadata base_level 'x' must be a number (line 7, col 8)
+	// ERROR: 'metadata.csv' line 5: chunk metadata references '0.5' must be a whole number greater than 0 (line 7, col 8)
+}
@@ -630,3 +630,34 @@ func Example_productionMatchInternalInvalidStatus2() {
 	// Output:
 	// ERROR: invalid _status 'something' for 'retrieval' in production 'start' (should be 'busy', 'empty', 'error', 'full') (line 8, col 30)
 }
+
+func Example_productionSetStatementSubtype() {
+	// Check that a matched buffer may be set to its type or one of its parents
+	generateToStdout(`
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(number): name]
+		[word: text]
+	}
+	==init==
+	==productions==
+	parent {
+		match { goal [digit: * *] }
+		do { set goal to [number: 1] }
+	}
+	subtype {
+		match { goal [number: *] }
+		do { set goal to [digit: 1 one] }
+	}
+	other {
+		match { goal [number: *] }
+		do { set goal to [word: one] }
+	}`)
+
+	// Output:
+	// ERROR: cannot set 'goal' to a 'digit' chunk in production 'subtype' (it matched a 'number' chunk) (line 18, col 19)
+	// ERROR: cannot set 'goal' to a 'word' chunk in production 'other' (it matched a 'number' chunk) (line 22, col 19)
+}
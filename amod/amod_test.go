@@ -8,3 +8,8 @@ func generateToStdout(str string) {
 	_, log, _ := GenerateModel(str)
 	log.Write(os.Stdout)
 }
+
+func generateInDirToStdout(str, dir string) {
+	_, log, _ := GenerateModelInDir(str, dir)
+	log.Write(os.Stdout)
+}
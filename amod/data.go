@@ -0,0 +1,389 @@
+package amod
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/actr/modules"
+
+	"github.com/asmaloney/gactar/util/container"
+)
+
+// maxDataFileErrors is the most errors reported for one data file so a bad file with thousands of
+// rows doesn't flood the log.
+const maxDataFileErrors = 10
+
+// dataRow is one chunk read from a data file. Values are keyed by column (slot) name.
+type dataRow struct {
+	line   int // line in the data file the row starts on
+	values map[string]string
+}
+
+// addDataFile loads the chunks in a CSV or JSON file into the module. Each column is a slot of the
+// chunk and each row is turned into a pattern which goes through the same checks as the
+// patterns in the amod file. Relative file names are relative to dir (the directory of the amod
+// file). If dir is empty, the amod didn't come from a file so it may not load any.
+func addDataFile(model *actr.Model, log *issueLog, module modules.ModuleInterface, file *dataFile, dir string) {
+	if dir == "" {
+		log.errorT(file.Tokens[3:4], "cannot load '%s': data files may only be loaded by amod files on disk", file.FileName)
+		return
+	}
+
+	chunk := model.LookupChunk(file.ChunkName)
+
+	path := file.FileName
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	if !container.Contains(path, model.SourceFiles) {
+		model.SourceFiles = append(model.SourceFiles, path)
+	}
+
+	numErrors := 0
+	rowError := func(line int, s string, a ...interface{}) {
+		numErrors++
+
+		switch {
+		case numErrors < maxDataFileErrors:
+			log.errorT(file.Tokens, "'%s' line %d: %s", file.FileName, line, fmt.Sprintf(s, a...))
+		case numErrors == maxDataFileErrors:
+			log.errorT(file.Tokens, "'%s': too many errors", file.FileName)
+		}
+	}
+
+	addRow := func(row dataRow) {
+		p, err := dataRowPattern(chunk, row)
+		if err != nil {
+			rowError(row.line, "%s", err)
+			return
+		}
+
+		metadata, err := dataRowMetadata(chunk, row)
+		if err != nil {
+			rowError(row.line, "%s", err)
+			return
+		}
+
+		if validatePattern(model, log, p) != nil {
+			return
+		}
+
+		pattern, err := createChunkPattern(model, log, p)
+		if err != nil {
+			return
+		}
+
+		model.Initializers = append(model.Initializers, &actr.Initializer{
+			Module:         module,
+			Pattern:        pattern,
+			Metadata:       metadata,
+			AMODLineNumber: file.Tokens[0].Pos.Line,
+		})
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.errorT(file.Tokens[3:4], "cannot load '%s': %s", file.FileName, unwrapPathError(err))
+		return
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		err = readCSVRows(f, chunk, rowError, addRow)
+	case ".json":
+		err = readJSONRows(f, rowError, addRow)
+	default:
+		log.errorT(file.Tokens[3:4], "cannot load '%s': data files must be .csv or .json", file.FileName)
+		return
+	}
+
+	if err != nil {
+		log.errorT(file.Tokens[3:4], "cannot load '%s': %s", file.FileName, err)
+	}
+}
+
+// readCSVRows reads a CSV file with a header row naming the chunk's slots.
+func readCSVRows(r io.Reader, chunk *actr.Chunk, rowError func(int, string, ...interface{}), addRow func(dataRow)) (err error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			err = errors.New("missing header row")
+		}
+		return
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.TrimSpace(name)
+	}
+
+	err = validateColumns(chunk, columns)
+	if err != nil {
+		return
+	}
+
+	for {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			return
+		}
+
+		if readErr != nil {
+			var parseErr *csv.ParseError
+			if errors.As(readErr, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+				rowError(parseErr.StartLine, "expected %d columns", len(columns))
+				continue
+			}
+
+			err = readErr
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		row := dataRow{line: line, values: make(map[string]string, len(columns))}
+		for i, column := range columns {
+			row.values[column] = strings.TrimSpace(record[i])
+		}
+
+		addRow(row)
+	}
+}
+
+// isMetadataColumn checks if the column holds chunk metadata (e.g. "base_level"). Slots with the
+// same names take precedence.
+func isMetadataColumn(chunk *actr.Chunk, column string) bool {
+	return !chunk.HasSlot(column) && actr.IsMetadataName(column)
+}
+
+// validateColumns checks that the columns are the chunk's slots (in any order) and optionally
+// its metadata. Columns for slots with default values may be left out.
+func validateColumns(chunk *actr.Chunk, columns []string) error {
+	found := map[string]bool{}
+
+	for _, column := range columns {
+		if !chunk.HasSlot(column) && !isMetadataColumn(chunk, column) {
+			return fmt.Errorf("chunk '%s' does not have a slot named '%s'", chunk.Name, column)
+		}
+
+		if found[column] {
+			return fmt.Errorf("duplicate column '%s'", column)
+		}
+
+		found[column] = true
+	}
+
+	for _, slot := range chunk.SlotNames {
+		if !found[slot] && chunk.DefaultValue(slot) == nil {
+			return fmt.Errorf("missing column for slot '%s'", slot)
+		}
+	}
+
+	return nil
+}
+
+// readJSONRows reads a JSON file containing an array of objects which map the chunk's slots to
+// their values (strings, numbers, or null).
+func readJSONRows(r io.Reader, rowError func(int, string, ...interface{}), addRow func(dataRow)) (err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		err = errors.New("expected an array of objects")
+		return
+	}
+
+	for decoder.More() {
+		// The offset is just before the object, so skip any whitespace & the comma to find its line.
+		offset := int(decoder.InputOffset())
+		for offset < len(data) && (data[offset] == ',' || unicode.IsSpace(rune(data[offset]))) {
+			offset++
+		}
+		line := bytes.Count(data[:offset], []byte("\n")) + 1
+
+		var object map[string]interface{}
+
+		err = decoder.Decode(&object)
+		if err != nil {
+			err = fmt.Errorf("line %d: expected an object: %w", line, err)
+			return
+		}
+
+		row := dataRow{line: line, values: make(map[string]string, len(object))}
+		valid := true
+
+		for key, value := range object {
+			switch value := value.(type) {
+			case nil:
+				row.values[key] = "nil"
+			case json.Number:
+				row.values[key] = value.String()
+			case string:
+				row.values[key] = strings.TrimSpace(value)
+			default:
+				rowError(line, "invalid value for slot '%s' (should be a string, number, or null)", key)
+				valid = false
+			}
+		}
+
+		if valid {
+			addRow(row)
+		}
+	}
+
+	return
+}
+
+// dataRowPattern creates the pattern for a row so it can be checked & created like the patterns in
+// the amod file. Values must be identifiers or numbers - empty values and "nil" are nil. Slots
+// which aren't in the row use their default values.
+func dataRowPattern(chunk *actr.Chunk, row dataRow) (p *pattern, err error) {
+	p = &pattern{ChunkName: chunk.Name}
+
+	columns := make([]string, 0, len(row.values))
+	for column := range row.values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		if !chunk.HasSlot(column) && !isMetadataColumn(chunk, column) {
+			err = fmt.Errorf("chunk '%s' does not have a slot named '%s'", chunk.Name, column)
+			return
+		}
+	}
+
+	for _, slot := range chunk.SlotNames {
+		value, ok := row.values[slot]
+		if !ok {
+			defaultValue := chunk.DefaultValue(slot)
+			if defaultValue == nil {
+				err = fmt.Errorf("missing value for slot '%s'", slot)
+				return
+			}
+
+			value = defaultValue.String()
+		}
+
+		item := &patternSlotItem{}
+
+		switch {
+		case value == "" || value == "nil":
+			isNil := true
+			item.Nil = &isNil
+
+		case isDataNumber(value):
+			item.Num = &value
+
+		case isDataIdentifier(value):
+			item.ID = &value
+
+		default:
+			err = fmt.Errorf("invalid value for slot '%s': '%s' (should be an identifier or a number)", slot, value)
+			return
+		}
+
+		p.Slots = append(p.Slots, &patternSlot{Items: []*patternSlotItem{item}})
+	}
+
+	return
+}
+
+// dataRowMetadata creates the chunk metadata from the row's metadata columns. Empty values aren't
+// set. It returns nil if there aren't any.
+func dataRowMetadata(chunk *actr.Chunk, row dataRow) (metadata *actr.ChunkMetadata, err error) {
+	for _, name := range actr.MetadataNames {
+		value, ok := row.values[name]
+		if !ok || value == "" || value == "nil" || !isMetadataColumn(chunk, name) {
+			continue
+		}
+
+		if !isDataNumber(value) {
+			err = fmt.Errorf("chunk metadata %s '%s' must be a number", name, value)
+			return
+		}
+
+		number, _ := strconv.ParseFloat(value, 64)
+
+		if metadata == nil {
+			metadata = &actr.ChunkMetadata{}
+		}
+
+		setErr := metadata.Set(name, number)
+		if setErr != nil {
+			err = fmt.Errorf("chunk metadata %s '%s' %s", name, value, setErr)
+			return
+		}
+	}
+
+	return
+}
+
+// isDataIdentifier checks if the value would be lexed as an identifier in the amod file.
+func isDataIdentifier(value string) bool {
+	for i, r := range value {
+		if !isAlphaNumeric(r) || (i == 0 && isDigit(r)) {
+			return false
+		}
+	}
+
+	return value != ""
+}
+
+// isDataNumber checks if the value would be lexed as a number in the amod file (e.g. "-1.5").
+func isDataNumber(value string) bool {
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		value = value[1:]
+	}
+
+	digits := 0
+	dots := 0
+	for _, r := range value {
+		switch {
+		case isDigit(r):
+			digits++
+		case r == '.':
+			dots++
+		default:
+			return false
+		}
+	}
+
+	return digits > 0 && dots <= 1
+}
+
+// unwrapPathError removes the path from file errors since we output the file name ourselves.
+func unwrapPathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+
+	return err
+}
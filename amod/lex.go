@@ -90,6 +90,7 @@ var keywords []string = []string{
 	"examples",
 	"gactar",
 	"match",
+	"measures",
 	"modules",
 	"name",
 	"nil",
@@ -14,7 +14,7 @@ import (
 
 // Railroad Diagrams
 // ------
-// First output the EBNF grammar to stdout with the command "gactar -ebnf".
+// First output the EBNF grammar to stdout with the command "gactar grammar".
 //
 // There are two ways to generate railroad diagrams:
 // 	1. Use the "railroad" tool from participle like this:
@@ -79,18 +79,31 @@ type field struct {
 	Tokens []lexer.Token
 }
 
+// chunkSlotDefault is the default value of a slot in a chunk declaration (e.g. "status=new").
+type chunkSlotDefault struct {
+	Nil *bool   `parser:"( @('nil':Keyword)"`
+	ID  *string `parser:"| @Ident"`
+	Num *string `parser:"| @Number )"`
+
+	Tokens []lexer.Token
+}
+
 type chunkSlot struct {
-	Space1 string `parser:"@PatternSpace?"`
-	Slot   string `parser:"@Ident"`
-	Space2 string `parser:"@PatternSpace?"`
+	Space1  string            `parser:"@PatternSpace?"`
+	Slot    string            `parser:"@Ident"`
+	Default *chunkSlotDefault `parser:"( '=':Char @@ )?"`
+	Space2  string            `parser:"@PatternSpace?"`
 
 	Tokens []lexer.Token
 }
 
+// chunkDecl declares a chunk. It may inherit the slots of a parent chunk which come before its own
+// (e.g. "[digit(number): name]"). If it has a parent, it doesn't need any slots of its own.
 type chunkDecl struct {
 	StartBracket string       `parser:"'['"` // not used - must be set for parse
-	Name         string       `parser:"@Ident ':'"`
-	Slots        []*chunkSlot `parser:"@@+"`
+	Name         string       `parser:"@Ident"`
+	Parent       *string      `parser:"( '(':Char @Ident ')':Char )?"`
+	Slots        []*chunkSlot `parser:"( ':' @@+ )?"`
 	EndBracket   string       `parser:"']'"` // not used - must be set for parse
 
 	Tokens []lexer.Token
@@ -103,17 +116,49 @@ type module struct {
 	Tokens []lexer.Token
 }
 
+// measure is either a function of a production (e.g. "rt: time_of(respond)"), a count
+// (e.g. "requests: retrievals"), or a slot at the end of the run (e.g. "answer: goal.judgment at end").
+type measure struct {
+	Name     string  `parser:"@Ident ':'"`
+	Function string  `parser:"@Ident"` // function or buffer name
+	Arg      *string `parser:"( '(' @Ident ')'"`
+	Slot     *string `parser:"| '.' @Ident 'at' 'end' )? (';')?"`
+
+	Tokens []lexer.Token
+}
+
 type configSection struct {
 	GACTAR     []*field     `parser:"('gactar' '{' @@* '}')?"`
 	Modules    []*module    `parser:"('modules' '{' @@* '}')?"`
 	ChunkDecls []*chunkDecl `parser:"('chunks' '{' @@* '}')?"`
+	Measures   []*measure   `parser:"('measures' '{' @@* '}')?"`
+
+	Tokens []lexer.Token
+}
+
+// dataFile loads chunks from a CSV or JSON file (e.g. "load count from 'count.csv'").
+type dataFile struct {
+	ChunkName string `parser:"'load' @Ident"`
+	FileName  string `parser:"'from' @String"`
+
+	Tokens []lexer.Token
+}
+
+// initPattern is a chunk to initialize a buffer or memory with. Chunks in memory may be named so
+// other chunks can refer to them (e.g. "one: [count: 0 1]") and may have metadata to set their
+// initial activation (e.g. "[count: 0 1] { base_level: 0.5 }").
+type initPattern struct {
+	ChunkName *string  `parser:"( @Ident ':' )?"`
+	Pattern   *pattern `parser:"@@"`
+	Metadata  []*field `parser:"( '{' @@* '}' )?"`
 
 	Tokens []lexer.Token
 }
 
 type initialization struct {
-	Name         string     `parser:"@Ident"`
-	InitPatterns []*pattern `parser:"( '{' @@+ '}' | @@ )"`
+	Name         string         `parser:"@Ident"`
+	DataFile     *dataFile      `parser:"( @@"`
+	InitPatterns []*initPattern `parser:"| '{' @@+ '}' | @@ )"`
 
 	Tokens []lexer.Token
 }
@@ -129,6 +174,7 @@ type patternSlotItem struct {
 	Nil      *bool   `parser:"( @('nil':Keyword)"`
 	ID       *string `parser:"| @Ident"`
 	Num      *string `parser:"| @Number"` // we don't need to treat this as a number anywhere, so keep as a string
+	ChunkRef *string `parser:"| '&':Char @Ident"`
 	Var      *string `parser:"| @PatternVar ))"`
 	Wildcard *string `parser:"| @PatternWildcard"`
 
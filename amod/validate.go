@@ -4,7 +4,9 @@ import (
 	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/asmaloney/gactar/actr"
 	"github.com/asmaloney/gactar/actr/buffer"
+	"github.com/asmaloney/gactar/actr/modules"
 
+	"github.com/asmaloney/gactar/util/container"
 	"github.com/asmaloney/gactar/util/issues"
 )
 
@@ -16,7 +18,7 @@ type varAndIndex struct {
 }
 
 // validateChunk checks the chunk name to ensure uniqueness and that it isn't using
-// reserved names.
+// reserved names. It also checks that its parent exists and that its slot names are unique.
 func validateChunk(model *actr.Model, log *issueLog, chunk *chunkDecl) (err error) {
 	if actr.IsInternalChunkName(chunk.Name) {
 		log.errorTR(chunk.Tokens, 1, 2, "cannot use reserved chunk name '%s' (chunks beginning with '_' are reserved)", chunk.Name)
@@ -29,7 +31,32 @@ func validateChunk(model *actr.Model, log *issueLog, chunk *chunkDecl) (err erro
 		return CompileError{}
 	}
 
-	return nil
+	slotNames := []string{}
+
+	if chunk.Parent != nil {
+		parent := model.LookupChunk(*chunk.Parent)
+		if parent == nil || parent.IsInternal() {
+			log.errorTR(chunk.Tokens, 3, 4, "could not find parent chunk named '%s' for chunk '%s'", *chunk.Parent, chunk.Name)
+			return CompileError{}
+		}
+
+		slotNames = append(slotNames, parent.SlotNames...)
+	} else if len(chunk.Slots) == 0 {
+		log.errorTR(chunk.Tokens, 1, 2, "chunk '%s' must have at least one slot", chunk.Name)
+		return CompileError{}
+	}
+
+	for _, slot := range chunk.Slots {
+		if container.Contains(slot.Slot, slotNames) {
+			log.errorT(slot.Tokens, "duplicate slot name '%s' in chunk '%s'", slot.Slot, chunk.Name)
+			err = CompileError{}
+			continue
+		}
+
+		slotNames = append(slotNames, slot.Slot)
+	}
+
+	return
 }
 
 func validateInitialization(model *actr.Model, log *issueLog, init *initialization) (err error) {
@@ -41,17 +68,69 @@ func validateInitialization(model *actr.Model, log *issueLog, init *initializati
 		return CompileError{}
 	}
 
+	if init.DataFile != nil {
+		return validateDataFile(model, log, module, init.DataFile)
+	}
+
 	if !module.AllowsMultipleInit() && len(init.InitPatterns) > 1 {
 		log.errorTR(init.Tokens, 0, 1, "module '%s' should only have one pattern in initialization", name)
 		return CompileError{}
 	}
 
 	for _, init := range init.InitPatterns {
-		pattern_err := validatePattern(model, log, init)
+		pattern_err := validatePattern(model, log, init.Pattern)
 		if pattern_err != nil {
 			err = CompileError{}
 			continue
 		}
+
+		if init.Metadata != nil && module != modules.ModuleInterface(model.Memory) {
+			log.errorT(init.Tokens[len(init.Pattern.Tokens):], "only chunks in memory may have metadata")
+			err = CompileError{}
+		}
+	}
+
+	return
+}
+
+// validateChunkName checks that a named chunk is in memory and that its name is unique and isn't
+// reserved. Chunk names are used by other chunks to refer to it.
+func validateChunkName(model *actr.Model, log *issueLog, module modules.ModuleInterface, init *initPattern) (err error) {
+	name := *init.ChunkName
+
+	if module != modules.ModuleInterface(model.Memory) {
+		log.errorTR(init.Tokens, 0, 1, "only chunks in memory may be named")
+		return CompileError{}
+	}
+
+	if actr.IsInternalChunkName(name) {
+		log.errorTR(init.Tokens, 0, 1, "cannot use reserved chunk name '%s' (chunks beginning with '_' are reserved)", name)
+		return CompileError{}
+	}
+
+	if model.LookupModule(name) != nil || model.LookupBuffer(name) != nil {
+		log.errorTR(init.Tokens, 0, 1, "cannot use reserved chunk name '%s' (module and buffer names are reserved)", name)
+		return CompileError{}
+	}
+
+	if model.LookupNamedChunk(name) != nil {
+		log.errorTR(init.Tokens, 0, 1, "duplicate chunk name: '%s'", name)
+		return CompileError{}
+	}
+
+	return
+}
+
+// validateDataFile checks that the module may be initialized from a file and that the chunk exists.
+func validateDataFile(model *actr.Model, log *issueLog, module modules.ModuleInterface, file *dataFile) (err error) {
+	if !module.AllowsMultipleInit() {
+		log.errorT(file.Tokens, "module '%s' cannot load chunks from a file", module.ModuleName())
+		return CompileError{}
+	}
+
+	if model.LookupChunk(file.ChunkName) == nil {
+		log.errorTR(file.Tokens, 1, 2, "could not find chunk named '%s'", file.ChunkName)
+		return CompileError{}
 	}
 
 	return
@@ -78,6 +157,54 @@ func validatePattern(model *actr.Model, log *issueLog, pattern *pattern) (err er
 	return
 }
 
+// validateMeasure checks that the measure's name is unique and that the production, buffer, or
+// slot it refers to exists.
+func validateMeasure(model *actr.Model, log *issueLog, m *measure) (err error) {
+	for _, existing := range model.Measures {
+		if existing.Name == m.Name {
+			log.errorTR(m.Tokens, 0, 1, "duplicate measure name: '%s'", m.Name)
+			return CompileError{}
+		}
+	}
+
+	switch {
+	case m.Arg != nil:
+		if m.Function != string(actr.MeasureTimeOf) && m.Function != string(actr.MeasureCountOf) {
+			log.errorTR(m.Tokens, 2, 3, "unrecognized function in measure '%s': '%s' (should be %s or %s)", m.Name, m.Function, actr.MeasureTimeOf, actr.MeasureCountOf)
+			return CompileError{}
+		}
+
+		if model.LookupProduction(*m.Arg) == nil {
+			log.errorTR(m.Tokens, 4, 5, "production '%s' not found in measure '%s'", *m.Arg, m.Name)
+			return CompileError{}
+		}
+
+	case m.Slot != nil:
+		if model.LookupBuffer(m.Function) == nil {
+			log.errorTR(m.Tokens, 2, 3, "buffer '%s' not found in measure '%s'", m.Function, m.Name)
+			return CompileError{}
+		}
+
+		for _, chunk := range model.Chunks {
+			if !chunk.IsInternal() && chunk.HasSlot(*m.Slot) {
+				return
+			}
+		}
+
+		log.errorTR(m.Tokens, 4, 5, "no chunk has a slot named '%s' in measure '%s'", *m.Slot, m.Name)
+		return CompileError{}
+
+	default:
+		if m.Function != string(actr.MeasureRetrievals) {
+			log.errorT(m.Tokens[2:3], "unrecognized measure '%s': '%s' (should be %s(production), %s(production), %s, or buffer.slot at end)",
+				m.Name, m.Function, actr.MeasureTimeOf, actr.MeasureCountOf, actr.MeasureRetrievals)
+			return CompileError{}
+		}
+	}
+
+	return
+}
+
 // validateMatch verifies several aspects of a match item.
 func validateMatch(match *match, model *actr.Model, log *issueLog, production *actr.Production) (err error) {
 	if match == nil {
@@ -192,6 +319,15 @@ func validateSetStatement(set *setStatement, model *actr.Model, log *issueLog, p
 		chunkName := set.Pattern.ChunkName
 		chunk := model.LookupChunk(chunkName)
 
+		// Setting a matched buffer modifies the chunk in it, so the pattern must use its type or
+		// one of its parents.
+		match := production.LookupMatchByBuffer(bufferName)
+		if match != nil && chunk != nil && !match.Pattern.Chunk.IsA(chunk) {
+			log.errorT(set.Pattern.Tokens, "cannot set '%s' to a '%s' chunk in production '%s' (it matched a '%s' chunk)", bufferName, chunkName, production.Name, match.Pattern.Chunk.Name)
+			err = CompileError{}
+			return
+		}
+
 		for slotIndex, slot := range set.Pattern.Slots {
 			if len(slot.Items) > 1 {
 				log.errorT(slot.Tokens, "cannot set '%s.%v' to compound var in production '%s'", bufferName, chunk.SlotName(slotIndex), production.Name)
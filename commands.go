@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/fit"
+)
+
+const defaultPort = 8181
+
+// commands returns the sub-commands gactar understands. Flags which only apply to one command
+// are declared with it.
+func commands() []*cli.Command {
+	watchFlag := &cli.BoolFlag{Name: "watch", Usage: "watch the amod files and do it again when they change"}
+	failFastFlag := &cli.BoolFlag{Name: "fail-fast", Usage: "stop at the first failure instead of continuing with the other files & frameworks"}
+	jobsFlag := &cli.IntFlag{Name: "jobs", Aliases: []string{"j"}, Value: runtime.NumCPU(), Usage: "maximum number of models to generate or run at the same time"}
+	seedFlag := &cli.UintFlag{Name: "seed", Usage: "seed the random number generators so runs are reproducible (overrides random_seed in the amod file)"}
+	outputFlag := &cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: outputText, Usage: "output format: text or json (one line per model & framework)"}
+
+	return []*cli.Command{
+		{
+			Name:      "generate",
+			Usage:     "generate code for each framework from amod files",
+			ArgsUsage: "FILES/DIRECTORIES/GLOBS...",
+			Flags:     []cli.Flag{watchFlag, jobsFlag, failFastFlag, seedFlag, outputFlag},
+			Action: func(c *cli.Context) error {
+				return exitStatus(generateAction(c, false))
+			},
+		},
+		{
+			Name:      "run",
+			Usage:     "generate code for each framework from amod files and run it",
+			ArgsUsage: "FILES/DIRECTORIES/GLOBS...",
+			Flags: []cli.Flag{
+				watchFlag, jobsFlag, failFastFlag, seedFlag, outputFlag,
+				&cli.IntFlag{Name: "repeat", Value: 1, Usage: "run each model N times and output statistics about the runs"},
+				&cli.PathFlag{Name: "csv", Usage: "write the data from each run and the statistics as CSV files to this directory"},
+				&cli.BoolFlag{Name: "dump-state", Usage: "output the contents of the buffers & declarative memory at the end of each run"},
+			},
+			Action: func(c *cli.Context) error {
+				return exitStatus(generateAction(c, true))
+			},
+		},
+		{
+			Name:      "sweep",
+			Usage:     "run a model using each combination of parameter values and output a CSV file of the results",
+			ArgsUsage: "FILE",
+			Flags: []cli.Flag{
+				&cli.GenericFlag{Name: "param", Aliases: []string{"p"}, Value: &paramFlag{}, Usage: "parameter & values to sweep: module.param=from:to:step or module.param=value,value,... (may be repeated)"},
+				&cli.IntFlag{Name: "repeat", Value: 1, Usage: "run each combination N times"},
+				&cli.PathFlag{Name: "csv", Usage: "write the CSV to this file instead of stdout"},
+				jobsFlag, seedFlag,
+			},
+			Action: func(c *cli.Context) error {
+				return exitStatus(sweepAction(c))
+			},
+		},
+		{
+			Name:      "fit",
+			Usage:     "search for the parameter values which best fit a model to data",
+			ArgsUsage: "FILE",
+			Flags: []cli.Flag{
+				&cli.PathFlag{Name: "data", Usage: "CSV file of observed data with a 'condition' column"},
+				&cli.StringFlag{Name: "column", Value: "value", Usage: "column in the data file with the observed values"},
+				&cli.GenericFlag{Name: "condition", Aliases: []string{"c"}, Value: &listFlag{}, Usage: "initial goal for a condition in the data: name=goal (may be repeated)"},
+				&cli.GenericFlag{Name: "param", Aliases: []string{"p"}, Value: &listFlag{}, Usage: "parameter to fit & its bounds: module.param=lower:upper or module.param=lower:upper:start (may be repeated)"},
+				&cli.StringFlag{Name: "measure", Aliases: []string{"m"}, Value: "end_time", Usage: "measure to compare with the data: end_time, retrievals, retrieval_failures, retrieval_success_rate, or fired:<production>"},
+				&cli.StringFlag{Name: "objective", Value: string(fit.MinimizeRMSE), Usage: "minimize the RMSE (rmse) or maximize the correlation (correlation)"},
+				&cli.IntFlag{Name: "repeat", Value: 10, Usage: "run each condition N times for each set of parameter values"},
+				&cli.IntFlag{Name: "max-evals", Value: 100, Usage: "maximum number of sets of parameter values to try"},
+				&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: outputText, Usage: "output format: text or json (one line with the result)"},
+				jobsFlag, seedFlag,
+			},
+			Action: func(c *cli.Context) error {
+				return exitStatus(fitAction(c))
+			},
+		},
+		{
+			Name:      "experiment",
+			Usage:     "run a model through the conditions & trials in an experiment file and output the results of each trial",
+			ArgsUsage: "FILE",
+			Flags: []cli.Flag{
+				&cli.PathFlag{Name: "csv", Usage: "write the CSV to this file instead of stdout"},
+				&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: outputText, Usage: "output format: text (CSV) or json (one line per trial)"},
+				jobsFlag, seedFlag,
+			},
+			Action: func(c *cli.Context) error {
+				return exitStatus(experimentAction(c))
+			},
+		},
+		{
+			Name:  "shell",
+			Usage: "run an interactive shell",
+			Flags: []cli.Flag{
+				&cli.PathFlag{Name: "script", Usage: "run shell commands from a file instead of prompting for them"},
+			},
+			Action: func(c *cli.Context) error {
+				frameworks, err := setup(c, os.Stdout)
+				if err != nil {
+					return exitStatus(err)
+				}
+
+				return exitStatus(handleInteractive(c, frameworks))
+			},
+		},
+		{
+			Name:  "serve",
+			Usage: "start a web server to run in a browser",
+			Flags: []cli.Flag{
+				&cli.IntFlag{Name: "port", Aliases: []string{"p"}, Value: defaultPort, Usage: "port to run the web server on"},
+				&cli.IntFlag{Name: "max-runs", Value: 4, Usage: "maximum number of framework processes to run at the same time"},
+				&cli.IntFlag{Name: "queue-size", Value: 32, Usage: "maximum number of framework runs waiting to start before rejecting requests"},
+				&cli.PathFlag{Name: "auth-tokens", Usage: "turn on authentication using a file of '<user name> <token>' lines"},
+				&cli.Int64Flag{Name: "max-body-size", Value: 1 << 20, Usage: "maximum size of a request body in bytes (only applies with --auth-tokens)"},
+			},
+			Action: func(c *cli.Context) error {
+				frameworks, err := setup(c, os.Stdout)
+				if err != nil {
+					return exitStatus(err)
+				}
+
+				return exitStatus(handleWeb(c, frameworks))
+			},
+		},
+		{
+			Name:  "grammar",
+			Usage: "output the amod grammar (EBNF)",
+			Action: func(c *cli.Context) error {
+				amod.OutputEBNF()
+				return nil
+			},
+		},
+		{
+			Name:      "validate",
+			Usage:     "check amod files for errors without generating code",
+			ArgsUsage: "FILES/DIRECTORIES/GLOBS...",
+			Flags:     []cli.Flag{failFastFlag, outputFlag},
+			Action: func(c *cli.Context) error {
+				results, err := newResultWriter(c)
+				if err != nil {
+					return exitStatus(err)
+				}
+
+				return exitStatus(handleValidate(c, results, results.progressWriter()))
+			},
+		},
+	}
+}
+
+// generateAction is used by the "generate" and "run" commands.
+func generateAction(c *cli.Context, run bool) (err error) {
+	results, err := newResultWriter(c)
+	if err != nil {
+		return
+	}
+
+	progress := results.progressWriter()
+
+	frameworks, err := setup(c, progress)
+	if err != nil {
+		return
+	}
+
+	return handleGenerate(c, frameworks, run, results, progress)
+}
+
+// seedFlagValue returns the value of "--seed" or nil if it wasn't set. Seeds are 32 bits, so larger
+// values are rejected instead of being truncated (which would make different seeds run the same).
+func seedFlagValue(ctx *cli.Context) (seed *uint32, err error) {
+	if !ctx.IsSet("seed") {
+		return
+	}
+
+	value := ctx.Uint("seed")
+	if uint64(value) > math.MaxUint32 {
+		err = cli.Exit(fmt.Sprintf("--seed must be at most %d", uint32(math.MaxUint32)), exitUsage)
+		return
+	}
+
+	value32 := uint32(value)
+	seed = &value32
+	return
+}
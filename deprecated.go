@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/amod"
+
+	"github.com/asmaloney/gactar/util/clicontext"
+)
+
+// Before we had commands, the mode was chosen using flags. We keep these (hidden) so existing
+// scripts continue to work.
+
+// deprecatedFlags returns the old mode flags and the flags which went with them.
+func deprecatedFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{Name: "ebnf", Hidden: true, Usage: "deprecated: use 'grammar'"},
+
+		&cli.BoolFlag{Name: "run", Aliases: []string{"r"}, Hidden: true, Usage: "deprecated: use 'run'"},
+		&cli.BoolFlag{Name: "watch", Hidden: true, Usage: "deprecated: use 'generate --watch' or 'run --watch'"},
+
+		&cli.BoolFlag{Name: "interactive", Aliases: []string{"i"}, Hidden: true, Usage: "deprecated: use 'shell'"},
+		&cli.PathFlag{Name: "script", Hidden: true, Usage: "deprecated: use 'shell --script'"},
+
+		&cli.BoolFlag{Name: "web", Aliases: []string{"w"}, Hidden: true, Usage: "deprecated: use 'serve'"},
+		&cli.IntFlag{Name: "port", Aliases: []string{"p"}, Hidden: true, Value: defaultPort, Usage: "deprecated: use 'serve --port'"},
+		&cli.IntFlag{Name: "max-runs", Hidden: true, Value: 4, Usage: "deprecated: use 'serve --max-runs'"},
+		&cli.IntFlag{Name: "queue-size", Hidden: true, Value: 32, Usage: "deprecated: use 'serve --queue-size'"},
+		&cli.PathFlag{Name: "auth-tokens", Hidden: true, Usage: "deprecated: use 'serve --auth-tokens'"},
+		&cli.Int64Flag{Name: "max-body-size", Hidden: true, Value: 1 << 20, Usage: "deprecated: use 'serve --max-body-size'"},
+	}
+}
+
+// handleDeprecatedFlags is used when no command is given. It works the way gactar did before we
+// had commands.
+func handleDeprecatedFlags(c *cli.Context) error {
+	if c.NArg() == 0 && c.NumFlags() == 0 {
+		return cli.ShowAppHelp(c)
+	}
+
+	err := setupVirtualEnvironment(c)
+	if err != nil {
+		return cli.Exit(err, exitFramework)
+	}
+
+	if c.Bool("debug") {
+		amod.SetDebug(true)
+	}
+
+	if c.Bool("ebnf") {
+		warnDeprecated("--ebnf", "gactar grammar")
+		amod.OutputEBNF()
+		return nil
+	}
+
+	if c.Bool("web") && c.Bool("interactive") {
+		return cli.Exit("cannot run 'web' and 'interactive' at the same time", exitUsage)
+	}
+
+	// Create our temp dir. This will expand our "temp" to an absolute path.
+	err = clicontext.CreateTempDir(c)
+	if err != nil {
+		return exitStatus(err)
+	}
+
+	frameworks, err := createFrameworks(c, os.Stdout)
+	if err != nil {
+		return exitStatus(err)
+	}
+
+	if c.Bool("web") {
+		warnDeprecated("--web", "gactar serve")
+		return exitStatus(handleWeb(c, frameworks))
+	}
+
+	if c.Int("port") != defaultPort {
+		fmt.Println("info: --port only applies when using --web")
+	}
+
+	if c.IsSet("script") && !c.Bool("interactive") {
+		fmt.Println("info: --script only applies when using --interactive")
+	}
+
+	if c.Bool("interactive") {
+		warnDeprecated("--interactive", "gactar shell")
+		return exitStatus(handleInteractive(c, frameworks))
+	}
+
+	// We are not interactive or web, so simply generate the output files.
+	if c.Bool("run") {
+		warnDeprecated("--run", "gactar run")
+	} else {
+		warnDeprecated("passing files without a command", "gactar generate")
+	}
+
+	return exitStatus(handleGenerate(c, frameworks, c.Bool("run"), &resultWriter{}, os.Stdout))
+}
+
+func warnDeprecated(old, replacement string) {
+	fmt.Fprintf(os.Stderr, "warning: %s is deprecated - use '%s' instead\n", old, replacement)
+}
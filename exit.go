@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+// Exit statuses so scripts & CI can tell what went wrong.
+const (
+	exitGeneral   = 1 // any other error
+	exitUsage     = 2 // bad command line (e.g. unknown flag, no input files)
+	exitCompile   = 3 // an amod file has errors or is not valid for a framework
+	exitFramework = 4 // a framework could not be initialized
+	exitRun       = 5 // writing or running the generated code failed
+)
+
+// exitStatus converts errors to ones the cli package will output & exit with.
+// Errors which don't already have a status use exitGeneral.
+func exitStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(cli.ExitCoder); ok {
+		return err
+	}
+
+	return cli.Exit(err, exitGeneral)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/experiment"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/container"
+)
+
+// experimentAction is used by the "experiment" command.
+func experimentAction(c *cli.Context) (err error) {
+	format := c.String("output")
+	if format != outputText && format != outputJSON {
+		err = cli.Exit(fmt.Sprintf("invalid output format %q (must be %q or %q)", format, outputText, outputJSON), exitUsage)
+		return
+	}
+
+	// Unless we are writing the CSV to a file, the results go to stdout so everything else goes
+	// to stderr.
+	var progress io.Writer = os.Stdout
+	if c.Path("csv") == "" || format == outputJSON {
+		progress = os.Stderr
+	}
+
+	frameworks, err := setup(c, progress)
+	if err != nil {
+		return
+	}
+
+	return handleExperiment(c, frameworks, os.Stdout, progress)
+}
+
+// handleExperiment runs each condition of an experiment on each framework and writes the results
+// of each trial to out. Everything else is output to progress.
+func handleExperiment(ctx *cli.Context, frameworks framework.List, out, progress io.Writer) (err error) {
+	showVersion(ctx, progress)
+
+	if ctx.Args().Len() != 1 {
+		err = cli.Exit("experiment requires one experiment file", exitUsage)
+		return
+	}
+
+	e, err := experiment.Load(ctx.Args().First())
+	if err != nil {
+		err = cli.Exit(err, exitUsage)
+		return
+	}
+
+	source, err := os.ReadFile(e.Model)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(progress, "Generating model for %s\n", e.Model)
+	model, log, err := amod.GenerateModelInDir(string(source), filepath.Dir(e.Model))
+	fmt.Fprint(progress, log)
+
+	if err != nil {
+		err = cli.Exit(fmt.Sprintf("%s has errors", e.Model), exitCompile)
+		return
+	}
+
+	err = e.Validate(model)
+	if err != nil {
+		err = cli.Exit(fmt.Sprintf("%s: %s", ctx.Args().First(), err), exitUsage)
+		return
+	}
+
+	frameworks, failed := initializeFrameworks(frameworks, progress)
+	if len(frameworks) == 0 {
+		err = cli.Exit("could not initialize any frameworks - please check your installation", exitFramework)
+		return
+	}
+
+	// If frameworks were chosen on the command line, they must all work.
+	if len(failed) > 0 && !container.Contains("all", ctx.StringSlice("framework")) {
+		err = cli.Exit(fmt.Sprintf("could not initialize %s", strings.Join(failed, ", ")), exitFramework)
+		return
+	}
+
+	seed, err := seedFlagValue(ctx)
+	if err != nil {
+		return
+	}
+
+	names := frameworks.Names()
+	sort.Strings(names)
+
+	sessions := e.Sessions(model, names, filepath.Join(ctx.Path("temp"), "experiment"), seed)
+
+	numTrials := 0
+	for _, session := range sessions {
+		numTrials += session.NumTrials()
+	}
+
+	fmt.Fprintf(progress, "Running %d conditions using %s (%d runs, %d trials)\n", len(e.Conditions), strings.Join(names, ", "), len(sessions), numTrials)
+
+	runParallel(len(sessions), ctx.Int("jobs"), func(i int) {
+		sessions[i].Execute(*e, frameworks[sessions[i].Framework])
+	})
+
+	results := []experiment.TrialResult{}
+	for _, session := range sessions {
+		results = append(results, session.Results...)
+	}
+
+	// The errors are usually the same for every trial, so only output the first one in full.
+	numFailed := 0
+	firstError := ""
+	for _, result := range results {
+		if result.Error == "" {
+			continue
+		}
+
+		numFailed++
+
+		if firstError == "" {
+			firstError = result.Error
+			fmt.Fprintf(progress, "Trial %s of condition %q failed using %s:\n%s\n", result.Trial, result.Condition, result.Framework, firstError)
+		}
+	}
+
+	err = writeExperimentResults(ctx, out, progress, e.Measures, results)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(progress, "%d of %d trials succeeded\n", len(results)-numFailed, len(results))
+
+	if numFailed > 0 {
+		err = cli.Exit(fmt.Sprintf("%d trials failed", numFailed), exitRun)
+	}
+
+	return
+}
+
+// writeExperimentResults writes the results as CSV to the "--csv" file (or "out" if there isn't
+// one) and as lines of JSON to "out" when using "--output json".
+func writeExperimentResults(ctx *cli.Context, out, progress io.Writer, measures []experiment.Measure, results []experiment.TrialResult) (err error) {
+	if ctx.String("output") == outputJSON {
+		encoder := json.NewEncoder(out)
+		for _, result := range results {
+			encoder.Encode(result)
+		}
+	}
+
+	path := ctx.Path("csv")
+	if path == "" {
+		if ctx.String("output") == outputJSON {
+			return
+		}
+
+		return experiment.WriteCSV(out, measures, results)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	err = experiment.WriteCSV(f, measures, results)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(progress, "Results written to %s\n", path)
+	return
+}
@@ -0,0 +1,48 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes one row for each trial result with a column for each measure.
+func WriteCSV(w io.Writer, measures []Measure, results []TrialResult) (err error) {
+	writer := csv.NewWriter(w)
+
+	header := []string{"framework", "condition", "repetition", "trial", "presentation", "seed", "status"}
+	for _, measure := range measures {
+		header = append(header, measure.Name)
+	}
+	header = append(header, "error")
+
+	writer.Write(header)
+
+	for _, result := range results {
+		seed := ""
+		if result.Seed != nil {
+			seed = strconv.FormatUint(uint64(*result.Seed), 10)
+		}
+
+		status := "ok"
+		if result.Error != "" {
+			status = "error"
+		}
+
+		row := []string{
+			result.Framework, result.Condition, strconv.Itoa(result.Repetition),
+			result.Trial, strconv.Itoa(result.Presentation), seed, status,
+		}
+
+		for _, measure := range measures {
+			row = append(row, result.Measures[measure.Name])
+		}
+
+		row = append(row, result.Error)
+
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
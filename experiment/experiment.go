@@ -0,0 +1,148 @@
+// Package experiment runs a model through the trials of an experiment and extracts measures from
+// each trial.
+//
+// An experiment is described in a JSON file which lists conditions, each with a sequence of
+// trials which set the initial contents of buffers such as goal & imaginal. Declarative memory
+// is either reset before each trial or carried over from one trial to the next within a
+// condition.
+package experiment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+)
+
+// Ways of handling declarative memory between trials.
+const (
+	MemoryReset     = "reset"      // each trial starts with the model's initial memory
+	MemoryCarryOver = "carry-over" // the trials in a condition are run one after the other
+)
+
+// Trial sets the initial contents of buffers and runs the model.
+type Trial struct {
+	Name    string                   `json:"name,omitempty"`   // used in the results (defaults to the trial number)
+	Buffers framework.InitialBuffers `json:"buffers"`          // initial contents of buffers such as goal & imaginal
+	Repeat  int                      `json:"repeat,omitempty"` // number of times to present the trial in a row (default 1)
+}
+
+// Condition is a sequence of trials.
+type Condition struct {
+	Name   string  `json:"name"`
+	Trials []Trial `json:"trials"`
+}
+
+// Experiment describes the model, the conditions to run it in, and what to measure.
+type Experiment struct {
+	Model      string      `json:"model"`            // amod file (relative to the experiment file)
+	Memory     string      `json:"memory,omitempty"` // "reset" (default) or "carry-over"
+	Repeat     int         `json:"repeat,omitempty"` // number of times to run the whole experiment (default 1)
+	Conditions []Condition `json:"conditions"`
+	Measures   []Measure   `json:"measures,omitempty"`
+}
+
+// Load reads an experiment from a JSON file. The model's path is made relative to the file.
+func Load(path string) (e *Experiment, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	e = &Experiment{}
+
+	err = decoder.Decode(e)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+		return nil, err
+	}
+
+	if e.Model == "" {
+		err = fmt.Errorf("%s: no model", path)
+		return nil, err
+	}
+
+	if !filepath.IsAbs(e.Model) {
+		e.Model = filepath.Join(filepath.Dir(path), e.Model)
+	}
+
+	return
+}
+
+// Validate checks the experiment's settings and that the trials & measures work with the model.
+func (e Experiment) Validate(model *actr.Model) (err error) {
+	if e.Memory != "" && e.Memory != MemoryReset && e.Memory != MemoryCarryOver {
+		return fmt.Errorf("unknown memory setting %q (must be %q or %q)", e.Memory, MemoryReset, MemoryCarryOver)
+	}
+
+	if e.Repeat < 0 {
+		return errors.New("repeat must not be negative")
+	}
+
+	if len(e.Conditions) == 0 {
+		return errors.New("no conditions")
+	}
+
+	conditionNames := map[string]bool{}
+	for _, condition := range e.Conditions {
+		if condition.Name == "" {
+			return errors.New("each condition requires a name")
+		}
+
+		if conditionNames[condition.Name] {
+			return fmt.Errorf("duplicate condition %q", condition.Name)
+		}
+		conditionNames[condition.Name] = true
+
+		if len(condition.Trials) == 0 {
+			return fmt.Errorf("condition %q has no trials", condition.Name)
+		}
+
+		for i, trial := range condition.Trials {
+			if trial.Repeat < 0 {
+				return fmt.Errorf("condition %q trial %s: repeat must not be negative", condition.Name, trial.label(i))
+			}
+
+			if len(trial.Buffers) == 0 {
+				return fmt.Errorf("condition %q trial %s: no buffers to set", condition.Name, trial.label(i))
+			}
+
+			_, err = framework.ParseInitialBuffers(model, trial.Buffers)
+			if err != nil {
+				return fmt.Errorf("condition %q trial %s: %w", condition.Name, trial.label(i), err)
+			}
+		}
+	}
+
+	measureNames := map[string]bool{}
+	for _, measure := range e.Measures {
+		if measureNames[measure.Name] {
+			return fmt.Errorf("duplicate measure %q", measure.Name)
+		}
+		measureNames[measure.Name] = true
+
+		err = measure.validate(model)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// label returns the trial's name or its number if it doesn't have one.
+func (t Trial) label(index int) string {
+	if t.Name != "" {
+		return t.Name
+	}
+
+	return fmt.Sprintf("%d", index+1)
+}
@@ -0,0 +1,175 @@
+package experiment
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+)
+
+const testSource = `
+==model==
+name: Test
+==config==
+gactar { random_seed: 42 }
+chunks { [count: first second] }
+==init==
+==productions==
+start {
+	match { goal [count: * *] }
+	do { clear goal }
+}`
+
+func testModel(t *testing.T) *actr.Model {
+	model, log, err := amod.GenerateModel(testSource)
+	if err != nil {
+		t.Fatalf("model has errors: %s", log)
+	}
+
+	return model
+}
+
+func testExperiment() Experiment {
+	return Experiment{
+		Memory: MemoryCarryOver,
+		Repeat: 2,
+		Conditions: []Condition{
+			{Name: "a", Trials: []Trial{
+				{Name: "first", Buffers: framework.InitialBuffers{"goal": "count: 1 2"}, Repeat: 2},
+				{Buffers: framework.InitialBuffers{"goal": "count: 2 3"}},
+			}},
+			{Name: "b", Trials: []Trial{
+				{Buffers: framework.InitialBuffers{"goal": "count: 3 4"}},
+			}},
+		},
+		Measures: []Measure{
+			{Name: "rt", Type: MeasureLatency, Production: "start"},
+			{Name: "answer", Type: MeasurePrinted},
+		},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exp.json")
+
+	err := os.WriteFile(path, []byte(`{"model": "test.amod", "conditions": [{"name": "a", "trials": [{"buffers": {"goal": "count: 1 2"}}]}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if e.Model != filepath.Join(dir, "test.amod") {
+		t.Errorf("expected the model relative to the experiment file, got %q", e.Model)
+	}
+
+	err = os.WriteFile(path, []byte(`{"model": "test.amod", "trails": []}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "trails") {
+		t.Errorf("expected error for unknown field, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	model := testModel(t)
+
+	if err := testExperiment().Validate(model); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	tests := map[string]func(e *Experiment){
+		"memory":             func(e *Experiment) { e.Memory = "forget" },
+		"no conditions":      func(e *Experiment) { e.Conditions = nil },
+		"duplicate":          func(e *Experiment) { e.Conditions[1].Name = "a" },
+		"no trials":          func(e *Experiment) { e.Conditions[1].Trials = nil },
+		"bad buffer":         func(e *Experiment) { e.Conditions[1].Trials[0].Buffers = framework.InitialBuffers{"foo": "count: 1 2"} },
+		"bad chunk":          func(e *Experiment) { e.Conditions[1].Trials[0].Buffers = framework.InitialBuffers{"goal": "bar: 1 2"} },
+		"unknown type":       func(e *Experiment) { e.Measures[0].Type = "accuracy" },
+		"unknown production": func(e *Experiment) { e.Measures[0].Production = "stop" },
+		"duplicate measure":  func(e *Experiment) { e.Measures[1].Name = "rt" },
+	}
+
+	for name, change := range tests {
+		e := testExperiment()
+		change(&e)
+
+		if err := e.Validate(model); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	model := testModel(t)
+	e := testExperiment()
+
+	// carry-over: one session for each condition & repetition
+	sessions := e.Sessions(model, []string{"ccm"}, "tmp", nil)
+	if len(sessions) != 4 {
+		t.Fatalf("expected 4 sessions, got %d", len(sessions))
+	}
+
+	if sessions[0].NumTrials() != 3 || sessions[2].NumTrials() != 1 {
+		t.Errorf("unexpected number of trials: %d & %d", sessions[0].NumTrials(), sessions[2].NumTrials())
+	}
+
+	// Each condition uses the same seeds.
+	if *sessions[0].Model.RandomSeed != *sessions[2].Model.RandomSeed || *sessions[0].Model.RandomSeed == *sessions[1].Model.RandomSeed {
+		t.Errorf("unexpected seeds")
+	}
+
+	// reset: one session for each presentation of each trial
+	e.Memory = MemoryReset
+	sessions = e.Sessions(model, []string{"ccm", "pyactr"}, "tmp", nil)
+	if len(sessions) != (3+1)*2*2 {
+		t.Errorf("expected 16 sessions, got %d", len(sessions))
+	}
+}
+
+func TestExtractAndWriteCSV(t *testing.T) {
+	e := testExperiment()
+
+	trace := framework.NewTrace()
+	trace.ProductionFired("start", 0.4)
+	trace.Printed = []string{"3"}
+	trace.RetrievalFailures = 1
+
+	retrieval := Measure{Name: "retrieved", Type: MeasureRetrieval}
+	if value := retrieval.Extract(trace, 0.3); value != RetrievalFailure {
+		t.Errorf("expected retrieval failure, got %q", value)
+	}
+
+	results := []TrialResult{{Framework: "ccm", Condition: "a", Repetition: 1, Trial: "first", Presentation: 1, Measures: map[string]string{}}}
+	for _, measure := range e.Measures {
+		results[0].Measures[measure.Name] = measure.Extract(trace, 0.3)
+	}
+
+	results = append(results, TrialResult{Framework: "ccm", Condition: "b", Repetition: 1, Trial: "1", Presentation: 1, Error: "failed"})
+
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, e.Measures, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `framework,condition,repetition,trial,presentation,seed,status,rt,answer,error
+ccm,a,1,first,1,,ok,0.1,3,
+ccm,b,1,1,1,,error,,,failed
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,103 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/runstats"
+)
+
+// Types of measures.
+const (
+	MeasureLatency   = "latency"   // time from the start of the trial until a production first fired
+	MeasureDuration  = "duration"  // time from the start to the end of the trial
+	MeasurePrinted   = "printed"   // lines printed during the trial
+	MeasureRetrieval = "retrieval" // outcome of the trial's retrievals: "success", "failure", or "none"
+)
+
+// Retrieval outcomes.
+const (
+	RetrievalSuccess = "success" // all retrievals succeeded
+	RetrievalFailure = "failure" // at least one retrieval failed
+	RetrievalNone    = "none"    // nothing was retrieved
+)
+
+// Measure is a value to extract from each trial.
+type Measure struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Production string `json:"production,omitempty"` // production to time when using "latency"
+}
+
+func (m Measure) validate(model *actr.Model) (err error) {
+	if m.Name == "" {
+		return errors.New("each measure requires a name")
+	}
+
+	switch m.Type {
+	case MeasureLatency:
+		if m.Production == "" {
+			return fmt.Errorf("measure %q requires a production", m.Name)
+		}
+
+		for _, production := range model.Productions {
+			if production.Name == m.Production {
+				return
+			}
+		}
+
+		return fmt.Errorf("measure %q: model does not have a production named %q", m.Name, m.Production)
+
+	case MeasureDuration, MeasurePrinted, MeasureRetrieval:
+		if m.Production != "" {
+			return fmt.Errorf("measure %q: only %q measures use a production", m.Name, MeasureLatency)
+		}
+
+	default:
+		return fmt.Errorf("measure %q: unknown type %q (must be %s, %s, %s, or %s)", m.Name, m.Type, MeasureLatency, MeasureDuration, MeasurePrinted, MeasureRetrieval)
+	}
+
+	return
+}
+
+// Extract returns the measure's value from the trace of a trial which started at "start". It
+// returns an empty string if it doesn't have a value (e.g. the production didn't fire).
+func (m Measure) Extract(trace *framework.Trace, start float64) string {
+	switch m.Type {
+	case MeasureLatency:
+		fired, ok := trace.FiredAt[m.Production]
+		if !ok {
+			return ""
+		}
+
+		return formatTime(fired - start)
+
+	case MeasureDuration:
+		return formatTime(math.Max(trace.EndTime-start, 0))
+
+	case MeasurePrinted:
+		return strings.Join(trace.Printed, "\n")
+
+	case MeasureRetrieval:
+		switch {
+		case trace.RetrievalFailures > 0:
+			return RetrievalFailure
+		case trace.Retrievals > 0:
+			return RetrievalSuccess
+		default:
+			return RetrievalNone
+		}
+	}
+
+	return ""
+}
+
+// formatTime formats a time without rounding errors from subtracting the start time.
+func formatTime(t float64) string {
+	return runstats.FormatFloat(math.Round(t*1e9) / 1e9)
+}
@@ -0,0 +1,185 @@
+package experiment
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/filesystem"
+)
+
+// presentation is one presentation of a trial.
+type presentation struct {
+	trial  int // index of the trial in the condition
+	number int // presentation of the trial (starting at 1)
+}
+
+// Session is one run of a model on a framework. With carry-over memory, a session runs all the
+// trials of a condition. Otherwise each presentation of a trial is its own session.
+type Session struct {
+	Framework  string
+	Condition  int // index of the condition
+	Repetition int // repetition of the experiment (starting at 1)
+	Dir        string
+	Model      *actr.Model
+
+	presentations []presentation
+
+	Results []TrialResult // set by Execute
+}
+
+// TrialResult is the outcome of one presentation of a trial.
+type TrialResult struct {
+	Framework    string            `json:"framework"`
+	Condition    string            `json:"condition"`
+	Repetition   int               `json:"repetition"`   // repetition of the experiment (starting at 1)
+	Trial        string            `json:"trial"`        // name or number of the trial
+	Presentation int               `json:"presentation"` // presentation of the trial (starting at 1)
+	Seed         *uint32           `json:"seed,omitempty"`
+	Measures     map[string]string `json:"measures,omitempty"`
+	Trace        *framework.Trace  `json:"trace,omitempty"`
+	Error        string            `json:"error,omitempty"` // set if the run failed
+}
+
+// Sessions creates the sessions to run the experiment on each framework. Each one writes its
+// code to its own directory under "dir" so they may be run at the same time. If "seed" is nil,
+// the model's random seed is used.
+//
+// When seeded, each condition uses the same seeds so the differences are due to the conditions.
+func (e Experiment) Sessions(model *actr.Model, frameworkNames []string, dir string, seed *uint32) (sessions []*Session) {
+	repeat := e.Repeat
+	if repeat < 1 {
+		repeat = 1
+	}
+
+	if seed == nil {
+		seed = model.RandomSeed
+	}
+
+	for c, condition := range e.Conditions {
+		// Group the presentations into runs.
+		var runs [][]presentation
+		var sequence []presentation
+
+		for t, trial := range condition.Trials {
+			count := trial.Repeat
+			if count < 1 {
+				count = 1
+			}
+
+			for n := 1; n <= count; n++ {
+				p := presentation{trial: t, number: n}
+
+				if e.Memory == MemoryCarryOver {
+					sequence = append(sequence, p)
+				} else {
+					runs = append(runs, []presentation{p})
+				}
+			}
+		}
+
+		if e.Memory == MemoryCarryOver {
+			runs = append(runs, sequence)
+		}
+
+		for _, name := range frameworkNames {
+			run := 1
+
+			for r := 1; r <= repeat; r++ {
+				for _, presentations := range runs {
+					session := &Session{
+						Framework:     name,
+						Condition:     c,
+						Repetition:    r,
+						Dir:           filepath.Join(dir, fmt.Sprintf("condition-%d", c+1), fmt.Sprintf("%s-run-%d", name, run)),
+						Model:         model,
+						presentations: presentations,
+					}
+
+					if seed != nil {
+						session.Model = model.WithRandomSeed(actr.DeriveSeed(*seed, run))
+					}
+
+					sessions = append(sessions, session)
+					run++
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// NumTrials returns the number of trial presentations in the session.
+func (s Session) NumTrials() int {
+	return len(s.presentations)
+}
+
+// Execute runs the session using a new instance of the framework and sets its results.
+func (s *Session) Execute(e Experiment, prototype framework.Framework) {
+	condition := e.Conditions[s.Condition]
+
+	trials := make([]framework.InitialBuffers, len(s.presentations))
+	for i, p := range s.presentations {
+		trials[i] = condition.Trials[p.trial].Buffers
+	}
+
+	runResults, err := s.run(prototype, trials)
+
+	s.Results = make([]TrialResult, len(s.presentations))
+	for i, p := range s.presentations {
+		result := TrialResult{
+			Framework:    s.Framework,
+			Condition:    condition.Name,
+			Repetition:   s.Repetition,
+			Trial:        condition.Trials[p.trial].label(p.trial),
+			Presentation: p.number,
+			Seed:         s.Model.RandomSeed,
+		}
+
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+
+		case i >= len(runResults) || runResults[i].Trace == nil:
+			result.Error = "no output for trial"
+
+		default:
+			runResult := runResults[i]
+
+			result.Trace = runResult.Trace
+			result.Measures = make(map[string]string, len(e.Measures))
+
+			for _, measure := range e.Measures {
+				result.Measures[measure.Name] = measure.Extract(runResult.Trace, runResult.StartTime)
+			}
+		}
+
+		s.Results[i] = result
+	}
+}
+
+func (s *Session) run(prototype framework.Framework, trials []framework.InitialBuffers) (results []*framework.RunResult, err error) {
+	f := prototype.Clone(s.Dir)
+
+	log := f.ValidateModel(s.Model)
+	if log.HasError() {
+		err = fmt.Errorf("%s", strings.TrimSpace(log.String()))
+		return
+	}
+
+	err = filesystem.CreateDir(s.Dir)
+	if err != nil {
+		return
+	}
+
+	err = f.SetModel(s.Model)
+	if err != nil {
+		return
+	}
+
+	return f.RunTrials(trials)
+}
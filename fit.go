@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/fit"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/sweep"
+
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/validate"
+)
+
+// fitAction is used by the "fit" command.
+func fitAction(c *cli.Context) (err error) {
+	results, err := newResultWriter(c)
+	if err != nil {
+		return
+	}
+
+	progress := results.progressWriter()
+
+	frameworks, err := setup(c, progress)
+	if err != nil {
+		return
+	}
+
+	return handleFit(c, frameworks, results, progress)
+}
+
+// handleFit searches for the parameter values which best fit the data and outputs them along with
+// the fit stats & the prediction for each condition. Everything other than the JSON result is
+// output to progress.
+func handleFit(ctx *cli.Context, frameworks framework.List, results *resultWriter, progress io.Writer) (err error) {
+	showVersion(ctx, progress)
+
+	if ctx.Args().Len() != 1 {
+		err = cli.Exit("fit requires one amod file", exitUsage)
+		return
+	}
+
+	// Fit to one framework since they may not agree.
+	names := ctx.StringSlice("framework")
+	if len(names) != 1 || names[0] == "all" {
+		err = cli.Exit("fit requires one framework (e.g. -f ccm)", exitUsage)
+		return
+	}
+
+	file := ctx.Args().First()
+
+	f := fit.Fit{
+		Framework:      names[0],
+		Measure:        ctx.String("measure"),
+		Objective:      fit.Objective(ctx.String("objective")),
+		Repeat:         ctx.Int("repeat"),
+		MaxEvaluations: ctx.Int("max-evals"),
+		Dir:            filepath.Join(ctx.Path("temp"), "fit"),
+	}
+
+	f.Seed, err = seedFlagValue(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, spec := range listFlagValues(ctx, "param") {
+		var param fit.Param
+		param, err = fit.ParseParam(spec)
+		if err != nil {
+			err = cli.Exit(err, exitUsage)
+			return
+		}
+
+		f.Params = append(f.Params, param)
+	}
+
+	f.Conditions, err = fitConditions(ctx)
+	if err != nil {
+		err = cli.Exit(err, exitUsage)
+		return
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	f.Source = string(source)
+	f.SourceDir = filepath.Dir(file)
+
+	fmt.Fprintf(progress, "Generating model for %s\n", file)
+	model, log, err := amod.GenerateModelInDir(f.Source, f.SourceDir)
+	if err == nil {
+		for _, condition := range f.Conditions {
+			validate.Goal(model, condition.Goal, log)
+		}
+	}
+	fmt.Fprint(progress, log)
+
+	if err != nil || log.HasError() {
+		err = cli.Exit(fmt.Sprintf("%s has errors", file), exitCompile)
+		return
+	}
+
+	err = f.Validate()
+	if err != nil {
+		err = cli.Exit(err, exitUsage)
+		return
+	}
+
+	frameworks, _ = initializeFrameworks(frameworks, progress)
+	if len(frameworks) == 0 {
+		err = cli.Exit(fmt.Sprintf("could not initialize %s", f.Framework), exitFramework)
+		return
+	}
+
+	if f.Seed == nil && model.RandomSeed == nil {
+		fmt.Fprintln(progress, "Note: the runs are not seeded, so the fit may vary each time (see --seed)")
+	}
+
+	if f.Repeat < 1 {
+		f.Repeat = 1
+	}
+
+	fmt.Fprintf(progress, "Fitting %d parameters to %d conditions using %s (%d runs per condition)\n", len(f.Params), len(f.Conditions), f.Framework, f.Repeat)
+
+	run := func(tasks []*sweep.Task) {
+		runTasks(tasks, frameworks, ctx.Int("jobs"))
+	}
+
+	evaluated := func(e fit.Evaluation) {
+		fmt.Fprintf(progress, "Evaluation %d: %s: ", e.Number, formatFitValues(f.Params, e.Values))
+
+		if e.Err != nil {
+			fmt.Fprintf(progress, "failed - %s\n", firstLine(e.Err.Error()))
+			return
+		}
+
+		fmt.Fprintf(progress, "RMSE %.4g, r %s\n", e.RMSE, formatCorrelation(e.Correlation))
+	}
+
+	result, err := f.Run(run, evaluated)
+	if err != nil {
+		err = cli.Exit(err, exitRun)
+		return
+	}
+
+	outputFit(progress, result)
+	results.encode(result)
+
+	return
+}
+
+// fitConditions reads the data file and combines it with the goal for each condition.
+func fitConditions(ctx *cli.Context) (conditions []fit.Condition, err error) {
+	goals := map[string]string{}
+	for _, spec := range listFlagValues(ctx, "condition") {
+		name, goal, found := strings.Cut(spec, "=")
+		if !found || strings.TrimSpace(name) == "" || strings.TrimSpace(goal) == "" {
+			err = fmt.Errorf("condition %q should be in the form 'name=initial goal' (e.g. 'easy=countFrom: 2 4 starting')", spec)
+			return
+		}
+
+		goals[strings.TrimSpace(name)] = strings.TrimSpace(goal)
+	}
+
+	path := ctx.Path("data")
+	if path == "" {
+		err = fmt.Errorf("fit requires a data file (--data)")
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	observations, err := fit.ReadData(file, ctx.String("column"))
+	if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+		return
+	}
+
+	for _, observation := range observations {
+		goal, ok := goals[observation.Condition]
+		if !ok {
+			err = fmt.Errorf("no initial goal for condition %q (use --condition '%s=<goal>')", observation.Condition, observation.Condition)
+			return
+		}
+
+		delete(goals, observation.Condition)
+
+		conditions = append(conditions, fit.Condition{
+			Name:     observation.Condition,
+			Goal:     goal,
+			Observed: observation.Value,
+		})
+	}
+
+	for name := range goals {
+		err = fmt.Errorf("condition %q is not in %s", name, path)
+		return
+	}
+
+	return
+}
+
+// outputFit outputs the best-fit values, the fit stats, and the prediction for each condition to
+// progress.
+func outputFit(progress io.Writer, result *fit.Result) {
+	fmt.Fprintln(progress)
+
+	if result.Converged {
+		fmt.Fprintf(progress, "Best fit after %d evaluations:\n", result.Evaluations)
+	} else {
+		fmt.Fprintf(progress, "Best fit after %d evaluations (stopped before converging - see --max-evals):\n", result.Evaluations)
+	}
+
+	w := tabwriter.NewWriter(progress, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "  PARAMETER\tVALUE\tLOWER\tUPPER")
+	for _, param := range result.Params {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", param.Name, numbers.Float64Str(param.Value), numbers.Float64Str(param.Lower), numbers.Float64Str(param.Upper))
+	}
+	w.Flush()
+
+	fmt.Fprintf(progress, "  RMSE: %.4g\n", result.RMSE)
+	fmt.Fprintf(progress, "  Correlation: %s\n", formatCorrelation(result.Correlation))
+
+	fmt.Fprintf(progress, "Predictions (%s):\n", result.Measure)
+
+	fmt.Fprintln(w, "  CONDITION\tOBSERVED\tPREDICTED\tSD\tRUNS\tFAILED")
+	for _, p := range result.Predictions {
+		fmt.Fprintf(w, "  %s\t%.4g\t%.4g\t%.4g\t%d\t%d\n", p.Condition, p.Observed, p.Predicted, p.SD, p.Runs, p.Failures)
+	}
+	w.Flush()
+}
+
+func formatFitValues(params []fit.Param, values []float64) string {
+	list := make([]string, len(params))
+	for i, param := range params {
+		list[i] = fmt.Sprintf("%s=%s", param.Name, numbers.Float64Str(values[i]))
+	}
+
+	return strings.Join(list, " ")
+}
+
+func formatCorrelation(r *float64) string {
+	if r == nil {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.4g", *r)
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// listFlag collects the values of a flag which may be repeated. We can't use a string slice
+// since it splits values on commas, which may be in the values (e.g. goals).
+type listFlag struct {
+	values []string
+}
+
+// serializedListPrefix marks the value the cli package uses to copy the values between the
+// flag's name & alias.
+const serializedListPrefix = "serialized-list:"
+
+func (l *listFlag) Set(value string) (err error) {
+	if strings.HasPrefix(value, serializedListPrefix) {
+		return json.Unmarshal([]byte(strings.TrimPrefix(value, serializedListPrefix)), &l.values)
+	}
+
+	l.values = append(l.values, value)
+	return
+}
+
+func (l *listFlag) String() string {
+	return strings.Join(l.values, " ")
+}
+
+// Serialize implements cli.Serializer so the values are copied properly.
+func (l *listFlag) Serialize() string {
+	data, _ := json.Marshal(l.values)
+	return serializedListPrefix + string(data)
+}
+
+// listFlagValues returns the values of a listFlag.
+func listFlagValues(ctx *cli.Context, name string) []string {
+	if list, ok := ctx.Generic(name).(*listFlag); ok {
+		return list.values
+	}
+
+	return nil
+}
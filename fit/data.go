@@ -0,0 +1,111 @@
+package fit
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ConditionColumn is the column in the data file which names the condition of each row.
+const ConditionColumn = "condition"
+
+// Observation is the empirical value for a condition.
+type Observation struct {
+	Condition string  `json:"condition"`
+	Value     float64 `json:"value"` // mean of the rows for the condition
+	Rows      int     `json:"rows"`  // number of rows for the condition
+}
+
+// ReadData reads a CSV file with a header row. Each row has a "condition" column and a column
+// with the observed value (e.g. a response time or accuracy). If there is more than one row for
+// a condition (e.g. one for each participant), they are averaged. The observations are in the
+// order the conditions first appear.
+func ReadData(r io.Reader, column string) (observations []Observation, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("data file is empty")
+		}
+		return
+	}
+
+	conditionIndex, valueIndex := -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case ConditionColumn:
+			conditionIndex = i
+		case column:
+			valueIndex = i
+		}
+	}
+
+	if conditionIndex == -1 {
+		err = fmt.Errorf("data file does not have a %q column", ConditionColumn)
+		return
+	}
+
+	if valueIndex == -1 {
+		err = fmt.Errorf("data file does not have a %q column", column)
+		return
+	}
+
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	order := []string{}
+
+	for {
+		var row []string
+		row, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		condition := strings.TrimSpace(row[conditionIndex])
+		if condition == "" {
+			err = fmt.Errorf("line %d: missing condition", line)
+			return
+		}
+
+		var value float64
+		value, err = strconv.ParseFloat(strings.TrimSpace(row[valueIndex]), 64)
+		if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+			err = fmt.Errorf("line %d: %q is not a number", line, row[valueIndex])
+			return
+		}
+
+		if _, ok := counts[condition]; !ok {
+			order = append(order, condition)
+		}
+
+		sums[condition] += value
+		counts[condition]++
+	}
+
+	if len(order) == 0 {
+		err = errors.New("data file has no rows")
+		return
+	}
+
+	for _, condition := range order {
+		observations = append(observations, Observation{
+			Condition: condition,
+			Value:     sums[condition] / float64(counts[condition]),
+			Rows:      counts[condition],
+		})
+	}
+
+	return
+}
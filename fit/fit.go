@@ -0,0 +1,360 @@
+// Package fit searches for the module parameter values which make a model's predictions best
+// match empirical data.
+//
+// Each condition in the data is run using its own initial goal. The model is run repeatedly for
+// each condition at each set of parameter values and the mean of a measure (e.g. "end_time") is
+// compared with the observed value. The runs are done as sweep tasks so the caller can decide how
+// to schedule them.
+package fit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/sweep"
+
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/runstats"
+)
+
+// Objective is what the fit tries to optimize.
+type Objective string
+
+const (
+	MinimizeRMSE        Objective = "rmse"        // minimize the root mean squared error
+	MaximizeCorrelation Objective = "correlation" // maximize the correlation
+)
+
+// precision is the number of significant digits used for parameter values. This keeps the values
+// written to the generated code readable.
+const precision = 6
+
+// Condition is an experimental condition: the initial goal to run the model with and the value
+// observed for it.
+type Condition struct {
+	Name     string
+	Goal     string
+	Observed float64
+}
+
+// Fit describes the model, the data to fit, and how to run it.
+type Fit struct {
+	Source     string // text of the amod file
+	SourceDir  string // directory data files loaded by the amod are relative to (empty if it may not load any)
+	Framework  string // name of the framework to run on
+	Conditions []Condition
+	Params     []Param
+
+	Measure   string // measure from the run stats to compare with the data (e.g. "end_time")
+	Objective Objective
+
+	Repeat         int     // number of times to run each condition at each set of values
+	Seed           *uint32 // if set, overrides the model's random seed
+	MaxEvaluations int     // most sets of parameter values to try
+
+	Dir string // directory to write the generated code to
+}
+
+// RunFunc executes the tasks. They may be run at the same time.
+type RunFunc func(tasks []*sweep.Task)
+
+// Prediction compares the model's prediction for a condition with the observed value.
+type Prediction struct {
+	Condition string  `json:"condition"`
+	Observed  float64 `json:"observed"`
+	Predicted float64 `json:"predicted"` // mean of the measure over the runs
+	SD        float64 `json:"sd"`        // standard deviation of the measure over the runs
+	Runs      int     `json:"runs"`      // number of successful runs
+	Failures  int     `json:"failures"`  // number of failed runs
+}
+
+// Evaluation is the result of running the model at one set of parameter values.
+type Evaluation struct {
+	Number      int          // evaluations are numbered starting at 1
+	Values      []float64    // one for each of the fit's params (in the same order)
+	Predictions []Prediction // one for each condition (in the same order)
+	RMSE        float64
+	Correlation *float64 // nil if it can't be computed (e.g. the predictions don't vary)
+	Err         error    // set if the model couldn't be run for every condition
+}
+
+// FittedParam is a parameter with its best-fit value.
+type FittedParam struct {
+	Param
+	Value float64 `json:"value"`
+}
+
+// Result is the best fit found.
+type Result struct {
+	Framework   string        `json:"framework"`
+	Measure     string        `json:"measure"`
+	Objective   Objective     `json:"objective"`
+	Params      []FittedParam `json:"params"`
+	RMSE        float64       `json:"rmse"`
+	Correlation *float64      `json:"correlation,omitempty"` // not set if it can't be computed
+	Predictions []Prediction  `json:"predictions"`
+	Evaluations int           `json:"evaluations"` // number of sets of parameter values tried
+	Converged   bool          `json:"converged"`   // false if we stopped at the maximum number of evaluations
+}
+
+// Validate checks the fit's settings and that the model can use the parameters & measure.
+func (f Fit) Validate() (err error) {
+	if len(f.Params) == 0 {
+		return errors.New("no parameters to fit")
+	}
+
+	if len(f.Conditions) == 0 {
+		return errors.New("no conditions to fit")
+	}
+
+	if f.Objective != MinimizeRMSE && f.Objective != MaximizeCorrelation {
+		return fmt.Errorf("unknown objective %q (must be %q or %q)", f.Objective, MinimizeRMSE, MaximizeCorrelation)
+	}
+
+	if f.Objective == MaximizeCorrelation && len(f.Conditions) < 3 {
+		return errors.New("fitting the correlation requires at least 3 conditions")
+	}
+
+	// Set the parameters to their starting values to check them.
+	s := sweep.Sweep{Source: f.Source, SourceDir: f.SourceDir}
+	for _, param := range f.Params {
+		s.Params = append(s.Params, sweep.Param{Name: param.Name, Values: []string{numbers.Float64Str(param.Start)}})
+	}
+
+	points, err := s.Points()
+	if err != nil {
+		return
+	}
+
+	return validMeasure(points[0].Model, f.Measure)
+}
+
+// validMeasure checks that the measure is one the fit can use with the model.
+func validMeasure(model *actr.Model, name string) (err error) {
+	switch name {
+	case "end_time", "retrievals", "retrieval_failures", "retrieval_success_rate":
+		return
+
+	default:
+		if strings.HasPrefix(name, "fired:") {
+			production := strings.TrimPrefix(name, "fired:")
+			for _, p := range model.Productions {
+				if p.Name == production {
+					return
+				}
+			}
+
+			return fmt.Errorf("measure %q: model does not have a production named %q", name, production)
+		}
+	}
+
+	return fmt.Errorf("unknown measure %q (must be end_time, retrievals, retrieval_failures, retrieval_success_rate, or fired:<production name>)", name)
+}
+
+// Run searches for the best-fit parameter values. "progress" is called after each evaluation.
+func (f Fit) Run(run RunFunc, progress func(Evaluation)) (result *Result, err error) {
+	err = f.Validate()
+	if err != nil {
+		return
+	}
+
+	n := len(f.Params)
+	start, lower, upper := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, param := range f.Params {
+		start[i], lower[i], upper[i] = param.Start, param.Lower, param.Upper
+	}
+
+	// The search may return to the same (rounded) values, so keep the evaluations to avoid
+	// running them again.
+	evaluations := map[string]*Evaluation{}
+
+	objective := func(x []float64) (value float64, err error) {
+		x = round(x)
+
+		key := valuesKey(x)
+		e, ok := evaluations[key]
+		if !ok {
+			e, err = f.evaluate(x, run)
+			if err != nil {
+				return
+			}
+
+			e.Number = len(evaluations) + 1
+			evaluations[key] = e
+
+			if progress != nil {
+				progress(*e)
+			}
+		}
+
+		return f.objectiveValue(e), nil
+	}
+
+	maxCalls := f.MaxEvaluations
+	if maxCalls < n+1 {
+		maxCalls = n + 1
+	}
+
+	minimum, err := Minimize(objective, start, lower, upper, maxCalls)
+	if err != nil {
+		return
+	}
+
+	best := evaluations[valuesKey(round(minimum.X))]
+	if best.Err != nil {
+		err = fmt.Errorf("could not run the model using any of the parameter values tried: %w", best.Err)
+		return
+	}
+
+	result = &Result{
+		Framework:   f.Framework,
+		Measure:     f.Measure,
+		Objective:   f.Objective,
+		RMSE:        best.RMSE,
+		Correlation: best.Correlation,
+		Predictions: best.Predictions,
+		Evaluations: len(evaluations),
+		Converged:   minimum.Converged,
+	}
+
+	for i, param := range f.Params {
+		result.Params = append(result.Params, FittedParam{Param: param, Value: best.Values[i]})
+	}
+
+	return
+}
+
+// evaluate runs the model for each condition using the parameter values. Errors in the model or
+// the values stop the search and are returned. Failed runs are recorded in the evaluation.
+func (f Fit) evaluate(x []float64, run RunFunc) (e *Evaluation, err error) {
+	e = &Evaluation{Values: x}
+
+	params := make([]sweep.Param, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = sweep.Param{Name: param.Name, Values: []string{numbers.Float64Str(x[i])}}
+	}
+
+	points := make([][]sweep.Point, len(f.Conditions))
+	tasks := make([][]*sweep.Task, len(f.Conditions))
+	all := []*sweep.Task{}
+
+	for i, condition := range f.Conditions {
+		s := sweep.Sweep{
+			Source:         f.Source,
+			SourceDir:      f.SourceDir,
+			InitialBuffers: framework.InitialBuffers{"goal": condition.Goal},
+			Params:         params,
+			Repeat:         f.Repeat,
+			Seed:           f.Seed,
+		}
+
+		points[i], err = s.Points()
+		if err != nil {
+			return
+		}
+
+		tasks[i] = s.Tasks(points[i], []string{f.Framework}, filepath.Join(f.Dir, fmt.Sprintf("condition-%d", i+1)))
+		all = append(all, tasks[i]...)
+	}
+
+	run(all)
+
+	observed := make([]float64, len(f.Conditions))
+	predicted := make([]float64, len(f.Conditions))
+
+	for i, condition := range f.Conditions {
+		stats := sweep.Collect(points[i], tasks[i])[0]
+
+		if stats.Stats.Runs == 0 {
+			e.Err = fmt.Errorf("all runs failed for condition %q", condition.Name)
+			if len(stats.Errors) > 0 {
+				e.Err = fmt.Errorf("%w: %s", e.Err, stats.Errors[0])
+			}
+			return
+		}
+
+		summary, ok := measureSummary(stats.Stats, f.Measure)
+		if !ok {
+			e.Err = fmt.Errorf("%s is not available for condition %q", f.Measure, condition.Name)
+			return
+		}
+
+		observed[i] = condition.Observed
+		predicted[i] = summary.Mean
+
+		e.Predictions = append(e.Predictions, Prediction{
+			Condition: condition.Name,
+			Observed:  condition.Observed,
+			Predicted: summary.Mean,
+			SD:        summary.SD,
+			Runs:      stats.Stats.Runs,
+			Failures:  stats.Stats.Failures,
+		})
+	}
+
+	e.RMSE = RMSE(observed, predicted)
+
+	if r := Correlation(observed, predicted); !math.IsNaN(r) {
+		e.Correlation = &r
+	}
+
+	return
+}
+
+// objectiveValue returns the value to minimize for the evaluation.
+func (f Fit) objectiveValue(e *Evaluation) float64 {
+	if e.Err != nil {
+		return math.Inf(1)
+	}
+
+	if f.Objective == MaximizeCorrelation {
+		// Treat a correlation we can't compute as no correlation.
+		if e.Correlation == nil {
+			return 1
+		}
+
+		return 1 - *e.Correlation
+	}
+
+	return e.RMSE
+}
+
+// measureSummary finds the measure in the stats. A production which never fired counts as 0.
+func measureSummary(stats *runstats.Stats, name string) (summary runstats.Summary, ok bool) {
+	for _, measure := range stats.Measures() {
+		if measure.Name == name {
+			return measure.Summary, true
+		}
+	}
+
+	if strings.HasPrefix(name, "fired:") {
+		return runstats.Summary{}, true
+	}
+
+	return
+}
+
+// round rounds the values to "precision" significant digits.
+func round(x []float64) []float64 {
+	rounded := make([]float64, len(x))
+	for i, value := range x {
+		rounded[i], _ = strconv.ParseFloat(strconv.FormatFloat(value, 'g', precision, 64), 64)
+	}
+
+	return rounded
+}
+
+func valuesKey(x []float64) string {
+	values := make([]string, len(x))
+	for i, value := range x {
+		values[i] = numbers.Float64Str(value)
+	}
+
+	return strings.Join(values, ",")
+}
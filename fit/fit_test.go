@@ -0,0 +1,224 @@
+package fit
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/sweep"
+)
+
+const testSource = `
+==model==
+name: Test
+==config==
+gactar { random_seed: 42 }
+chunks { [count: first second] }
+==init==
+==productions==
+start {
+	match { goal [count: * *] }
+	do { clear goal }
+}`
+
+func TestParseParam(t *testing.T) {
+	param, err := ParseParam("memory.latency_factor=0.1:0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if param.Lower != 0.1 || param.Upper != 0.5 || param.Start != 0.3 {
+		t.Errorf("unexpected param: %+v", param)
+	}
+
+	param, err = ParseParam("memory.retrieval_threshold=-2:0:-1.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if param.Start != -1.5 {
+		t.Errorf("unexpected start: %v", param.Start)
+	}
+
+	invalid := []string{
+		"memory.latency_factor",
+		"latency_factor=0:1",
+		"memory.latency_factor=1",
+		"memory.latency_factor=1:0",
+		"memory.latency_factor=0:1:2",
+		"memory.latency_factor=a:1",
+	}
+
+	for _, spec := range invalid {
+		if _, err := ParseParam(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestReadData(t *testing.T) {
+	data := "participant,condition,rt\n1,easy,0.5\n1,hard,1.0\n2,easy,0.7\n2,hard,1.2\n"
+
+	observations, err := ReadData(strings.NewReader(data), "rt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(observations) != 2 {
+		t.Fatalf("expected 2 conditions, got %d", len(observations))
+	}
+
+	easy := observations[0]
+	if easy.Condition != "easy" || math.Abs(easy.Value-0.6) > 1e-9 || easy.Rows != 2 {
+		t.Errorf("unexpected observation: %+v", easy)
+	}
+
+	_, err = ReadData(strings.NewReader(data), "accuracy")
+	if err == nil || !strings.Contains(err.Error(), `"accuracy" column`) {
+		t.Errorf("expected missing column error, got %v", err)
+	}
+
+	_, err = ReadData(strings.NewReader("condition,rt\neasy,fast\n"), "rt")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error on line 2, got %v", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	observed := []float64{1, 2, 3}
+
+	if rmse := RMSE(observed, []float64{2, 3, 4}); rmse != 1 {
+		t.Errorf("expected RMSE of 1, got %v", rmse)
+	}
+
+	if r := Correlation(observed, []float64{2, 4, 6}); math.Abs(r-1) > 1e-9 {
+		t.Errorf("expected correlation of 1, got %v", r)
+	}
+
+	if r := Correlation(observed, []float64{1, 1, 1}); !math.IsNaN(r) {
+		t.Errorf("expected NaN correlation when predictions don't vary, got %v", r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	f := Fit{
+		Source:    testSource,
+		Framework: "ccm",
+		Conditions: []Condition{
+			{Name: "easy", Goal: "count: 1 2", Observed: 1},
+			{Name: "hard", Goal: "count: 3 4", Observed: 2},
+		},
+		Params:         []Param{{Name: "memory.latency_factor", Lower: 0.1, Upper: 2, Start: 1}},
+		Measure:        "end_time",
+		Objective:      MinimizeRMSE,
+		Repeat:         2,
+		MaxEvaluations: 100,
+		Dir:            t.TempDir(),
+	}
+
+	// Pretend to run the model: the end time is proportional to the latency factor and is twice
+	// as long for the hard condition, so the best fit is 0.5.
+	runs := 0
+	run := func(tasks []*sweep.Task) {
+		for _, task := range tasks {
+			runs++
+
+			factor := 2.0
+			if task.InitialBuffers["goal"] == "count: 3 4" {
+				factor = 4
+			}
+
+			trace := framework.NewTrace()
+			trace.EndTime = factor * *task.Model.Memory.LatencyFactor
+			task.Trace = trace
+		}
+	}
+
+	evaluations := 0
+	result, err := f.Run(run, func(e Evaluation) { evaluations++ })
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if math.Abs(result.Params[0].Value-0.5) > 1e-3 {
+		t.Errorf("expected best fit of 0.5, got %v", result.Params[0].Value)
+	}
+
+	if !result.Converged || result.RMSE > 1e-3 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if result.Evaluations != evaluations || runs != evaluations*4 {
+		t.Errorf("expected each evaluation to run each condition twice: %d evaluations, %d runs", evaluations, runs)
+	}
+
+	if len(result.Predictions) != 2 || result.Predictions[1].Condition != "hard" || result.Predictions[1].Runs != 2 {
+		t.Errorf("unexpected predictions: %+v", result.Predictions)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	f := Fit{
+		Source:         testSource,
+		Framework:      "ccm",
+		Conditions:     []Condition{{Name: "easy", Goal: "count: 1 2", Observed: 1}},
+		Params:         []Param{{Name: "memory.latency_factor", Lower: 0.1, Upper: 2, Start: 1}},
+		Measure:        "end_time",
+		Objective:      MinimizeRMSE,
+		MaxEvaluations: 10,
+		Dir:            t.TempDir(),
+	}
+
+	// Every run fails.
+	fail := func(tasks []*sweep.Task) {
+		for _, task := range tasks {
+			task.Err = errors.New("framework exploded")
+		}
+	}
+
+	_, err := f.Run(fail, nil)
+	if err == nil || !strings.Contains(err.Error(), "all runs failed") || !strings.Contains(err.Error(), "framework exploded") {
+		t.Errorf("expected run failure error, got %v", err)
+	}
+
+	// An unknown parameter stops the search.
+	f.Params[0].Name = "memory.foo"
+	_, err = f.Run(fail, nil)
+	if err == nil || strings.Contains(err.Error(), "all runs failed") {
+		t.Errorf("expected parameter error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Fit{
+		Source:     testSource,
+		Conditions: []Condition{{Name: "easy", Goal: "count: 1 2", Observed: 1}},
+		Params:     []Param{{Name: "memory.latency_factor", Lower: 0.1, Upper: 2, Start: 1}},
+		Measure:    "fired:start",
+		Objective:  MinimizeRMSE,
+	}
+
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	tests := map[string]func(f *Fit){
+		"unknown measure":    func(f *Fit) { f.Measure = "accuracy" },
+		"unknown production": func(f *Fit) { f.Measure = "fired:stop" },
+		"unknown objective":  func(f *Fit) { f.Objective = "mse" },
+		"too few conditions": func(f *Fit) { f.Objective = MaximizeCorrelation },
+		"unknown parameter":  func(f *Fit) { f.Params = []Param{{Name: "memory.foo", Start: 1}} },
+		"no parameters":      func(f *Fit) { f.Params = nil },
+	}
+
+	for name, change := range tests {
+		f := valid
+		change(&f)
+
+		if err := f.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
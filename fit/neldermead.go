@@ -0,0 +1,208 @@
+package fit
+
+import (
+	"math"
+	"sort"
+)
+
+// Nelder–Mead coefficients (the usual ones).
+const (
+	reflection  = 1.0
+	expansion   = 2.0
+	contraction = 0.5
+	shrink      = 0.5
+)
+
+// Tolerances used to decide when the simplex has converged.
+const (
+	valueTolerance    = 1e-6 // spread of the objective values (relative to the best one)
+	positionTolerance = 1e-4 // size of the simplex (relative to the range of each parameter)
+)
+
+// initialStep is the size of the initial simplex relative to the range of each parameter.
+const initialStep = 0.25
+
+// Function computes the value to minimize at x. It returns an error to stop the search.
+type Function func(x []float64) (value float64, err error)
+
+// Minimum is the result of a search.
+type Minimum struct {
+	X         []float64 // position of the lowest value found
+	Value     float64   // lowest value found
+	Calls     int       // number of times the objective was called
+	Converged bool      // false if we stopped because we reached the maximum number of calls
+}
+
+type vertex struct {
+	x     []float64
+	value float64
+}
+
+// Minimize searches for the minimum of f using the Nelder–Mead simplex method starting at
+// "start". Each position is kept within the lower & upper bounds by clamping it. It stops when
+// the simplex converges or f has been called maxCalls times.
+func Minimize(f Function, start, lower, upper []float64, maxCalls int) (minimum Minimum, err error) {
+	n := len(start)
+
+	evaluate := func(x []float64) (v vertex, err error) {
+		v.x = clamp(x, lower, upper)
+		v.value, err = f(v.x)
+		if math.IsNaN(v.value) {
+			v.value = math.Inf(1)
+		}
+
+		minimum.Calls++
+		return
+	}
+
+	// The initial simplex has the start and one vertex offset along each axis. Offset towards
+	// the middle of the range so we stay inside the bounds.
+	simplex := make([]vertex, n+1)
+
+	simplex[0], err = evaluate(start)
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		x := append([]float64{}, start...)
+
+		step := initialStep * (upper[i] - lower[i])
+		if x[i]+step > upper[i] {
+			step = -step
+		}
+		x[i] += step
+
+		simplex[i+1], err = evaluate(x)
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		sort.SliceStable(simplex, func(i, j int) bool { return simplex[i].value < simplex[j].value })
+
+		if converged(simplex, lower, upper) {
+			minimum.Converged = true
+			break
+		}
+
+		if minimum.Calls >= maxCalls {
+			break
+		}
+
+		best, worst := simplex[0], simplex[n]
+		middle := centroid(simplex[:n])
+
+		var reflected vertex
+		reflected, err = evaluate(move(middle, worst.x, -reflection))
+		if err != nil {
+			return
+		}
+
+		switch {
+		case reflected.value < best.value:
+			var expanded vertex
+			expanded, err = evaluate(move(middle, worst.x, -expansion))
+			if err != nil {
+				return
+			}
+
+			if expanded.value < reflected.value {
+				simplex[n] = expanded
+			} else {
+				simplex[n] = reflected
+			}
+
+		case reflected.value < simplex[n-1].value:
+			simplex[n] = reflected
+
+		default:
+			// Contract towards the better of the worst & reflected points.
+			towards := worst
+			if reflected.value < worst.value {
+				towards = reflected
+			}
+
+			var contracted vertex
+			contracted, err = evaluate(move(middle, towards.x, contraction))
+			if err != nil {
+				return
+			}
+
+			if contracted.value < towards.value {
+				simplex[n] = contracted
+				break
+			}
+
+			// Nothing was better, so shrink everything towards the best vertex.
+			for i := 1; i <= n; i++ {
+				simplex[i], err = evaluate(move(best.x, simplex[i].x, shrink))
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	minimum.X = simplex[0].x
+	minimum.Value = simplex[0].value
+
+	return
+}
+
+// converged checks whether the values & positions of the (sorted) simplex are close together.
+func converged(simplex []vertex, lower, upper []float64) bool {
+	best := simplex[0]
+	worst := simplex[len(simplex)-1]
+
+	if math.IsInf(worst.value, 1) {
+		return false
+	}
+
+	if worst.value-best.value > valueTolerance*math.Max(math.Abs(best.value), 1e-10) {
+		return false
+	}
+
+	for _, v := range simplex[1:] {
+		for i := range v.x {
+			if math.Abs(v.x[i]-best.x[i]) > positionTolerance*(upper[i]-lower[i]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// centroid returns the centre of the vertices.
+func centroid(vertices []vertex) []float64 {
+	c := make([]float64, len(vertices[0].x))
+
+	for _, v := range vertices {
+		for i := range c {
+			c[i] += v.x[i] / float64(len(vertices))
+		}
+	}
+
+	return c
+}
+
+// move returns from + t * (to - from).
+func move(from, to []float64, t float64) []float64 {
+	x := make([]float64, len(from))
+	for i := range x {
+		x[i] = from[i] + t*(to[i]-from[i])
+	}
+
+	return x
+}
+
+func clamp(x, lower, upper []float64) []float64 {
+	clamped := make([]float64, len(x))
+	for i := range x {
+		clamped[i] = math.Min(math.Max(x[i], lower[i]), upper[i])
+	}
+
+	return clamped
+}
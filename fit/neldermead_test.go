@@ -0,0 +1,72 @@
+package fit
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	// Rosenbrock function with its minimum at (1, 1)
+	rosenbrock := func(x []float64) (float64, error) {
+		a, b := 1-x[0], x[1]-x[0]*x[0]
+		return a*a + 100*b*b, nil
+	}
+
+	minimum, err := Minimize(rosenbrock, []float64{-1, 2}, []float64{-2, -2}, []float64{2, 3}, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !minimum.Converged {
+		t.Errorf("expected the search to converge (%d calls)", minimum.Calls)
+	}
+
+	if math.Abs(minimum.X[0]-1) > 0.01 || math.Abs(minimum.X[1]-1) > 0.01 {
+		t.Errorf("expected minimum at (1, 1), got %v", minimum.X)
+	}
+}
+
+func TestMinimizeBounds(t *testing.T) {
+	// The minimum is outside the bounds, so we should end up at the upper bound.
+	f := func(x []float64) (float64, error) {
+		return (x[0] - 5) * (x[0] - 5), nil
+	}
+
+	minimum, err := Minimize(f, []float64{0}, []float64{-1}, []float64{1}, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if math.Abs(minimum.X[0]-1) > 1e-3 {
+		t.Errorf("expected minimum at the upper bound, got %v", minimum.X)
+	}
+}
+
+func TestMinimizeLimits(t *testing.T) {
+	calls := 0
+	f := func(x []float64) (float64, error) {
+		calls++
+		return x[0]*x[0] + x[1]*x[1], nil
+	}
+
+	minimum, err := Minimize(f, []float64{0.9, 0.9}, []float64{-1, -1}, []float64{1, 1}, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if minimum.Converged {
+		t.Errorf("expected the search to stop before converging")
+	}
+
+	// Each iteration may call f more than once before we check the limit.
+	if calls != minimum.Calls || calls > 5+4 {
+		t.Errorf("unexpected number of calls: %d (reported %d)", calls, minimum.Calls)
+	}
+
+	stop := errors.New("stop")
+	_, err = Minimize(func(x []float64) (float64, error) { return 0, stop }, []float64{0}, []float64{-1}, []float64{1}, 5)
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the function's error, got %v", err)
+	}
+}
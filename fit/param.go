@@ -0,0 +1,69 @@
+package fit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Param is a module parameter to fit and the range of values it may take.
+type Param struct {
+	Name  string  `json:"name"`  // module & parameter name (e.g. "memory.latency_factor")
+	Lower float64 `json:"lower"` // lowest value
+	Upper float64 `json:"upper"` // highest value
+	Start float64 `json:"start"` // value to start the search at
+}
+
+// ParseParam parses a parameter from the command line. It is in the form "name=lower:upper" or
+// "name=lower:upper:start". If the start isn't given, the search starts in the middle.
+func ParseParam(spec string) (param Param, err error) {
+	name, bounds, found := strings.Cut(spec, "=")
+	if !found || name == "" || bounds == "" {
+		err = fmt.Errorf("parameter %q should be in the form 'module.param=lower:upper' (e.g. memory.latency_factor=0.05:1.0)", spec)
+		return
+	}
+
+	if !strings.Contains(name, ".") {
+		err = fmt.Errorf("parameter %q should be in the form 'module.param' (e.g. memory.latency_factor)", name)
+		return
+	}
+
+	parts := strings.Split(bounds, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		err = fmt.Errorf("parameter %q: bounds %q should be in the form 'lower:upper' or 'lower:upper:start'", name, bounds)
+		return
+	}
+
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsInf(values[i], 0) || math.IsNaN(values[i]) {
+			err = fmt.Errorf("parameter %q: %q is not a number", name, part)
+			return
+		}
+	}
+
+	param = Param{
+		Name:  name,
+		Lower: values[0],
+		Upper: values[1],
+		Start: (values[0] + values[1]) / 2,
+	}
+
+	if len(values) == 3 {
+		param.Start = values[2]
+	}
+
+	if param.Upper <= param.Lower {
+		err = fmt.Errorf("parameter %q: the lower bound must be less than the upper bound", name)
+		return
+	}
+
+	if param.Start < param.Lower || param.Start > param.Upper {
+		err = fmt.Errorf("parameter %q: the start must be between the lower & upper bounds", name)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,45 @@
+package fit
+
+import "math"
+
+// RMSE returns the root mean squared error between the observed & predicted values.
+func RMSE(observed, predicted []float64) float64 {
+	sum := 0.0
+	for i := range observed {
+		diff := predicted[i] - observed[i]
+		sum += diff * diff
+	}
+
+	return math.Sqrt(sum / float64(len(observed)))
+}
+
+// Correlation returns the Pearson correlation between the observed & predicted values. It is NaN
+// if there are fewer than two values or either set of values doesn't vary.
+func Correlation(observed, predicted []float64) float64 {
+	n := float64(len(observed))
+	if n < 2 {
+		return math.NaN()
+	}
+
+	meanObserved, meanPredicted := 0.0, 0.0
+	for i := range observed {
+		meanObserved += observed[i] / n
+		meanPredicted += predicted[i] / n
+	}
+
+	covariance, varObserved, varPredicted := 0.0, 0.0, 0.0
+	for i := range observed {
+		o := observed[i] - meanObserved
+		p := predicted[i] - meanPredicted
+
+		covariance += o * p
+		varObserved += o * o
+		varPredicted += p * p
+	}
+
+	if varObserved == 0 || varPredicted == 0 {
+		return math.NaN()
+	}
+
+	return covariance / math.Sqrt(varObserved*varPredicted)
+}
@@ -2,6 +2,7 @@ package ccm_pyactr
 
 import (
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 	"time"
@@ -44,12 +45,16 @@ func New(ctx *cli.Context) (c *CCMPyACTR, err error) {
 	return
 }
 
+func (CCMPyACTR) Clone(tmpPath string) framework.Framework {
+	return &CCMPyACTR{tmpPath: tmpPath}
+}
+
 func (CCMPyACTR) Info() *framework.Info {
 	return &Info
 }
 
-func (c *CCMPyACTR) Initialize() (err error) {
-	return framework.Setup(&Info)
+func (c *CCMPyACTR) Initialize(out io.Writer) (err error) {
+	return framework.Setup(&Info, out)
 }
 
 func (CCMPyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
@@ -59,6 +64,8 @@ func (CCMPyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 		log.Warning(nil, "ccm does not support memory module's latency_exponent")
 	}
 
+	framework.WarnSubtypeRecalls(log, "ccm", model)
+
 	return
 }
 
@@ -82,36 +89,80 @@ func (c CCMPyACTR) Model() (model *actr.Model) {
 // Run generates the python code from the amod file, writes it to disk, creates a "run" file
 // to actually run the model, and returns the output (stdout and stderr combined).
 func (c *CCMPyACTR) Run(initialBuffers framework.InitialBuffers) (result *framework.RunResult, err error) {
-	runFile, err := c.WriteModel(c.tmpPath, initialBuffers)
+	results, err := c.RunTrials([]framework.InitialBuffers{initialBuffers})
+	if len(results) > 0 {
+		result = results[0]
+	}
+
+	return
+}
+
+// RunTrials generates the python code with each trial after the first setting its buffers and
+// running the model again, runs it, and returns the output of each trial.
+func (c *CCMPyACTR) RunTrials(trials []framework.InitialBuffers) (results []*framework.RunResult, err error) {
+	if len(trials) == 0 {
+		err = fmt.Errorf("no trials to run")
+		return
+	}
+
+	nextTrials, err := framework.ParseTrials(c.model, trials[1:])
 	if err != nil {
 		return
 	}
 
-	result = &framework.RunResult{
-		FileName:      runFile,
-		GeneratedCode: c.GetContents(),
+	runFile, err := c.writeModel(c.tmpPath, trials[0], nextTrials)
+	if err != nil {
+		return
 	}
 
+	code := c.GetContents()
+
 	cmd := exec.Command("python3", runFile)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		err = fmt.Errorf("%s", string(output))
-		return
+		return []*framework.RunResult{{FileName: runFile, GeneratedCode: code}}, err
 	}
 
-	result.Output = output
+	outputs, startTimes := framework.SplitTrials(output)
+	if len(trials) == 1 {
+		outputs = [][]byte{output}
+	}
+
+	for i, trialOutput := range outputs {
+		measures, trialOutput := framework.ParseMeasures(c.model, trialOutput)
+		state, trialOutput := framework.ParseState(c.model, trialOutput)
+		trace := parseTrace(trialOutput)
+		framework.MergeSubtypeProductions(c.model, trace)
+
+		results = append(results, &framework.RunResult{
+			FileName:      runFile,
+			GeneratedCode: code,
+			Output:        trialOutput,
+			Trace:         trace,
+			Measures:      measures,
+			State:         state,
+			Seed:          c.model.RandomSeed,
+			StartTime:     startTimes[i],
+		})
+	}
 
 	return
 }
 
 // WriteModel converts the internal actr.Model to python and writes it to a file.
 func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuffers) (outputFileName string, err error) {
+	return c.writeModel(path, initialBuffers, nil)
+}
+
+// writeModel writes the model with the initial buffers of the first trial. Each of the
+// nextTrials sets its buffers and runs the model again.
+func (c *CCMPyACTR) writeModel(path string, initialBuffers framework.InitialBuffers, nextTrials []framework.ParsedInitialBuffers) (outputFileName string, err error) {
 	patterns, err := framework.ParseInitialBuffers(c.model, initialBuffers)
 	if err != nil {
 		return
 	}
-	goal := patterns["goal"]
 
 	outputFileName = fmt.Sprintf("%s.py", c.className)
 	if path != "" {
@@ -154,6 +205,10 @@ func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuff
 		additionalImports = append(additionalImports, "DMSpreading")
 	}
 
+	if c.model.HasMetadata() {
+		additionalImports = append(additionalImports, "DMBaseLevel")
+	}
+
 	if len(additionalImports) > 0 {
 		c.Write("from python_actr import %s\n", strings.Join(additionalImports, ", "))
 	}
@@ -162,8 +217,26 @@ func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuff
 		c.Writeln("from python_actr import log, log_everything")
 	}
 
+	if c.model.DumpState {
+		c.Writeln("")
+		c.Writeln("import json")
+	}
+
+	if c.model.HasMetadata() {
+		c.Writeln("")
+		c.Writeln("import math")
+	}
+
+	if c.model.RandomSeed != nil {
+		c.Writeln("")
+		c.Writeln("import random")
+		c.Writeln("random.seed(%d)", *c.model.RandomSeed)
+	}
+
 	c.Write("\n\n")
 
+	c.outputBaseLevelClass()
+
 	c.Writeln("class %s(ACTR):", c.className)
 
 	for _, buffer := range c.model.BufferNames() {
@@ -209,6 +282,11 @@ func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuff
 		c.Writeln("")
 	}
 
+	if c.model.HasMetadata() {
+		c.Writeln("\tbase_level = gactar_base_level(%s)", memory.ModuleName())
+		c.Writeln("")
+	}
+
 	procedural := c.model.Procedural
 	if procedural.DefaultActionTime != nil {
 		c.Writeln("\tproduction_time = %s", numbers.Float64Str(*procedural.DefaultActionTime))
@@ -223,14 +301,16 @@ func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuff
 		c.Writeln("")
 	}
 
-	if len(c.model.Initializers) > 0 {
+	userBuffers := patterns.BufferNames()
+
+	if len(c.model.Initializers) > 0 || len(userBuffers) > 0 {
 		c.Writeln("\tdef init():")
 
 		for _, init := range c.model.Initializers {
 			module := init.Module
 
-			// allow the user-set goal to override the initializer
-			if module.ModuleName() == "goal" && (goal != nil) {
+			// allow the user-set buffers to override the initializer
+			if patterns[module.BufferName()] != nil {
 				continue
 			}
 
@@ -242,49 +322,62 @@ func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuff
 				c.Write("\t\t%s.set(", module.ModuleName())
 			}
 
-			c.outputPattern(init.Pattern)
+			c.outputNamedPattern(init.Pattern, init.ChunkName)
 			c.Writeln(")")
+
+			if init.Metadata != nil {
+				c.Writeln("\t\tbase_level.set_metadata(%s.dm[-1]%s)", module.ModuleName(), chunkMetadataArgs(init.Metadata))
+			}
 		}
 
-		c.Writeln("")
-	}
+		// Add user-set buffers if any
+		for _, name := range userBuffers {
+			c.Write("\t\t%s.set(", name)
+			c.outputPattern(patterns[name])
+			c.Writeln(")")
+		}
 
-	// Add user-set goal if any
-	if goal != nil {
-		c.Write("\t\tgoal.set(")
-		c.outputPattern(goal)
-		c.Write(")\n\n")
+		c.Writeln("")
 	}
 
 	for _, production := range c.model.Productions {
-		if production.Description != nil {
-			c.Writeln("\t# %s", *production.Description)
-		}
+		for _, variant := range framework.SubtypeProductions(c.model, production) {
+			if variant.Description != nil {
+				c.Writeln("\t# %s", *variant.Description)
+			}
 
-		c.Writeln("\t# amod line %d", production.AMODLineNumber)
+			c.Writeln("\t# amod line %d", variant.AMODLineNumber)
 
-		c.Write("\tdef %s(", production.Name)
+			c.Write("\tdef %s(", variant.Name)
 
-		numMatches := len(production.Matches)
-		for i, match := range production.Matches {
-			c.outputMatch(match)
+			numMatches := len(variant.Matches)
+			for i, match := range variant.Matches {
+				c.outputMatch(match)
 
-			if i != numMatches-1 {
-				c.Write(", ")
+				if i != numMatches-1 {
+					c.Write(", ")
+				}
 			}
-		}
 
-		c.Writeln("):")
+			c.Writeln("):")
 
-		if production.DoStatements != nil {
-			for _, statement := range production.DoStatements {
-				c.outputStatement(statement)
+			if variant.DoStatements != nil {
+				for _, statement := range variant.DoStatements {
+					c.outputStatement(statement)
+				}
 			}
-		}
 
-		c.Write("\n")
+			for _, measure := range c.model.MeasuresFiredBy(production) {
+				c.Writeln("\t\tprint('%s %s', self.now())", framework.MeasureMarker, measure.Name)
+			}
+
+			c.Write("\n")
+		}
 	}
 
+	c.outputSlotValueFunction()
+	c.outputDumpStateFunction()
+
 	c.Writeln("")
 	c.Writeln("if __name__ == \"__main__\":")
 	c.Writeln(fmt.Sprintf("\tmodel = %s()", c.className))
@@ -295,7 +388,162 @@ func (c *CCMPyACTR) WriteModel(path string, initialBuffers framework.InitialBuff
 	}
 
 	c.Writeln("\tmodel.run()")
+	c.outputSlotMeasures()
+	c.outputDumpState()
+
+	for i, trial := range nextTrials {
+		c.Writeln("")
+		c.Writeln("\tprint('%s %d %%s' %% model.now())", framework.TrialMarker, i+2)
+
+		for _, name := range trial.BufferNames() {
+			c.Write("\tmodel.%s.set(", name)
+			c.outputPattern(trial[name])
+			c.Writeln(")")
+		}
+
+		c.Writeln("\tmodel.run()")
+		c.outputSlotMeasures()
+		c.outputDumpState()
+	}
+
+	return
+}
+
+// outputBaseLevelClass outputs a class which hooks into ccm's base-level learning so chunks in
+// memory may set their initial activation. ccm only knows when a chunk was added during the run,
+// so chunks with references or a creation time use ACT-R's optimized learning equation instead.
+func (c *CCMPyACTR) outputBaseLevelClass() {
+	if !c.model.HasMetadata() {
+		return
+	}
+
+	c.Writeln("class gactar_base_level(DMBaseLevel):")
+	c.Writeln("\tdef __init__(self, memory, decay=0.5):")
+	c.Writeln("\t\tDMBaseLevel.__init__(self, memory, decay=decay)")
+	c.Writeln("\t\tself.decay = decay")
+	c.Writeln("\t\tself.metadata = {}")
+	c.Writeln("")
+	c.Writeln("\tdef set_metadata(self, chunk, base_level=None, references=1, created=0):")
+	c.Writeln("\t\tself.metadata[id(chunk)] = (base_level, references, created)")
+	c.Writeln("")
+	c.Writeln("\tdef activation(self, chunk):")
+	c.Writeln("\t\tif id(chunk) not in self.metadata:")
+	c.Writeln("\t\t\treturn DMBaseLevel.activation(self, chunk)")
+	c.Writeln("\t\tbase_level, references, created = self.metadata[id(chunk)]")
+	c.Writeln("\t\tif base_level is not None:")
+	c.Writeln("\t\t\treturn base_level")
+	c.Writeln("\t\t# avoid log(0) at the time the chunk was created")
+	c.Writeln("\t\tlifetime = max(self.now() - created, 0.05)")
+	c.Writeln("\t\treturn math.log(references / (1 - self.decay)) - self.decay * math.log(lifetime)")
+	c.Writeln("")
+	c.Writeln("")
+}
+
+// chunkMetadataArgs returns the arguments for gactar_base_level.set_metadata().
+func chunkMetadataArgs(metadata *actr.ChunkMetadata) (args string) {
+	if metadata.BaseLevel != nil {
+		args += ", base_level=" + numbers.Float64Str(*metadata.BaseLevel)
+	}
+
+	if metadata.References != nil {
+		args += fmt.Sprintf(", references=%d", *metadata.References)
+	}
+
+	if metadata.Created != nil {
+		args += ", created=" + numbers.Float64Str(*metadata.Created)
+	}
+
+	return
+}
+
+// outputSlotValueFunction outputs a function to look up a slot by name for the model's measures.
+// ccm's chunks store their slots by position, so it is passed the position of the slot in each
+// type of chunk which has it.
+func (c *CCMPyACTR) outputSlotValueFunction() {
+	if len(c.model.SlotMeasures()) == 0 {
+		return
+	}
+
+	c.Writeln("")
+	c.Writeln("def gactar_slot_value(buffer, positions):")
+	c.Writeln("\tchunk = buffer.chunk")
+	c.Writeln("\tif chunk is None or chunk.get('_0') not in positions:")
+	c.Writeln("\t\treturn None")
+	c.Writeln("\treturn chunk.get('_%%d' %% positions[chunk['_0']])")
+	c.Writeln("")
+}
 
+// outputDumpStateFunction outputs a function to output the contents of the buffers & declarative
+// memory as JSON. ccm's chunks store their slots by position, so it needs the slot names of each
+// type of chunk.
+func (c *CCMPyACTR) outputDumpStateFunction() {
+	if !c.model.DumpState {
+		return
+	}
+
+	slotNames := []string{}
+	for _, chunk := range c.model.Chunks {
+		if chunk.IsInternal() {
+			continue
+		}
+
+		slotNames = append(slotNames, fmt.Sprintf("'%s': ['%s']", chunk.Name, strings.Join(chunk.SlotNames, "', '")))
+	}
+
+	buffers := []string{}
+	for _, name := range c.model.BufferNames() {
+		buffers = append(buffers, fmt.Sprintf("'%s': chunk_state(model.%s.chunk)", name, name))
+	}
+
+	c.Writeln("")
+	c.Writeln("def gactar_dump_state(model):")
+	c.Writeln("\tslot_names = {%s}", strings.Join(slotNames, ", "))
+	c.Writeln("")
+	c.Writeln("\tdef chunk_state(chunk):")
+	c.Writeln("\t\tif chunk is None:")
+	c.Writeln("\t\t\treturn None")
+	c.Writeln("\t\tnames = slot_names.get(chunk.get('_0'), [])")
+	c.Writeln("\t\tslots = {name: str(chunk.get('_%%d' %% (i + 1))) for i, name in enumerate(names)}")
+	c.Writeln("\t\treturn {'name': chunk.get('name'), 'type': chunk.get('_0'), 'slots': slots}")
+	c.Writeln("")
+	c.Writeln("\tbuffers = {%s}", strings.Join(buffers, ", "))
+	c.Writeln("\tmemory = []")
+	c.Writeln("\tfor chunk in model.%s.dm:", c.model.Memory.ModuleName())
+	c.Writeln("\t\tstate = chunk_state(chunk)")
+	c.Writeln("\t\ttry:")
+	c.Writeln("\t\t\tstate['activation'] = float(model.%s.get_activation(chunk))", c.model.Memory.ModuleName())
+	c.Writeln("\t\texcept Exception:")
+	c.Writeln("\t\t\tpass")
+	c.Writeln("\t\tmemory.append(state)")
+	c.Writeln("")
+	c.Writeln("\tprint('%s', json.dumps({'buffers': buffers, 'memory': memory}))", framework.StateMarker)
+	c.Writeln("")
+}
+
+// outputDumpState outputs the call to output the state at the end of a run.
+func (c *CCMPyACTR) outputDumpState() {
+	if c.model.DumpState {
+		c.Writeln("\tgactar_dump_state(model)")
+	}
+}
+
+// outputSlotMeasures outputs the value of each slot measure at the end of a run.
+func (c *CCMPyACTR) outputSlotMeasures() {
+	for _, measure := range c.model.SlotMeasures() {
+		positions := []string{}
+		for _, chunk := range c.model.Chunks {
+			if !chunk.IsInternal() && chunk.HasSlot(measure.SlotName) {
+				positions = append(positions, fmt.Sprintf("'%s': %d", chunk.Name, chunk.SlotIndex(measure.SlotName)))
+			}
+		}
+
+		c.Writeln("\tprint('%s %s', gactar_slot_value(model.%s, {%s}))",
+			framework.MeasureMarker, measure.Name, measure.Buffer.BufferName(), strings.Join(positions, ", "))
+	}
+}
+
+// WriteSupportFiles does nothing since the generated python file is all we need.
+func (CCMPyACTR) WriteSupportFiles(path string) (fileNames []string, err error) {
 	return
 }
 
@@ -314,6 +562,13 @@ func (c *CCMPyACTR) outputAuthors() {
 }
 
 func (c *CCMPyACTR) outputPattern(pattern *actr.Pattern) {
+	c.outputNamedPattern(pattern, "")
+}
+
+// outputNamedPattern outputs the pattern for a chunk. ccm's chunks don't have names, so a named
+// chunk stores its name in a "name" slot after its positional slots. References to it are its
+// name, so they resolve to it.
+func (c *CCMPyACTR) outputNamedPattern(pattern *actr.Pattern, name string) {
 	str := fmt.Sprintf("'%s ", pattern.Chunk.Name)
 
 	for i, slot := range pattern.Slots {
@@ -324,6 +579,10 @@ func (c *CCMPyACTR) outputPattern(pattern *actr.Pattern) {
 		}
 	}
 
+	if name != "" {
+		str += " name:" + name
+	}
+
 	str += "'"
 
 	c.Write(str)
@@ -368,6 +627,9 @@ func patternSlotString(patternSlot *actr.PatternSlot) string {
 			str += *item.Var
 		} else if item.Num != nil {
 			str += *item.Num
+		} else if item.ChunkRef != nil {
+			// refers to the chunk with this name (see outputNamedPattern)
+			str += *item.ChunkRef
 		}
 	}
 
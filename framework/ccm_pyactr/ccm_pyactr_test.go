@@ -0,0 +1,88 @@
+package ccm_pyactr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/framework/frameworktest"
+)
+
+func TestChunkMetadata(t *testing.T) {
+	code, log := frameworktest.Generate(t, &CCMPyACTR{}, frameworktest.MetadataSource)
+
+	if log.HasIssues() {
+		t.Errorf("expected ccm to support chunk metadata, got %s", log)
+	}
+
+	frameworktest.ExpectContains(t, code, []string{
+		"from python_actr import DMBaseLevel",
+		"class gactar_base_level(DMBaseLevel):",
+		"\tbase_level = gactar_base_level(memory)",
+		"\t\tmemory.add('count 0 1')\n\t\tbase_level.set_metadata(memory.dm[-1], base_level=0.5)\n",
+		"\t\tmemory.add('count 1 2')\n\t\tbase_level.set_metadata(memory.dm[-1], references=10, created=-100)\n",
+		"\t\tmemory.add('count 2 3')\n\n",
+	})
+}
+
+func TestNoChunkMetadata(t *testing.T) {
+	code, _ := frameworktest.Generate(t, &CCMPyACTR{}, `
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory { [count: 0 1] }
+	==productions==`)
+
+	if strings.Contains(code, "base_level") {
+		t.Errorf("expected generated code without metadata not to use base levels:\n%s", code)
+	}
+}
+
+func TestNamedChunks(t *testing.T) {
+	code, _ := frameworktest.Generate(t, &CCMPyACTR{}, `
+	==model==
+	name: Test
+	==config==
+	chunks { [node: value next] }
+	==init==
+	memory {
+		three: [node: c nil]
+		two: [node: b &three]
+		[node: a &two]
+	}
+	==productions==
+	skip {
+		match { retrieval [node: ?value &three] }
+		do { print ?value }
+	}`)
+
+	frameworktest.ExpectContains(t, code, []string{
+		"\t\tmemory.add('node c None name:three')\n",
+		"\t\tmemory.add('node b three name:two')\n",
+		"\t\tmemory.add('node a two')\n",
+		"\tdef skip(retrieval='node ?value three'):\n",
+	})
+}
+
+func TestSubtypeMatches(t *testing.T) {
+	code, _ := frameworktest.Generate(t, &CCMPyACTR{}, `
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(number): name]
+	}
+	==init==
+	==productions==
+	report {
+		match { goal [number: ?value] }
+		do { print ?value }
+	}`)
+
+	frameworktest.ExpectContains(t, code, []string{
+		"\tdef report(goal='number ?value'):\n",
+		"\tdef report_goal_digit(goal='digit ?value ?'):\n",
+	})
+}
@@ -0,0 +1,55 @@
+package ccm_pyactr
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+// ccm's log lines look like "   0.100 production increment".
+var logLineRegex = regexp.MustCompile(`^\s*(\d+\.\d+) (\S+) ?(.*)$`)
+
+// parseTrace creates a trace from ccm's output. Lines which aren't log lines are from print
+// statements until we reach the summary ("Total time:") or the end of the run ("end...").
+func parseTrace(output []byte) (trace *framework.Trace) {
+	trace = framework.NewTrace()
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "Total time:") || line == "end..." {
+			break
+		}
+
+		matches := logLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			if strings.TrimSpace(line) != "" {
+				trace.Printed = append(trace.Printed, line)
+			}
+			continue
+		}
+
+		time, _ := strconv.ParseFloat(matches[1], 64)
+		trace.SetTime(time)
+
+		key, value := matches[2], matches[3]
+
+		switch {
+		case key == "production" && value != "None":
+			trace.ProductionFired(value, time)
+
+		case key == "retrieval.chunk" && value != "None":
+			trace.Retrievals++
+
+		case key == "memory.error" && value == "True":
+			trace.RetrievalFailures++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,44 @@
+package ccm_pyactr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	output := `   0.000 production_match_delay 0
+   0.000 memory.error False
+   0.000 retrieval.chunk None
+   0.050 production start
+   0.100 retrieval.chunk count 2 3
+   0.100 production increment
+   0.150 production None
+2
+   0.200 memory.error True
+   0.250 production stop
+the end
+Total time:    3.250
+ goal.chunk None
+end...
+`
+
+	trace := parseTrace([]byte(output))
+
+	expectedProductions := map[string]int{"start": 1, "increment": 1, "stop": 1}
+	if !reflect.DeepEqual(trace.Productions, expectedProductions) {
+		t.Errorf("expected productions %v, got %v", expectedProductions, trace.Productions)
+	}
+
+	if trace.Retrievals != 1 || trace.RetrievalFailures != 1 {
+		t.Errorf("expected 1 retrieval & 1 failure, got %d & %d", trace.Retrievals, trace.RetrievalFailures)
+	}
+
+	if trace.EndTime != 0.25 {
+		t.Errorf("expected end time 0.25, got %v", trace.EndTime)
+	}
+
+	expectedPrinted := []string{"2", "the end"}
+	if !reflect.DeepEqual(trace.Printed, expectedPrinted) {
+		t.Errorf("expected printed %q, got %q", expectedPrinted, trace.Printed)
+	}
+}
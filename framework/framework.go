@@ -1,6 +1,9 @@
 package framework
 
 import (
+	"io"
+	"sort"
+
 	"github.com/asmaloney/gactar/actr"
 
 	"github.com/asmaloney/gactar/util/container"
@@ -18,7 +21,8 @@ type Info struct {
 
 	FileExtension string `json:"fileExtension"` // file extension of the intermediate file
 
-	ExecutableName string `json:"executableName"` // name of the executable to run
+	ExecutableName    string `json:"executableName"`              // name of the executable to run
+	ExecutableVersion string `json:"executableVersion,omitempty"` // version of the executable (set by Setup())
 
 	PythonRequiredPackages []string `json:"pythonRequiredPackages,omitempty"` // (Python only) List of packages this framework requires
 }
@@ -27,22 +31,41 @@ type InfoList = []Info
 
 // RunResult is the result of a Run() call which runs the code using the framework's executable.
 type RunResult struct {
-	FileName      string // full path to the intermediate file
-	GeneratedCode []byte // code which was run
-	Output        []byte // resulting output (stdout + stderr)
+	FileName      string   // full path to the intermediate file
+	GeneratedCode []byte   // code which was run
+	Output        []byte   // resulting output (stdout + stderr) without the measures
+	Trace         *Trace   // summary of the run parsed from the output
+	Measures      Measures // values of the model's measures (nil if it doesn't have any)
+	State         *State   // contents of the buffers & memory at the end (nil unless the model's DumpState is set)
+	Seed          *uint32  // random seed the model was run with (nil if it wasn't seeded)
+	StartTime     float64  // simulated time the trial started at (only set by RunTrials)
 }
 
 type Framework interface {
 	Info() *Info
 
-	Initialize() (err error)
+	// Initialize checks that the framework is installed and outputs its version to out.
+	Initialize(out io.Writer) (err error)
 
 	ValidateModel(model *actr.Model) (log *issues.Log)
 	SetModel(model *actr.Model) (err error)
 	Model() (model *actr.Model)
 
 	Run(initialBuffers InitialBuffers) (result *RunResult, err error)
+
+	// RunTrials runs the trials one after the other in one run so declarative memory carries
+	// over from one trial to the next. It returns a result for each trial.
+	RunTrials(trials []InitialBuffers) (results []*RunResult, err error)
+
 	WriteModel(path string, initialBuffers InitialBuffers) (outputFileName string, err error)
+
+	// WriteSupportFiles writes any extra files needed to run the code written by WriteModel()
+	// (which must be called first) and returns their paths.
+	WriteSupportFiles(path string) (fileNames []string, err error)
+
+	// Clone creates a new instance of the framework which writes its intermediate files to tmpPath.
+	// This lets us run models concurrently without sharing state. The model is not copied.
+	Clone(tmpPath string) Framework
 }
 
 type List map[string]Framework
@@ -55,6 +78,18 @@ type InitialBuffers map[string]string
 // This is used when passing in user-defined initial contents e.g. through a web API.
 type ParsedInitialBuffers map[string]*actr.Pattern
 
+// BufferNames returns the sorted names of the buffers which have initial contents.
+func (p ParsedInitialBuffers) BufferNames() (names []string) {
+	for name, pattern := range p {
+		if pattern != nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return
+}
+
 // Names returns all the names of the frameworks in the list.
 func (l List) Names() (names []string) {
 	names = make([]string, len(l))
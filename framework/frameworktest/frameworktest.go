@@ -0,0 +1,72 @@
+// Package frameworktest provides helpers for testing the code written by the frameworks.
+package frameworktest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+// MetadataSource is a model with chunks in memory using each kind of chunk metadata.
+const MetadataSource = `
+==model==
+name: Test
+==config==
+chunks { [count: first second] }
+==init==
+memory {
+	[count: 0 1] { base_level: 0.5 }
+	[count: 1 2] { references: 10, created: -100 }
+	[count: 2 3]
+}
+==productions==`
+
+// Generate compiles the amod source, validates it using the framework, and returns the code the
+// framework writes for it along with the framework's validation log.
+func Generate(t *testing.T, f framework.Framework, source string) (code string, log *issues.Log) {
+	t.Helper()
+
+	model, log, err := amod.GenerateModel(source)
+	if err != nil {
+		t.Fatalf("could not generate model: %s", log)
+	}
+
+	log = f.ValidateModel(model)
+	if log.HasError() {
+		t.Fatalf("could not validate model: %s", log)
+	}
+
+	err = f.SetModel(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileName, err := f.WriteModel(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code = string(contents)
+	return
+}
+
+// ExpectContains checks that the code contains each of the expected strings.
+func ExpectContains(t *testing.T, code string, expected []string) {
+	t.Helper()
+
+	for _, str := range expected {
+		if !strings.Contains(code, str) {
+			t.Errorf("expected generated code to contain %q:\n%s", str, code)
+		}
+	}
+}
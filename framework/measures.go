@@ -0,0 +1,195 @@
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// MeasureMarker starts the lines the generated code outputs to collect the model's measures. It
+// is followed by the name of the measure and its value (e.g. "gactar-measure rt 0.35"). Measures
+// of productions output a line with the time each time the production fires.
+const MeasureMarker = "gactar-measure"
+
+// MeasureValue is the value of one of the model's measures. It is a number, a string, or neither
+// if the measure doesn't have a value (e.g. the production never fired).
+type MeasureValue struct {
+	Number *float64
+	Str    *string
+}
+
+// Measures maps the names of the model's measures to their values.
+type Measures map[string]MeasureValue
+
+// NumberValue creates a value from a number.
+func NumberValue(number float64) MeasureValue {
+	return MeasureValue{Number: &number}
+}
+
+// parseMeasureValue converts the text output by the framework to a value. Each framework has its
+// own name for nil.
+func parseMeasureValue(text string) (value MeasureValue) {
+	switch text {
+	case "", "None", "NIL", "nil":
+		return
+	}
+
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		return NumberValue(number)
+	}
+
+	value.Str = &text
+	return
+}
+
+// IsNil checks if the measure doesn't have a value.
+func (v MeasureValue) IsNil() bool {
+	return v.Number == nil && v.Str == nil
+}
+
+// String returns the value as text using "nil" if it doesn't have one.
+func (v MeasureValue) String() string {
+	switch {
+	case v.Number != nil:
+		return strconv.FormatFloat(*v.Number, 'f', -1, 64)
+	case v.Str != nil:
+		return *v.Str
+	}
+
+	return "nil"
+}
+
+// MarshalJSON outputs the value as a JSON number, string, or null.
+func (v MeasureValue) MarshalJSON() ([]byte, error) {
+	switch {
+	case v.Number != nil:
+		return json.Marshal(*v.Number)
+	case v.Str != nil:
+		return json.Marshal(*v.Str)
+	}
+
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON reads a JSON number, string, or null.
+func (v *MeasureValue) UnmarshalJSON(data []byte) (err error) {
+	*v = MeasureValue{}
+
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return
+	}
+
+	switch value := value.(type) {
+	case float64:
+		v.Number = &value
+	case string:
+		v.Str = &value
+	}
+
+	return
+}
+
+// ParseMeasures collects the model's measures from the lines the generated code output and
+// returns the rest of the output without them.
+func ParseMeasures(model *actr.Model, output []byte) (measures Measures, remaining []byte) {
+	if len(model.Measures) == 0 {
+		return nil, output
+	}
+
+	lines := map[string][]string{}
+	rest := &bytes.Buffer{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !strings.HasPrefix(line, MeasureMarker+" ") {
+			rest.WriteString(line)
+			rest.WriteByte('\n')
+			continue
+		}
+
+		fields := strings.SplitN(strings.TrimPrefix(line, MeasureMarker+" "), " ", 2)
+
+		value := ""
+		if len(fields) == 2 {
+			value = strings.TrimSpace(fields[1])
+		}
+
+		lines[fields[0]] = append(lines[fields[0]], value)
+	}
+
+	measures = make(Measures, len(model.Measures))
+
+	for _, measure := range model.Measures {
+		values := lines[measure.Name]
+
+		switch measure.Kind {
+		case actr.MeasureTimeOf:
+			if len(values) > 0 {
+				measures[measure.Name] = parseMeasureValue(values[0])
+			} else {
+				measures[measure.Name] = MeasureValue{}
+			}
+
+		case actr.MeasureCountOf, actr.MeasureRetrievals:
+			measures[measure.Name] = NumberValue(float64(len(values)))
+
+		case actr.MeasureSlotAtEnd:
+			if len(values) > 0 {
+				measures[measure.Name] = parseMeasureValue(values[len(values)-1])
+			} else {
+				measures[measure.Name] = MeasureValue{}
+			}
+		}
+	}
+
+	return measures, rest.Bytes()
+}
+
+// SetProductionMeasures sets the measures of productions from the trace. This is used by
+// frameworks which can't add code to their productions.
+func SetProductionMeasures(model *actr.Model, measures Measures, trace *Trace) {
+	for _, measure := range model.Measures {
+		switch measure.Kind {
+		case actr.MeasureTimeOf:
+			if time, ok := trace.FiredAt[measure.Production.Name]; ok {
+				measures[measure.Name] = NumberValue(time)
+			}
+
+		case actr.MeasureCountOf:
+			measures[measure.Name] = NumberValue(float64(trace.Productions[measure.Production.Name]))
+
+		case actr.MeasureRetrievals:
+			count := 0
+			for _, production := range model.Productions {
+				if production.HasRecall() {
+					count += trace.Productions[production.Name]
+				}
+			}
+
+			measures[measure.Name] = NumberValue(float64(count))
+		}
+	}
+}
+
+// WriteMeasures writes the values of the model's measures in the order they were declared.
+func WriteMeasures(w io.Writer, model *actr.Model, measures Measures) {
+	if len(measures) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "measures:")
+
+	for _, measure := range model.Measures {
+		fmt.Fprintf(w, "\t%s: %s\n", measure.Name, measures[measure.Name])
+	}
+}
@@ -0,0 +1,117 @@
+package framework
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+)
+
+const measuresSource = `
+==model==
+name: Test
+==config==
+chunks {
+	[question: judgment]
+	[count: first second]
+}
+measures {
+	rt: time_of(respond)
+	never: time_of(other)
+	responses: count_of(respond)
+	requests: retrievals
+	answer: goal.judgment at end
+}
+==init==
+==productions==
+respond {
+	match { goal [question: *] }
+	do {
+		set goal.judgment to 'yes'
+		recall [count: 1 *]
+	}
+}
+other {
+	match { goal [count: * *] }
+	do { clear goal }
+}`
+
+func measuresModel(t *testing.T) *actr.Model {
+	model, log, err := amod.GenerateModel(measuresSource)
+	if err != nil {
+		t.Fatalf("model has errors: %s", log)
+	}
+
+	return model
+}
+
+func TestParseMeasures(t *testing.T) {
+	model := measuresModel(t)
+
+	output := `   0.050 production respond
+gactar-measure rt 0.1
+gactar-measure requests 0.1
+hello
+gactar-measure rt 0.3
+gactar-measure requests 0.3
+gactar-measure answer yes
+`
+
+	measures, remaining := ParseMeasures(model, []byte(output))
+
+	if string(remaining) != "   0.050 production respond\nhello\n" {
+		t.Errorf("expected the measures to be removed from the output, got %q", string(remaining))
+	}
+
+	encoded, err := json.Marshal(measures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"answer":"yes","never":null,"requests":2,"responses":0,"rt":0.1}`
+	if string(encoded) != expected {
+		t.Errorf("expected %s, got %s", expected, string(encoded))
+	}
+
+	var decoded Measures
+	err = json.Unmarshal(encoded, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded["rt"].String() != "0.1" || decoded["answer"].String() != "yes" || !decoded["never"].IsNil() {
+		t.Errorf("unexpected decoded measures: %v", decoded)
+	}
+}
+
+func TestParseMeasuresNil(t *testing.T) {
+	model := measuresModel(t)
+
+	for _, text := range []string{"None", "NIL", ""} {
+		measures, _ := ParseMeasures(model, []byte("gactar-measure answer "+text+"\n"))
+
+		if !measures["answer"].IsNil() {
+			t.Errorf("expected %q to be nil, got %s", text, measures["answer"])
+		}
+	}
+}
+
+func TestSetProductionMeasures(t *testing.T) {
+	model := measuresModel(t)
+
+	measures, _ := ParseMeasures(model, []byte("gactar-measure answer yes\n"))
+
+	trace := NewTrace()
+	trace.ProductionFired("respond", 0.05)
+	trace.ProductionFired("respond", 0.1)
+
+	SetProductionMeasures(model, measures, trace)
+
+	encoded, _ := json.Marshal(measures)
+
+	expected := `{"answer":"yes","never":null,"requests":2,"responses":2,"rt":0.05}`
+	if string(encoded) != expected {
+		t.Errorf("expected %s, got %s", expected, string(encoded))
+	}
+}
@@ -3,6 +3,7 @@ package pyactr
 import (
 	_ "embed"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
@@ -50,12 +51,16 @@ func New(ctx *cli.Context) (p *PyACTR, err error) {
 	return
 }
 
+func (PyACTR) Clone(tmpPath string) framework.Framework {
+	return &PyACTR{tmpPath: tmpPath}
+}
+
 func (PyACTR) Info() *framework.Info {
 	return &Info
 }
 
-func (p *PyACTR) Initialize() (err error) {
-	return framework.Setup(&Info)
+func (p *PyACTR) Initialize(out io.Writer) (err error) {
+	return framework.Setup(&Info, out)
 }
 
 func (PyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
@@ -65,6 +70,16 @@ func (PyACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
 		log.Warning(nil, "pyactr does not support memory module's finst_time")
 	}
 
+	for _, init := range model.Initializers {
+		if init.Metadata != nil && init.Metadata.BaseLevel != nil {
+			location := issues.Location{Line: init.AMODLineNumber}
+			log.Warning(&location, "pyactr does not support chunk metadata base_level - it is ignored")
+			break
+		}
+	}
+
+	framework.WarnSubtypeRecalls(log, "pyactr", model)
+
 	for _, production := range model.Productions {
 		numPrintStatements := 0
 		if production.DoStatements != nil {
@@ -105,16 +120,34 @@ func (p PyACTR) Model() (model *actr.Model) {
 }
 
 func (p *PyACTR) Run(initialBuffers framework.InitialBuffers) (result *framework.RunResult, err error) {
-	runFile, err := p.WriteModel(p.tmpPath, initialBuffers)
+	results, err := p.RunTrials([]framework.InitialBuffers{initialBuffers})
+	if len(results) > 0 {
+		result = results[0]
+	}
+
+	return
+}
+
+// RunTrials generates the python code with each trial after the first setting its buffers and
+// running a new simulation of the same model, runs it, and returns the output of each trial.
+func (p *PyACTR) RunTrials(trials []framework.InitialBuffers) (results []*framework.RunResult, err error) {
+	if len(trials) == 0 {
+		err = fmt.Errorf("no trials to run")
+		return
+	}
+
+	nextTrials, err := framework.ParseTrials(p.model, trials[1:])
 	if err != nil {
 		return
 	}
 
-	result = &framework.RunResult{
-		FileName:      runFile,
-		GeneratedCode: p.GetContents(),
+	runFile, err := p.writeModel(p.tmpPath, trials[0], nextTrials)
+	if err != nil {
+		return
 	}
 
+	code := p.GetContents()
+
 	// run it!
 	cmd := exec.Command("python3", runFile)
 
@@ -122,35 +155,55 @@ func (p *PyACTR) Run(initialBuffers framework.InitialBuffers) (result *framework
 	output = removeWarning(output)
 	if err != nil {
 		err = fmt.Errorf("%s", string(output))
-		return
+		return []*framework.RunResult{{FileName: runFile, GeneratedCode: code}}, err
+	}
+
+	outputs, startTimes := framework.SplitTrials(output)
+	if len(trials) == 1 {
+		outputs = [][]byte{output}
 	}
 
-	result.Output = output
+	for i, trialOutput := range outputs {
+		measures, trialOutput := framework.ParseMeasures(p.model, trialOutput)
+		state, trialOutput := framework.ParseState(p.model, trialOutput)
+		trace := parseTrace(trialOutput)
+		framework.MergeSubtypeProductions(p.model, trace)
+
+		// pyactr's productions can't run our code, so use its trace of the rules which fired.
+		if measures != nil {
+			framework.SetProductionMeasures(p.model, measures, trace)
+		}
+
+		results = append(results, &framework.RunResult{
+			FileName:      runFile,
+			GeneratedCode: code,
+			Output:        trialOutput,
+			Trace:         trace,
+			Measures:      measures,
+			State:         state,
+			Seed:          p.model.RandomSeed,
+			StartTime:     startTimes[i],
+		})
+	}
 
 	return
 }
 
 func (p *PyACTR) WriteModel(path string, initialBuffers framework.InitialBuffers) (outputFileName string, err error) {
+	return p.writeModel(path, initialBuffers, nil)
+}
+
+// writeModel writes the model with the initial buffers of the first trial. Each of the
+// nextTrials sets its buffers and runs a new simulation starting when the last one ended.
+func (p *PyACTR) writeModel(path string, initialBuffers framework.InitialBuffers, nextTrials []framework.ParsedInitialBuffers) (outputFileName string, err error) {
 	patterns, err := framework.ParseInitialBuffers(p.model, initialBuffers)
 	if err != nil {
 		return
 	}
-	goal := patterns["goal"]
 
-	// If our model has a print statement, then write out our support file
-	if p.model.HasPrintStatement() {
-		supportFileName := "pyactr_print.py"
-		if path != "" {
-			supportFileName = fmt.Sprintf("%s/%s", path, supportFileName)
-		}
-
-		file, err := os.OpenFile(supportFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
-		if err != nil {
-			return "", err
-		}
-		defer file.Close()
-
-		file.WriteString(pyactrPrintPython)
+	_, err = p.WriteSupportFiles(path)
+	if err != nil {
+		return
 	}
 
 	outputFileName = fmt.Sprintf("%s.py", p.className)
@@ -188,6 +241,19 @@ func (p *PyACTR) WriteModel(path string, initialBuffers framework.InitialBuffers
 		p.Writeln("import pyactr_print")
 	}
 
+	if p.model.DumpState {
+		p.Writeln("import json")
+	}
+
+	if p.model.RandomSeed != nil {
+		// pyactr uses both numpy's and python's random number generators
+		p.Writeln("")
+		p.Writeln("import random")
+		p.Writeln("import numpy")
+		p.Writeln("random.seed(%d)", *p.model.RandomSeed)
+		p.Writeln("numpy.random.seed(%d)", *p.model.RandomSeed)
+	}
+
 	p.Writeln("")
 
 	memory := p.model.Memory
@@ -232,7 +298,7 @@ func (p *PyACTR) WriteModel(path string, initialBuffers framework.InitialBuffers
 
 	p.Write("\n")
 
-	// chunks
+	// chunks (pyactr doesn't have inheritance, so their slots include their parents' slots)
 	for _, chunk := range p.model.Chunks {
 		if chunk.IsInternal() {
 			continue
@@ -264,24 +330,36 @@ func (p *PyACTR) WriteModel(path string, initialBuffers framework.InitialBuffers
 	}
 
 	// initialize
+	if p.model.HasMetadata() {
+		p.outputAddChunkFunction()
+	}
+
 	for _, init := range p.model.Initializers {
 		module := init.Module
 
-		// allow the user-set goal to override the initializer
-		if module.ModuleName() == "goal" && (goal != nil) {
+		// allow the user-set buffers to override the initializer
+		if patterns[module.BufferName()] != nil {
 			continue
 		}
 
 		p.Writeln("# amod line %d", init.AMODLineNumber)
-		p.Writeln("%s.add(actr.chunkstring(string='''", module.ModuleName())
+
+		if init.Metadata != nil {
+			p.Writeln("gactar_add_chunk(%s, actr.chunkstring(%sstring='''", module.ModuleName(), chunkNameArg(init))
+			p.outputPattern(init.Pattern, 1)
+			p.Writeln("''')%s)", chunkMetadataArgs(init.Metadata))
+			continue
+		}
+
+		p.Writeln("%s.add(actr.chunkstring(%sstring='''", module.ModuleName(), chunkNameArg(init))
 		p.outputPattern(init.Pattern, 1)
 		p.Writeln("'''))")
 	}
 
-	// Add user-set goal if any
-	if goal != nil {
-		p.Writeln("goal.add(actr.chunkstring(string='''")
-		p.outputPattern(goal, 1)
+	// Add user-set buffers if any
+	for _, name := range patterns.BufferNames() {
+		p.Writeln("%s.add(actr.chunkstring(string='''", name)
+		p.outputPattern(patterns[name], 1)
 		p.Writeln("'''))")
 	}
 
@@ -289,38 +367,174 @@ func (p *PyACTR) WriteModel(path string, initialBuffers framework.InitialBuffers
 
 	// productions
 	for _, production := range p.model.Productions {
-		if production.Description != nil {
-			p.Writeln("# %s", *production.Description)
-		}
+		for _, variant := range framework.SubtypeProductions(p.model, production) {
+			if variant.Description != nil {
+				p.Writeln("# %s", *variant.Description)
+			}
 
-		p.Writeln("# amod line %d", production.AMODLineNumber)
+			p.Writeln("# amod line %d", variant.AMODLineNumber)
 
-		p.Writeln("%s.productionstring(name='%s', string='''", p.className, production.Name)
-		for _, match := range production.Matches {
-			p.outputMatch(match)
-		}
+			p.Writeln("%s.productionstring(name='%s', string='''", p.className, variant.Name)
+			for _, match := range variant.Matches {
+				p.outputMatch(match)
+			}
 
-		p.Writeln("\t==>")
+			p.Writeln("\t==>")
 
-		if production.DoStatements != nil {
-			for _, statement := range production.DoStatements {
-				p.outputStatement(production, statement)
+			if variant.DoStatements != nil {
+				for _, statement := range variant.DoStatements {
+					p.outputStatement(variant, statement)
+				}
 			}
-		}
 
-		p.Write("''')\n\n")
+			p.Write("''')\n\n")
+		}
 	}
 
 	p.Writeln("")
 
+	p.outputSlotValueFunction()
+	p.outputDumpStateFunction()
+
 	// ...add our code to run
 	p.Writeln("# Main")
 	p.Writeln("if __name__ == '__main__':")
 	p.Writeln("\tsim = %s.simulation()", p.className)
+	p.outputRun()
+
+	for i, trial := range nextTrials {
+		p.Writeln("")
+		p.Writeln("\tprint('%s %d %%s' %% sim.show_time())", framework.TrialMarker, i+2)
+
+		for _, name := range trial.BufferNames() {
+			p.Writeln("\t%s.add(actr.chunkstring(string='''", name)
+			p.outputPattern(trial[name], 2)
+			p.Writeln("\t'''))")
+		}
+
+		// The model's declarative memory is kept, so start the new simulation where the last one ended.
+		p.Writeln("\tsim = %s.simulation(initial_time=sim.show_time())", p.className)
+		p.outputRun()
+	}
+
+	return
+}
+
+// outputRun outputs the code to run the simulation.
+func (p *PyACTR) outputRun() {
 	p.Writeln("\tsim.run()")
+
+	// Output these before we pop the goal below.
+	for _, measure := range p.model.SlotMeasures() {
+		p.Writeln("\tprint('%s %s', gactar_slot_value(%s, '%s', '%s'))",
+			framework.MeasureMarker, measure.Name, p.className, measure.Buffer.BufferName(), measure.SlotName)
+	}
+
+	if p.model.DumpState {
+		p.Writeln("\tgactar_dump_state(%s)", p.className)
+	}
+
 	// TODO: Add some intelligent output when logging level is info or detail
 	p.Writeln("\tif goal.test_buffer('full') is True:")
 	p.Writeln("\t\tprint('final goal: ' + str(goal.pop()))")
+}
+
+// outputSlotValueFunction outputs a function to look up a slot in a buffer for the model's measures.
+func (p *PyACTR) outputSlotValueFunction() {
+	if len(p.model.SlotMeasures()) == 0 {
+		return
+	}
+
+	p.Writeln("def gactar_slot_value(model, buffer_name, slot_name):")
+	p.Writeln("\tfor chunk in model._ACTRModel__buffers[buffer_name]:")
+	p.Writeln("\t\treturn getattr(chunk, slot_name, None)")
+	p.Writeln("\treturn None")
+	p.Writeln("")
+	p.Writeln("")
+}
+
+// outputAddChunkFunction outputs a function to add a chunk to memory with its references spread
+// evenly from its creation time to the start of the run (which is what ACT-R's optimized learning
+// assumes). pyactr tracks the time of each presentation of a chunk.
+func (p *PyACTR) outputAddChunkFunction() {
+	p.Writeln("def gactar_add_chunk(memory, chunk, references=1, created=0):")
+	p.Writeln("\tfor i in range(references):")
+	p.Writeln("\t\tmemory.add(chunk, time=created - created * i / references)")
+	p.Writeln("")
+	p.Writeln("")
+}
+
+// chunkNameArg returns the name argument for actr.chunkstring() if the chunk is named so other
+// chunks can refer to it.
+func chunkNameArg(init *actr.Initializer) string {
+	if init.ChunkName == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("name='%s', ", init.ChunkName)
+}
+
+// chunkMetadataArgs returns the arguments for gactar_add_chunk. pyactr doesn't support fixed base
+// levels (see ValidateModel).
+func chunkMetadataArgs(metadata *actr.ChunkMetadata) (args string) {
+	if metadata.References != nil {
+		args += fmt.Sprintf(", references=%d", *metadata.References)
+	}
+
+	if metadata.Created != nil {
+		args += ", created=" + numbers.Float64Str(*metadata.Created)
+	}
+
+	return
+}
+
+// outputDumpStateFunction outputs a function to output the contents of the buffers & declarative
+// memory as JSON. pyactr doesn't give us the activations of the chunks.
+func (p *PyACTR) outputDumpStateFunction() {
+	if !p.model.DumpState {
+		return
+	}
+
+	p.Writeln("def gactar_dump_state(model):")
+	p.Writeln("\tdef chunk_state(chunk):")
+	p.Writeln("\t\treturn {'type': chunk.typename, 'slots': {slot: str(value) for slot, value in chunk}}")
+	p.Writeln("")
+	p.Writeln("\tbuffers = {}")
+	p.Writeln("\tfor name, buffer in model._ACTRModel__buffers.items():")
+	p.Writeln("\t\tbuffers[name] = None")
+	p.Writeln("\t\tfor chunk in buffer:")
+	p.Writeln("\t\t\tbuffers[name] = chunk_state(chunk)")
+	p.Writeln("")
+	p.Writeln("\tmemory = [chunk_state(chunk) for chunk in model.decmem]")
+	p.Writeln("")
+	p.Writeln("\tprint('%s', json.dumps({'buffers': buffers, 'memory': memory}))", framework.StateMarker)
+	p.Writeln("")
+	p.Writeln("")
+}
+
+// WriteSupportFiles writes our print support file if the model has a print statement.
+func (p *PyACTR) WriteSupportFiles(path string) (fileNames []string, err error) {
+	if !p.model.HasPrintStatement() {
+		return
+	}
+
+	supportFileName := "pyactr_print.py"
+	if path != "" {
+		supportFileName = fmt.Sprintf("%s/%s", path, supportFileName)
+	}
+
+	file, err := os.OpenFile(supportFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(pyactrPrintPython)
+	if err != nil {
+		return
+	}
+
+	fileNames = []string{supportFileName}
 
 	return
 }
@@ -390,6 +604,9 @@ func addPatternSlot(tabbedItems *framework.KeyValueList, slotName string, patter
 			value += fmt.Sprintf(`"%s"`, *item.ID)
 		} else if item.Num != nil {
 			value += *item.Num
+		} else if item.ChunkRef != nil {
+			// unquoted values refer to chunks
+			value += *item.ChunkRef
 		} else if item.Var != nil {
 			value += "="
 			value += strings.TrimPrefix(*item.Var, "?")
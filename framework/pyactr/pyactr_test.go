@@ -0,0 +1,43 @@
+package pyactr
+
+import (
+	"testing"
+
+	"github.com/asmaloney/gactar/framework/frameworktest"
+)
+
+func TestChunkMetadata(t *testing.T) {
+	code, log := frameworktest.Generate(t, &PyACTR{}, frameworktest.MetadataSource)
+
+	expected := "WARN: pyactr does not support chunk metadata base_level - it is ignored (line 8, col 0)\n"
+	if log.String() != expected {
+		t.Errorf("expected %q, got %q", expected, log.String())
+	}
+
+	frameworktest.ExpectContains(t, code, []string{
+		"def gactar_add_chunk(memory, chunk, references=1, created=0):",
+		"'''), references=10, created=-100)",
+	})
+}
+
+func TestSubtypeMatches(t *testing.T) {
+	code, _ := frameworktest.Generate(t, &PyACTR{}, `
+	==model==
+	name: Test
+	==config==
+	chunks {
+		[number: value]
+		[digit(number): name]
+	}
+	==init==
+	==productions==
+	reset {
+		match { goal [number: *] }
+		do { set goal.value to 0 }
+	}`)
+
+	frameworktest.ExpectContains(t, code, []string{
+		"productionstring(name='reset', string='''\n\t=goal>\n\t\tisa\tnumber\n\t==>\n\t=goal>\n\t\tisa\t\tnumber\n",
+		"productionstring(name='reset_goal_digit', string='''\n\t=goal>\n\t\tisa\tdigit\n\t==>\n\t=goal>\n\t\tisa\t\tdigit\n",
+	})
+}
@@ -0,0 +1,51 @@
+package pyactr
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+// pyactr's trace lines look like "(0.05, 'PROCEDURAL', 'RULE FIRED: increment')".
+var traceLineRegex = regexp.MustCompile(`^\((\d+(?:\.\d+)?), ['"]([^'"]*)['"], ['"](.*)['"]\)$`)
+
+// parseTrace creates a trace from pyactr's output. Lines which aren't trace lines are from print
+// statements except for the final goal which we output ourselves.
+func parseTrace(output []byte) (trace *framework.Trace) {
+	trace = framework.NewTrace()
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		matches := traceLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "final goal: ") {
+				trace.Printed = append(trace.Printed, line)
+			}
+			continue
+		}
+
+		time, _ := strconv.ParseFloat(matches[1], 64)
+		trace.SetTime(time)
+
+		module, event := matches[2], matches[3]
+
+		switch {
+		case module == "PROCEDURAL" && strings.HasPrefix(event, "RULE FIRED: "):
+			trace.ProductionFired(strings.TrimPrefix(event, "RULE FIRED: "), time)
+
+		case module == "retrieval" && strings.HasPrefix(event, "RETRIEVED: "):
+			trace.Retrievals++
+
+		case module == "retrieval" && strings.HasPrefix(event, "RETRIEVAL FAIL"):
+			trace.RetrievalFailures++
+		}
+	}
+
+	return
+}
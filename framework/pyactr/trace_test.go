@@ -0,0 +1,40 @@
+package pyactr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	output := `(0, 'PROCEDURAL', 'CONFLICT RESOLUTION')
+(0, 'PROCEDURAL', 'RULE SELECTED: start')
+(0.05, 'PROCEDURAL', 'RULE FIRED: start')
+(0.05, 'retrieval', 'START RETRIEVAL')
+(0.1, 'retrieval', 'RETRIEVED: count(first= 2, second= 3)')
+(0.15, 'PROCEDURAL', 'RULE FIRED: increment')
+2
+(0.2, 'retrieval', 'RETRIEVAL FAILURE')
+(0.25, 'PROCEDURAL', 'NO RULE FOUND')
+final goal: countFrom(end= 3, start= 3, count= stop)
+`
+
+	trace := parseTrace([]byte(output))
+
+	expectedProductions := map[string]int{"start": 1, "increment": 1}
+	if !reflect.DeepEqual(trace.Productions, expectedProductions) {
+		t.Errorf("expected productions %v, got %v", expectedProductions, trace.Productions)
+	}
+
+	if trace.Retrievals != 1 || trace.RetrievalFailures != 1 {
+		t.Errorf("expected 1 retrieval & 1 failure, got %d & %d", trace.Retrievals, trace.RetrievalFailures)
+	}
+
+	if trace.EndTime != 0.25 {
+		t.Errorf("expected end time 0.25, got %v", trace.EndTime)
+	}
+
+	expectedPrinted := []string{"2"}
+	if !reflect.DeepEqual(trace.Printed, expectedPrinted) {
+		t.Errorf("expected printed %q, got %q", expectedPrinted, trace.Printed)
+	}
+}
@@ -0,0 +1,192 @@
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// StateMarker starts the line the generated code outputs at the end of each run when the model's
+// DumpState is set. It is followed by the state as JSON.
+const StateMarker = "gactar-state"
+
+// ChunkState is a chunk in a buffer or declarative memory at the end of a run.
+type ChunkState struct {
+	Name       string            `json:"name,omitempty"`       // name of the chunk (if the framework names them)
+	Type       string            `json:"type,omitempty"`       // chunk type (empty if it doesn't match one in the model)
+	Slots      map[string]string `json:"slots"`                // value of each slot ("nil" if it is empty)
+	Activation *float64          `json:"activation,omitempty"` // activation (if the framework provides it)
+}
+
+// State is the contents of the buffers & declarative memory at the end of a run.
+type State struct {
+	Buffers map[string]*ChunkState `json:"buffers"` // contents of each buffer (nil if it is empty)
+	Memory  []*ChunkState          `json:"memory"`  // chunks in declarative memory
+}
+
+// ParseState parses the state output by the generated code and returns the rest of the output
+// without it. The state is nil if the model doesn't dump its state or the state couldn't be parsed.
+// If there is more than one (e.g. when running trials), the last one is used.
+func ParseState(model *actr.Model, output []byte) (state *State, remaining []byte) {
+	if !model.DumpState {
+		return nil, output
+	}
+
+	rest := &bytes.Buffer{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 16*1024*1024) // memory may be large
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, StateMarker+" ") {
+			var parsed State
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, StateMarker+" ")), &parsed) == nil {
+				state = &parsed
+				continue
+			}
+		}
+
+		rest.WriteString(line)
+		rest.WriteByte('\n')
+	}
+
+	if state != nil {
+		state.normalize(model)
+	}
+
+	return state, rest.Bytes()
+}
+
+// normalize makes the output of the frameworks consistent: empty slots are "nil" and chunks from
+// frameworks which don't store their types get the type of the model's chunk with their slots.
+func (s *State) normalize(model *actr.Model) {
+	chunks := append([]*ChunkState{}, s.Memory...)
+	for _, chunk := range s.Buffers {
+		chunks = append(chunks, chunk)
+	}
+
+	for _, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+
+		if chunk.Slots == nil {
+			chunk.Slots = map[string]string{}
+		}
+
+		for slot, value := range chunk.Slots {
+			switch value {
+			case "", "None", "NIL":
+				chunk.Slots[slot] = "nil"
+			}
+		}
+
+		if chunk.Type != "" {
+			continue
+		}
+
+		if chunkType := chunkTypeWithSlots(model, chunk.Slots); chunkType != nil {
+			chunk.Type = chunkType.Name
+
+			for _, slot := range chunkType.SlotNames {
+				if _, ok := chunk.Slots[slot]; !ok {
+					chunk.Slots[slot] = "nil"
+				}
+			}
+		}
+	}
+}
+
+// chunkTypeWithSlots returns the model's chunk with the fewest slots which has all of the slots
+// (or nil if there isn't one). Some frameworks only output the slots which aren't empty.
+func chunkTypeWithSlots(model *actr.Model, slots map[string]string) (found *actr.Chunk) {
+	for _, chunk := range model.Chunks {
+		if chunk.IsInternal() || chunk.NumSlots < len(slots) {
+			continue
+		}
+
+		if found != nil && found.NumSlots <= chunk.NumSlots {
+			continue
+		}
+
+		hasAll := true
+		for slot := range slots {
+			if !chunk.HasSlot(slot) {
+				hasAll = false
+				break
+			}
+		}
+
+		if hasAll {
+			found = chunk
+		}
+	}
+
+	return
+}
+
+// WriteState writes the contents of the buffers & memory in a readable form.
+func WriteState(w io.Writer, model *actr.Model, state *State) {
+	if state == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "state:")
+
+	for _, name := range model.BufferNames() {
+		fmt.Fprintf(w, "\t%s: %s\n", name, state.Buffers[name].text(model))
+	}
+
+	fmt.Fprintf(w, "\tmemory: %d chunks\n", len(state.Memory))
+
+	for _, chunk := range state.Memory {
+		fmt.Fprintf(w, "\t\t%s\n", chunk.text(model))
+	}
+}
+
+// text returns the chunk as "name type (slot: value, ...)" with the slots in the order they are
+// declared in the model.
+func (c *ChunkState) text(model *actr.Model) string {
+	if c == nil {
+		return "nil"
+	}
+
+	var slots []string
+	if chunkType := model.LookupChunk(c.Type); chunkType != nil {
+		slots = append(slots, chunkType.SlotNames...)
+	} else {
+		for slot := range c.Slots {
+			slots = append(slots, slot)
+		}
+		sort.Strings(slots)
+	}
+
+	values := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		values = append(values, fmt.Sprintf("%s: %s", slot, c.Slots[slot]))
+	}
+
+	parts := []string{}
+	if c.Name != "" {
+		parts = append(parts, c.Name)
+	}
+	if c.Type != "" {
+		parts = append(parts, c.Type)
+	}
+	parts = append(parts, "("+strings.Join(values, ", ")+")")
+
+	if c.Activation != nil {
+		parts = append(parts, "activation "+strconv.FormatFloat(*c.Activation, 'f', 3, 64))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,73 @@
+package framework
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseState(t *testing.T) {
+	model := measuresModel(t).WithDumpState()
+
+	output := `   0.050 production respond
+gactar-state {"buffers": {"goal": {"type": "question", "slots": {"judgment": "None"}}, "retrieval": null}, "memory": []}
+gactar-state {"buffers": {"goal": {"name": "goal-0", "slots": {"judgment": "yes"}}, "retrieval": null}, "memory": [{"name": "count0", "slots": {"first": "1"}, "activation": 0.5}]}
+gactar-state {not json
+end...
+`
+
+	state, remaining := ParseState(model, []byte(output))
+	if state == nil {
+		t.Fatal("expected a state")
+	}
+
+	expectedOutput := "   0.050 production respond\ngactar-state {not json\nend...\n"
+	if string(remaining) != expectedOutput {
+		t.Errorf("unexpected remaining output: %q", string(remaining))
+	}
+
+	// the last state is used and the types & empty slots are filled in
+	goal := state.Buffers["goal"]
+	if goal == nil || goal.Type != "question" || goal.Slots["judgment"] != "yes" {
+		t.Errorf("unexpected goal: %+v", goal)
+	}
+
+	if state.Buffers["retrieval"] != nil {
+		t.Errorf("expected empty retrieval buffer: %+v", state.Buffers["retrieval"])
+	}
+
+	if len(state.Memory) != 1 {
+		t.Fatalf("expected 1 chunk in memory: %+v", state.Memory)
+	}
+
+	chunk := state.Memory[0]
+	if chunk.Type != "count" || chunk.Slots["first"] != "1" || chunk.Slots["second"] != "nil" {
+		t.Errorf("unexpected memory chunk: %+v", chunk)
+	}
+
+	if chunk.Activation == nil || *chunk.Activation != 0.5 {
+		t.Errorf("unexpected activation: %v", chunk.Activation)
+	}
+
+	var text bytes.Buffer
+	WriteState(&text, model, state)
+
+	if !strings.Contains(text.String(), "\t\tcount0 count (first: 1, second: nil) activation 0.500\n") {
+		t.Errorf("unexpected text: %q", text.String())
+	}
+}
+
+func TestParseStateNotDumped(t *testing.T) {
+	model := measuresModel(t)
+
+	output := "gactar-state {\"buffers\": {}, \"memory\": []}\n"
+
+	state, remaining := ParseState(model, []byte(output))
+	if state != nil {
+		t.Errorf("expected no state: %+v", state)
+	}
+
+	if string(remaining) != output {
+		t.Errorf("unexpected remaining output: %q", string(remaining))
+	}
+}
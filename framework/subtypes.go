@@ -0,0 +1,100 @@
+package framework
+
+import (
+	"github.com/asmaloney/gactar/actr"
+
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+// SubtypeProductions returns the variants of a production to output for frameworks which flatten
+// chunk types (like pyactr and ccm). These only match chunks of the exact type in a pattern, while
+// ACT-R matches any chunk which has the slots in the pattern. So a production matching a chunk type
+// with subtypes is output once for each combination of the types it may match. The first one
+// is the production itself; each of the others is named after the buffers and types it matches.
+func SubtypeProductions(model *actr.Model, production *actr.Production) (productions []*actr.Production) {
+	productions = []*actr.Production{production}
+
+	for i, match := range production.Matches {
+		subtypes := model.Subtypes(match.Pattern.Chunk)
+		if len(subtypes) == 1 {
+			continue
+		}
+
+		variants := productions
+		for _, subtype := range subtypes[1:] {
+			for _, variant := range variants {
+				productions = append(productions, subtypeVariant(variant, i, subtype))
+			}
+		}
+	}
+
+	return
+}
+
+// subtypeVariant copies the production, replacing the pattern of one match with one matching the
+// subtype. Statements setting slots on the matched buffer refer to the subtype as well.
+func subtypeVariant(production *actr.Production, matchIndex int, subtype *actr.Chunk) *actr.Production {
+	match := *production.Matches[matchIndex]
+	match.Pattern = match.Pattern.AsSubtype(subtype)
+
+	variant := *production
+	variant.Name += "_" + match.Buffer.BufferName() + "_" + subtype.Name
+
+	variant.Matches = append([]*actr.Match{}, production.Matches...)
+	variant.Matches[matchIndex] = &match
+
+	variant.DoStatements = make([]*actr.Statement, len(production.DoStatements))
+	for i, statement := range production.DoStatements {
+		variant.DoStatements[i] = statement
+
+		if statement.Set == nil || statement.Set.Slots == nil || statement.Set.Buffer != match.Buffer {
+			continue
+		}
+
+		set := *statement.Set
+		set.Chunk = subtype
+
+		variant.DoStatements[i] = &actr.Statement{Set: &set}
+	}
+
+	return &variant
+}
+
+// MergeSubtypeProductions counts the firings of the variants from SubtypeProductions in the trace
+// as firings of the production they came from.
+func MergeSubtypeProductions(model *actr.Model, trace *Trace) {
+	for _, production := range model.Productions {
+		for _, variant := range SubtypeProductions(model, production)[1:] {
+			count, ok := trace.Productions[variant.Name]
+			if !ok {
+				continue
+			}
+
+			firedAt := trace.FiredAt[variant.Name]
+			if original, ok := trace.FiredAt[production.Name]; !ok || firedAt < original {
+				trace.FiredAt[production.Name] = firedAt
+			}
+
+			trace.Productions[production.Name] += count
+
+			delete(trace.Productions, variant.Name)
+			delete(trace.FiredAt, variant.Name)
+		}
+	}
+}
+
+// WarnSubtypeRecalls warns about recalls of chunk types which have subtypes. Frameworks which
+// flatten chunk types can match subtypes using SubtypeProductions, but a recall is one request
+// so it only retrieves chunks of the exact type.
+func WarnSubtypeRecalls(log *issues.Log, frameworkName string, model *actr.Model) {
+	for _, production := range model.Productions {
+		for _, statement := range production.DoStatements {
+			if statement.Recall == nil || !model.HasSubtypes(statement.Recall.Pattern.Chunk) {
+				continue
+			}
+
+			location := issues.Location{Line: production.AMODLineNumber}
+			log.Warning(&location, "%s only recalls chunks of type '%s' - not its subtypes (in '%s')", frameworkName, statement.Recall.Pattern.Chunk.Name, production.Name)
+		}
+	}
+}
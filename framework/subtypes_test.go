@@ -0,0 +1,89 @@
+package framework
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/util/issues"
+)
+
+const subtypesSource = `
+==model==
+name: Test
+==config==
+chunks {
+	[number: value]
+	[digit(number): name]
+	[teen(number): tens]
+}
+==init==
+==productions==
+compare {
+	match {
+		goal [number: ?value]
+		retrieval [number: ?value]
+	}
+	do { clear goal, retrieval }
+}`
+
+func TestSubtypeProductions(t *testing.T) {
+	model, log, err := amod.GenerateModel(subtypesSource)
+	if err != nil {
+		t.Fatalf("could not generate model: %s", log)
+	}
+
+	productions := SubtypeProductions(model, model.Productions[0])
+
+	names := []string{}
+	for _, production := range productions {
+		names = append(names, production.Name)
+	}
+
+	expected := "[compare compare_goal_digit compare_goal_teen compare_retrieval_digit compare_goal_digit_retrieval_digit compare_goal_teen_retrieval_digit compare_retrieval_teen compare_goal_digit_retrieval_teen compare_goal_teen_retrieval_teen]"
+	if actual := fmt.Sprint(names); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestMergeSubtypeProductions(t *testing.T) {
+	model, log, err := amod.GenerateModel(subtypesSource)
+	if err != nil {
+		t.Fatalf("could not generate model: %s", log)
+	}
+
+	trace := NewTrace()
+	trace.ProductionFired("compare_goal_digit_retrieval_digit", 0.05)
+	trace.ProductionFired("compare", 0.1)
+	trace.ProductionFired("compare_retrieval_teen", 0.15)
+
+	MergeSubtypeProductions(model, trace)
+
+	expected := "map[compare:3] map[compare:0.05]"
+	if actual := fmt.Sprint(trace.Productions, " ", trace.FiredAt); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestWarnSubtypeRecalls(t *testing.T) {
+	model, log, err := amod.GenerateModel(subtypesSource + `
+recall_number {
+	match { goal [digit: ?value *] }
+	do { recall [number: ?value] }
+}
+recall_digit {
+	match { goal [number: ?value] }
+	do { recall [digit: ?value *] }
+}`)
+	if err != nil {
+		t.Fatalf("could not generate model: %s", log)
+	}
+
+	log = issues.New()
+	WarnSubtypeRecalls(log, "ccm", model)
+
+	expected := "WARN: ccm only recalls chunks of type 'number' - not its subtypes (in 'recall_number') (line 19, col 0)\n"
+	if actual := log.String(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
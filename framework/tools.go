@@ -2,6 +2,7 @@ package framework
 
 import (
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 
@@ -14,14 +15,14 @@ import (
 
 // Some tools for working with our frameworks
 
-// Setup will check that the executable exists and then use it to identify itself.
-func Setup(info *Info) (err error) {
+// Setup will check that the executable exists and then use it to identify itself to out.
+func Setup(info *Info, out io.Writer) (err error) {
 	_, err = filesystem.CheckForExecutable(info.ExecutableName)
 	if err != nil {
 		return
 	}
 
-	err = identifyYourself(info.Name, info.ExecutableName)
+	info.ExecutableVersion, err = identifyYourself(out, info.Name, info.ExecutableName)
 	if err != nil {
 		return
 	}
@@ -46,6 +47,12 @@ func ParseInitialBuffers(model *actr.Model, initialBuffers InitialBuffers) (pars
 			return
 		}
 
+		// buffers such as retrieval are initialized using the memory initializers
+		if buffer.AllowsMultipleInit() {
+			err = fmt.Errorf("ERROR cannot initialize buffer '%s' - use initializers in the amod file", bufferName)
+			return
+		}
+
 		pattern, parseErr := amod.ParseChunk(model, bufferInit)
 		if parseErr != nil {
 			err = fmt.Errorf("ERROR in initial buffer  '%s' - %s", bufferName, parseErr)
@@ -78,23 +85,23 @@ func PythonValuesToStrings(values *[]*actr.Value, quoteStrings bool) []string {
 	return str
 }
 
-// identifyYourself outputs version info and the path to an executable.
-func identifyYourself(frameworkName, exeName string) (err error) {
+// identifyYourself outputs version info and the path to an executable to out and returns the version.
+func identifyYourself(out io.Writer, frameworkName, exeName string) (version string, err error) {
 	cmd := exec.Command(exeName, "--version")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return err
+		return
 	}
 
-	version := strings.TrimSpace(string(output))
+	version = strings.TrimSpace(string(output))
 
 	cmd = exec.Command("which", exeName)
 	output, err = cmd.CombinedOutput()
 	if err != nil {
-		return err
+		return
 	}
 
-	fmt.Printf("%s: Using %s from %s", frameworkName, version, string(output))
+	fmt.Fprintf(out, "%s: Using %s from %s", frameworkName, version, string(output))
 
 	return
 }
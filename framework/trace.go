@@ -0,0 +1,43 @@
+package framework
+
+// Trace summarizes what happened during a run. Each framework outputs its trace differently, so
+// they fill this in by parsing their output.
+//
+// The framework only outputs production firings & retrievals if the model's log_level is 'info'
+// or 'detail'.
+type Trace struct {
+	Printed []string `json:"printed,omitempty"` // lines output by the model's print statements
+
+	Productions map[string]int     `json:"productions,omitempty"` // number of times each production fired
+	FiredAt     map[string]float64 `json:"firedAt,omitempty"`     // time each production first fired (in seconds)
+
+	Retrievals        int `json:"retrievals"`        // number of successful retrievals
+	RetrievalFailures int `json:"retrievalFailures"` // number of failed retrievals
+
+	EndTime float64 `json:"endTime"` // simulated time of the last event (in seconds)
+}
+
+// NewTrace creates an empty trace.
+func NewTrace() *Trace {
+	return &Trace{
+		Productions: map[string]int{},
+		FiredAt:     map[string]float64{},
+	}
+}
+
+// ProductionFired records a production firing at the given time.
+func (t *Trace) ProductionFired(name string, time float64) {
+	if t.Productions[name] == 0 {
+		t.FiredAt[name] = time
+	}
+
+	t.Productions[name]++
+	t.SetTime(time)
+}
+
+// SetTime records the time of an event. The end time is the time of the latest one.
+func (t *Trace) SetTime(time float64) {
+	if time > t.EndTime {
+		t.EndTime = time
+	}
+}
@@ -0,0 +1,66 @@
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// TrialMarker starts the line the generated code outputs before each trial after the first when
+// running several trials in one run. It is followed by the trial number and the simulated time
+// the trial starts at (e.g. "gactar-trial 2 0.35").
+const TrialMarker = "gactar-trial"
+
+// ParseTrials parses the initial buffers of each trial.
+func ParseTrials(model *actr.Model, trials []InitialBuffers) (parsed []ParsedInitialBuffers, err error) {
+	for i, trial := range trials {
+		var patterns ParsedInitialBuffers
+		patterns, err = ParseInitialBuffers(model, trial)
+		if err != nil {
+			err = fmt.Errorf("trial %d: %w", i+1, err)
+			return
+		}
+
+		parsed = append(parsed, patterns)
+	}
+
+	return
+}
+
+// SplitTrials splits the output of a run at the trial markers. It returns the output of each
+// trial and the simulated time each one started at.
+func SplitTrials(output []byte) (trials [][]byte, startTimes []float64) {
+	current := &bytes.Buffer{}
+	startTimes = []float64{0}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, TrialMarker+" ") {
+			trials = append(trials, current.Bytes())
+			current = &bytes.Buffer{}
+
+			fields := strings.Fields(line)
+
+			start := 0.0
+			if len(fields) == 3 {
+				start, _ = strconv.ParseFloat(fields[2], 64)
+			}
+
+			startTimes = append(startTimes, start)
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+
+	trials = append(trials, current.Bytes())
+
+	return
+}
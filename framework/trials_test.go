@@ -0,0 +1,44 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitTrials(t *testing.T) {
+	output := `   0.050 production start
+2
+gactar-trial 2 0.35
+   0.400 production start
+3
+gactar-trial 3 0.7
+`
+
+	trials, startTimes := SplitTrials([]byte(output))
+
+	expected := []string{"   0.050 production start\n2\n", "   0.400 production start\n3\n", ""}
+	if len(trials) != len(expected) {
+		t.Fatalf("expected %d trials, got %d", len(expected), len(trials))
+	}
+
+	for i := range expected {
+		if string(trials[i]) != expected[i] {
+			t.Errorf("trial %d: expected %q, got %q", i+1, expected[i], string(trials[i]))
+		}
+	}
+
+	if !reflect.DeepEqual(startTimes, []float64{0, 0.35, 0.7}) {
+		t.Errorf("unexpected start times: %v", startTimes)
+	}
+}
+
+func TestTraceFiredAt(t *testing.T) {
+	trace := NewTrace()
+	trace.ProductionFired("start", 0.05)
+	trace.ProductionFired("increment", 0.1)
+	trace.ProductionFired("increment", 0.15)
+
+	if trace.FiredAt["increment"] != 0.1 || trace.Productions["increment"] != 2 {
+		t.Errorf("expected increment to first fire at 0.1, got %v", trace.FiredAt)
+	}
+}
@@ -0,0 +1,64 @@
+package vanilla_actr
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/framework"
+)
+
+// ACT-R's trace lines look like "     0.050   PROCEDURAL             PRODUCTION-FIRED START".
+var traceLineRegex = regexp.MustCompile(`^\s*(\d+\.\d+)\s+(\S+)\s+(.*)$`)
+
+// parseTrace creates a trace from ACT-R's output. Lines which aren't trace lines are from
+// !output! statements.
+//
+// Lisp uppercases the production names, so we use the model to look up the original names.
+func parseTrace(model *actr.Model, output []byte) (trace *framework.Trace) {
+	trace = framework.NewTrace()
+
+	productionNames := map[string]string{}
+	for _, production := range model.Productions {
+		productionNames[strings.ToUpper(production.Name)] = production.Name
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		matches := traceLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && !strings.HasPrefix(trimmed, ";") && !strings.HasPrefix(trimmed, "#|") {
+				trace.Printed = append(trace.Printed, line)
+			}
+			continue
+		}
+
+		time, _ := strconv.ParseFloat(matches[1], 64)
+		trace.SetTime(time)
+
+		module, event := matches[2], strings.TrimSpace(matches[3])
+
+		switch {
+		case module == "PROCEDURAL" && strings.HasPrefix(event, "PRODUCTION-FIRED "):
+			name := strings.TrimPrefix(event, "PRODUCTION-FIRED ")
+			if original, ok := productionNames[name]; ok {
+				name = original
+			}
+			trace.ProductionFired(name, time)
+
+		case module == "DECLARATIVE" && strings.HasPrefix(event, "RETRIEVED-CHUNK "):
+			trace.Retrievals++
+
+		case module == "DECLARATIVE" && event == "RETRIEVAL-FAILURE":
+			trace.RetrievalFailures++
+		}
+	}
+
+	return
+}
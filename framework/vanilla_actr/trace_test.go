@@ -0,0 +1,43 @@
+package vanilla_actr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+func TestParseTrace(t *testing.T) {
+	model := &actr.Model{
+		Productions: []*actr.Production{{Name: "start"}, {Name: "incrementCount"}},
+	}
+
+	output := `     0.000   GOAL                   SET-BUFFER-CHUNK GOAL GOAL NIL
+     0.050   PROCEDURAL             PRODUCTION-FIRED START
+     0.100   DECLARATIVE            RETRIEVED-CHUNK C1
+     0.150   PROCEDURAL             PRODUCTION-FIRED INCREMENTCOUNT
+2
+     0.200   DECLARATIVE            RETRIEVAL-FAILURE
+     0.300   ------                 Stopped because no events left to process
+`
+
+	trace := parseTrace(model, []byte(output))
+
+	expectedProductions := map[string]int{"start": 1, "incrementCount": 1}
+	if !reflect.DeepEqual(trace.Productions, expectedProductions) {
+		t.Errorf("expected productions %v, got %v", expectedProductions, trace.Productions)
+	}
+
+	if trace.Retrievals != 1 || trace.RetrievalFailures != 1 {
+		t.Errorf("expected 1 retrieval & 1 failure, got %d & %d", trace.Retrievals, trace.RetrievalFailures)
+	}
+
+	if trace.EndTime != 0.3 {
+		t.Errorf("expected end time 0.3, got %v", trace.EndTime)
+	}
+
+	expectedPrinted := []string{"2"}
+	if !reflect.DeepEqual(trace.Printed, expectedPrinted) {
+		t.Errorf("expected printed %q, got %q", expectedPrinted, trace.Printed)
+	}
+}
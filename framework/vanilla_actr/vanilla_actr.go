@@ -2,8 +2,10 @@ package vanilla_actr
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -46,12 +48,19 @@ func New(ctx *cli.Context) (v *VanillaACTR, err error) {
 	return
 }
 
+func (v VanillaACTR) Clone(tmpPath string) framework.Framework {
+	return &VanillaACTR{
+		tmpPath: tmpPath,
+		envPath: v.envPath,
+	}
+}
+
 func (VanillaACTR) Info() *framework.Info {
 	return &Info
 }
 
-func (v *VanillaACTR) Initialize() (err error) {
-	return framework.Setup(&Info)
+func (v *VanillaACTR) Initialize(out io.Writer) (err error) {
+	return framework.Setup(&Info, out)
 }
 
 func (VanillaACTR) ValidateModel(model *actr.Model) (log *issues.Log) {
@@ -76,18 +85,36 @@ func (c VanillaACTR) Model() (model *actr.Model) {
 }
 
 func (v *VanillaACTR) Run(initialBuffers framework.InitialBuffers) (result *framework.RunResult, err error) {
-	modelFile, err := v.WriteModel(v.tmpPath, initialBuffers)
+	results, err := v.RunTrials([]framework.InitialBuffers{initialBuffers})
+	if len(results) > 0 {
+		result = results[0]
+	}
+
+	return
+}
+
+// RunTrials writes the model with the first trial's buffers and a run file which sets the buffers
+// of each trial after the first and runs the model again without resetting it.
+func (v *VanillaACTR) RunTrials(trials []framework.InitialBuffers) (results []*framework.RunResult, err error) {
+	if len(trials) == 0 {
+		err = fmt.Errorf("no trials to run")
+		return
+	}
+
+	nextTrials, err := framework.ParseTrials(v.model, trials[1:])
 	if err != nil {
 		return
 	}
 
-	// Save the current code for our result
-	result = &framework.RunResult{
-		FileName:      modelFile,
-		GeneratedCode: v.GetContents(),
+	modelFile, err := v.WriteModel(v.tmpPath, trials[0])
+	if err != nil {
+		return
 	}
 
-	runFile, err := v.createRunFile(modelFile)
+	// Save the current code for our result
+	code := v.GetContents()
+
+	runFile, err := v.createRunFile(v.tmpPath, modelFile, nextTrials, false)
 	if err != nil {
 		return
 	}
@@ -98,10 +125,29 @@ func (v *VanillaACTR) Run(initialBuffers framework.InitialBuffers) (result *fram
 	output = removePreamble(output)
 	if err != nil {
 		err = fmt.Errorf("%s", string(output))
-		return
+		return []*framework.RunResult{{FileName: modelFile, GeneratedCode: code}}, err
+	}
+
+	outputs, startTimes := framework.SplitTrials(output)
+	if len(trials) == 1 {
+		outputs = [][]byte{output}
 	}
 
-	result.Output = output
+	for i, trialOutput := range outputs {
+		measures, trialOutput := framework.ParseMeasures(v.model, trialOutput)
+		state, trialOutput := framework.ParseState(v.model, trialOutput)
+
+		results = append(results, &framework.RunResult{
+			FileName:      modelFile,
+			GeneratedCode: code,
+			Output:        trialOutput,
+			Trace:         parseTrace(v.model, trialOutput),
+			Measures:      measures,
+			State:         state,
+			Seed:          v.model.RandomSeed,
+			StartTime:     startTimes[i],
+		})
+	}
 
 	return
 }
@@ -151,6 +197,10 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 	// enable subsymbolic computations
 	v.Writeln("\t:esc t")
 
+	if v.model.RandomSeed != nil {
+		v.Writeln("\t:seed (%d 0)", *v.model.RandomSeed)
+	}
+
 	memory := v.model.Memory
 	if memory.LatencyFactor != nil {
 		v.Writeln("\t:lf %s", numbers.Float64Str(*memory.LatencyFactor))
@@ -211,7 +261,7 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 		}
 
 		v.Writeln(";; amod line %d", chunk.AMODLineNumber)
-		v.Writeln("(chunk-type %s %s)", chunk.Name, strings.Join(chunk.SlotNames, " "))
+		v.Writeln("(chunk-type %s)", chunkTypeDecl(chunk))
 	}
 	v.Writeln("")
 
@@ -219,8 +269,8 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 	for i, init := range v.model.Initializers {
 		module := init.Module
 
-		// allow the user-set goal to override the initializer
-		if module.ModuleName() == "goal" && (goal != nil) {
+		// allow the user-set buffers to override the initializer
+		if patterns[module.BufferName()] != nil {
 			continue
 		}
 
@@ -232,7 +282,7 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 
 		if initializer == "memory" {
 			v.Writeln(" ;; amod line %d", init.AMODLineNumber)
-			v.Writeln(" (fact_%d", i)
+			v.Writeln(" (%s", memoryChunkName(i, init))
 		} else {
 			v.Writeln(" ;; amod line %d", init.AMODLineNumber)
 			v.Writeln(" (%s", initializer)
@@ -250,6 +300,8 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 
 	v.Writeln(")\n")
 
+	v.outputChunkMetadata(patterns)
+
 	// productions
 	for _, production := range v.model.Productions {
 		v.Writeln(";; amod line %d", production.AMODLineNumber)
@@ -271,6 +323,10 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 			}
 		}
 
+		for _, measure := range v.model.MeasuresFiredBy(production) {
+			v.Writeln("\t!eval!\t(format t \"~&%s %s ~a~%%\" (mp-time))", framework.MeasureMarker, measure.Name)
+		}
+
 		v.Writeln(")\n")
 	}
 
@@ -278,14 +334,18 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 		v.Writeln(";; initialize our imaginal buffer")
 		v.Writeln("(define-chunks (imaginal-init")
 
-		// find our imaginal initializer and output it
-		for _, init := range v.model.Initializers {
-			if init.Module != nil {
-				if init.Module.ModuleName() != "imaginal" {
-					continue
-				}
+		// use the user-set contents if we have them, otherwise find our imaginal initializer and output it
+		if userImaginal := patterns["imaginal"]; userImaginal != nil {
+			v.outputPattern(userImaginal, 1)
+		} else {
+			for _, init := range v.model.Initializers {
+				if init.Module != nil {
+					if init.Module.ModuleName() != "imaginal" {
+						continue
+					}
 
-				v.outputPattern(init.Pattern, 1)
+					v.outputPattern(init.Pattern, 1)
+				}
 			}
 		}
 		v.Writeln("))")
@@ -294,10 +354,6 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 		v.Writeln("")
 	}
 
-	// Useful for debugging - output the contents of the imaginal buffer and the dm
-	// v.Writeln("(buffer-chunk imaginal)")
-	// v.Writeln("(dm)")
-
 	v.Writeln("(goal-focus goal)")
 
 	v.Writeln(")")
@@ -305,6 +361,25 @@ func (v *VanillaACTR) WriteModel(path string, initialBuffers framework.InitialBu
 	return
 }
 
+// WriteSupportFiles writes the lisp file which loads ACT-R and runs the model written by WriteModel().
+// These files are meant to be used elsewhere (e.g. in an archive), so the run file doesn't refer to
+// our environment or the path it was written to.
+func (v *VanillaACTR) WriteSupportFiles(path string) (fileNames []string, err error) {
+	modelFile := fmt.Sprintf("%s.lisp", v.modelName)
+	if path != "" {
+		modelFile = fmt.Sprintf("%s/%s", path, modelFile)
+	}
+
+	runFile, err := v.createRunFile(path, modelFile, nil, true)
+	if err != nil {
+		return
+	}
+
+	fileNames = []string{runFile}
+
+	return
+}
+
 func (v *VanillaACTR) outputAuthors() {
 	if len(v.model.Authors) == 0 {
 		return
@@ -371,6 +446,8 @@ func addPatternSlot(tabbedItems *framework.KeyValueList, slotName string, patter
 			value = fmt.Sprintf(`"%s"`, *item.ID)
 		} else if item.Num != nil {
 			value = *item.Num
+		} else if item.ChunkRef != nil {
+			value = *item.ChunkRef
 		} else if item.Var != nil {
 			varName := strings.TrimPrefix(*item.Var, "?")
 			value = fmt.Sprintf("=%s", varName)
@@ -457,11 +534,13 @@ func createOutputArgs(values *[]*actr.Value) string {
 	return formatStr + argStr
 }
 
-// createRunFile creates a lisp program to load ACTR and our model and then run them.
-func (v *VanillaACTR) createRunFile(modelFile string) (outputFile string, err error) {
+// createRunFile creates a lisp program to load ACTR and our model and then run them. Each of the
+// nextTrials sets its buffers and runs the model again. If portable is set, the program may be run
+// from another directory or machine (see outputPortableLoad()).
+func (v *VanillaACTR) createRunFile(path, modelFile string, nextTrials []framework.ParsedInitialBuffers, portable bool) (outputFile string, err error) {
 	outputFile = fmt.Sprintf("%s_run.lisp", v.modelName)
-	if v.tmpPath != "" {
-		outputFile = fmt.Sprintf("%s/%s", v.tmpPath, outputFile)
+	if path != "" {
+		outputFile = fmt.Sprintf("%s/%s", path, outputFile)
 	}
 
 	err = v.InitWriterHelper(outputFile)
@@ -470,14 +549,179 @@ func (v *VanillaACTR) createRunFile(modelFile string) (outputFile string, err er
 	}
 	defer v.CloseWriterHelper()
 
-	v.Writeln("#!%s/bin/sbcl --script", v.envPath)
-	v.Writeln(`(load "%s/actr/load-single-threaded-act-r.lisp")`, v.envPath)
-	v.Writeln(`(load "%s")`, modelFile)
+	if portable {
+		v.outputPortableLoad(filepath.Base(outputFile), filepath.Base(modelFile))
+	} else {
+		v.Writeln("#!%s/bin/sbcl --script", v.envPath)
+		v.Writeln(`(load "%s/actr/load-single-threaded-act-r.lisp")`, v.envPath)
+		v.Writeln(`(load "%s")`, modelFile)
+	}
+
+	v.outputDumpStateFunctions()
 	v.Writeln(`(run 10.0)`)
+	v.outputSlotMeasures()
+	v.outputDumpState()
+
+	for i, trial := range nextTrials {
+		v.Writeln("")
+		v.Writeln(`(format t "~&%s %d ~a~%%" (mp-time))`, framework.TrialMarker, i+2)
+
+		for _, name := range trial.BufferNames() {
+			chunkName := fmt.Sprintf("trial-%d-%s", i+2, name)
+
+			v.Writeln("(define-chunks (%s", chunkName)
+			v.outputPattern(trial[name], 1)
+			v.Writeln("))")
+
+			if name == "goal" {
+				v.Writeln("(goal-focus %s)", chunkName)
+			} else {
+				v.Writeln("(set-buffer-chunk '%s '%s)", name, chunkName)
+			}
+		}
+
+		v.Writeln(`(run 10.0)`)
+		v.outputSlotMeasures()
+		v.outputDumpState()
+	}
 
 	return
 }
 
+// outputPortableLoad outputs the code to load ACT-R and the model without using any absolute paths.
+// ACT-R is found using the ACTR_PATH environment variable (or an "actr" directory next to the run
+// file) and the model is loaded from the same directory as the run file.
+func (v *VanillaACTR) outputPortableLoad(runFile, modelFile string) {
+	v.Writeln(";; Run using: sbcl --script %s", runFile)
+	v.Writeln(";; Set ACTR_PATH to the ACT-R directory (e.g. <gactar env>/actr) or put ACT-R in \"actr\" next to this file.")
+	v.Writeln(`(load (concatenate 'string`)
+	v.Writeln(`  (or (sb-ext:posix-getenv "ACTR_PATH") (namestring (merge-pathnames "actr/" *load-truename*)))`)
+	v.Writeln(`  "/load-single-threaded-act-r.lisp"))`)
+	v.Writeln(`(load (merge-pathnames "%s" *load-truename*))`, modelFile)
+}
+
+// chunkTypeDecl returns the declaration of the chunk's type. Chunks with parents include their
+// parent's type so only their own slots are declared, and slots with defaults are output as
+// (slot value).
+func chunkTypeDecl(chunk *actr.Chunk) string {
+	decl := []string{chunk.Name}
+	if chunk.Parent != nil {
+		decl = []string{fmt.Sprintf("(%s (:include %s))", chunk.Name, chunk.Parent.Name)}
+	}
+
+	for _, slot := range chunk.OwnSlotNames() {
+		value := chunk.DefaultValue(slot)
+
+		switch {
+		case value == nil || value.Nil:
+			decl = append(decl, slot)
+		case value.ID != nil:
+			decl = append(decl, fmt.Sprintf(`(%s "%s")`, slot, *value.ID))
+		case value.Num != nil:
+			decl = append(decl, fmt.Sprintf("(%s %s)", slot, *value.Num))
+		}
+	}
+
+	return strings.Join(decl, " ")
+}
+
+// memoryChunkName returns the name of the chunk in memory created by the initializer at index i.
+// Chunks which aren't named in the amod file are named using their index.
+func memoryChunkName(i int, init *actr.Initializer) string {
+	if init.ChunkName != "" {
+		return init.ChunkName
+	}
+
+	return fmt.Sprintf("fact_%d", i)
+}
+
+// outputChunkMetadata sets the parameters of the chunks in memory which set their initial
+// activation. The chunks are named in "add-dm" using memoryChunkName().
+func (v *VanillaACTR) outputChunkMetadata(patterns framework.ParsedInitialBuffers) {
+	if !v.model.HasMetadata() || patterns[v.model.Memory.BufferName()] != nil {
+		return
+	}
+
+	for i, init := range v.model.Initializers {
+		metadata := init.Metadata
+		if metadata == nil {
+			continue
+		}
+
+		params := []string{}
+
+		if metadata.BaseLevel != nil {
+			params = append(params, ":base-level "+numbers.Float64Str(*metadata.BaseLevel))
+		}
+
+		if metadata.References != nil {
+			params = append(params, fmt.Sprintf(":reference-count %d", *metadata.References))
+		}
+
+		if metadata.Created != nil {
+			params = append(params, ":creation-time "+numbers.Float64Str(*metadata.Created))
+		}
+
+		v.Writeln(";; amod line %d", init.AMODLineNumber)
+		v.Writeln("(sdp %s %s)", memoryChunkName(i, init), strings.Join(params, " "))
+	}
+
+	v.Writeln("")
+}
+
+// outputDumpStateFunctions outputs functions to output the contents of the buffers & declarative
+// memory as JSON. Chunks don't have types in ACT-R, so only their names & filled slots are output.
+func (v *VanillaACTR) outputDumpStateFunctions() {
+	if !v.model.DumpState {
+		return
+	}
+
+	v.Writeln("")
+	v.Writeln("(defun gactar-json (value)")
+	v.Writeln("\t(with-output-to-string (out)")
+	v.Writeln(`		(write-char #\" out)`)
+	v.Writeln("\t\t(loop for c across (format nil \"~(~a~)\" value)")
+	v.Writeln(`			do (when (member c '(#\" #\\)) (write-char #\\ out))`)
+	v.Writeln("\t\t\t(write-char c out))")
+	v.Writeln(`		(write-char #\" out)))`)
+	v.Writeln("")
+	v.Writeln("(defun gactar-chunk-json (chunk activation)")
+	v.Writeln(`	(format nil "{\"name\": ~a, \"slots\": {~{~a~^, ~}}~@[, \"activation\": ~f~]}"`)
+	v.Writeln("\t\t(gactar-json chunk)")
+	v.Writeln("\t\t(mapcar (lambda (slot) (format nil \"~a: ~a\" (gactar-json slot) (gactar-json (chunk-slot-value-fct chunk slot))))")
+	v.Writeln("\t\t\t(chunk-filled-slots-list-fct chunk))")
+	v.Writeln("\t\tactivation))")
+	v.Writeln("")
+	v.Writeln("(defun gactar-dump-state ()")
+	v.Writeln(`	(format t "~&%s {\"buffers\": {~{~a~^, ~}}, \"memory\": [~{~a~^, ~}]}~%%"`, framework.StateMarker)
+	v.Writeln("\t\t(mapcar (lambda (buffer)")
+	v.Writeln("\t\t\t\t(let ((chunk (buffer-read buffer)))")
+	v.Writeln("\t\t\t\t\t(format nil \"~a: ~a\" (gactar-json buffer) (if chunk (gactar-chunk-json chunk nil) \"null\"))))")
+	v.Writeln("\t\t\t'(%s))", strings.Join(v.model.BufferNames(), " "))
+	v.Writeln("\t\t(mapcar (lambda (chunk)")
+	v.Writeln("\t\t\t\t(let ((activation (ignore-errors (caar (no-output (sdp-fct (list chunk :activation)))))))")
+	v.Writeln("\t\t\t\t\t(gactar-chunk-json chunk (and (realp activation) activation))))")
+	v.Writeln("\t\t\t(all-dm-chunks (get-module declarative)))))")
+	v.Writeln("")
+}
+
+// outputDumpState outputs the call to output the state at the end of a run.
+func (v *VanillaACTR) outputDumpState() {
+	if v.model.DumpState {
+		v.Writeln("(gactar-dump-state)")
+	}
+}
+
+// outputSlotMeasures outputs the value of each slot measure at the end of a run. Lisp upper-cases
+// symbols, so they are output in lower case to match the amod file.
+func (v *VanillaACTR) outputSlotMeasures() {
+	for _, measure := range v.model.SlotMeasures() {
+		v.Writeln("(let ((chunk (buffer-read '%s)))", measure.Buffer.BufferName())
+		v.Writeln("\t(format t \"~&%s %s ~(~a~)~%%\"", framework.MeasureMarker, measure.Name)
+		v.Writeln("\t\t(and chunk (member '%s (chunk-filled-slots-list-fct chunk)) (chunk-slot-value-fct chunk '%s))))", measure.SlotName, measure.SlotName)
+	}
+}
+
 // removePreamble will remove the long preamble whenever ACT-R is loaded.
 func removePreamble(text []byte) []byte {
 	str := string(text)
@@ -0,0 +1,20 @@
+package vanilla_actr
+
+import (
+	"testing"
+
+	"github.com/asmaloney/gactar/framework/frameworktest"
+)
+
+func TestChunkMetadata(t *testing.T) {
+	code, log := frameworktest.Generate(t, &VanillaACTR{}, frameworktest.MetadataSource)
+
+	if log.HasIssues() {
+		t.Errorf("expected vanilla to support chunk metadata, got %s", log)
+	}
+
+	frameworktest.ExpectContains(t, code, []string{
+		"(sdp fact_0 :base-level 0.5)",
+		"(sdp fact_1 :reference-count 10 :creation-time -100)",
+	})
+}
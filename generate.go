@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/container"
+	"github.com/asmaloney/gactar/util/filesystem"
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/validate"
+)
+
+// compiledFile is the result of generating a model from an amod file.
+type compiledFile struct {
+	file  string
+	model *actr.Model // nil if there were errors
+	log   *issues.Log
+}
+
+// batch generates (and optionally runs) the code for a set of amod files.
+type batch struct {
+	frameworks framework.List
+	outputDir  string
+	run        bool
+	failFast   bool    // stop at the first failure
+	jobs       int     // maximum number of models to generate/run at the same time
+	repeat     int     // number of times to run each model on each framework
+	csvDir     string  // if set, write the run data & stats as CSV files here
+	seed       *uint32 // if set, overrides the random seed from the amod files
+	dumpState  bool    // output the buffers & memory at the end of each run
+
+	results  *resultWriter
+	progress io.Writer // where to output everything other than the results
+
+	failure error // the first failure - this includes the status to exit with
+}
+
+// handleGenerate generates code for each file & framework and optionally runs them. Progress,
+// issues, and (unless using JSON) the output of each run are output to progress.
+func handleGenerate(ctx *cli.Context, frameworks framework.List, run bool, results *resultWriter, progress io.Writer) (err error) {
+	showVersion(ctx, progress)
+
+	existingFiles, err := inputFiles(ctx, progress)
+	if err != nil {
+		return
+	}
+
+	b := &batch{
+		outputDir: ctx.Path("temp"),
+		run:       run,
+		failFast:  ctx.Bool("fail-fast"),
+		jobs:      ctx.Int("jobs"),
+		repeat:    ctx.Int("repeat"),
+		csvDir:    ctx.Path("csv"),
+		dumpState: ctx.Bool("dump-state"),
+		results:   results,
+		progress:  progress,
+	}
+
+	b.seed, err = seedFlagValue(ctx)
+	if err != nil {
+		return
+	}
+
+	if b.jobs < 1 {
+		b.jobs = 1
+	}
+
+	if b.repeat < 1 {
+		b.repeat = 1
+	}
+
+	fmt.Fprintf(b.progress, "Intermediate file path: %q\n", b.outputDir)
+
+	frameworks, failed := initializeFrameworks(frameworks, progress)
+	if len(frameworks) == 0 {
+		err = cli.Exit("could not initialize any frameworks - please check your installation", exitFramework)
+		return
+	}
+
+	// If frameworks were chosen on the command line, they must all work.
+	if len(failed) > 0 && !container.Contains("all", ctx.StringSlice("framework")) {
+		if b.fail(exitFramework, fmt.Errorf("could not initialize %s", strings.Join(failed, ", "))) {
+			return b.failure
+		}
+	}
+
+	b.frameworks = frameworks
+
+	compiled, stopped := b.compile(existingFiles)
+
+	b.results.writeCompileErrors(compiled)
+
+	if !stopped {
+		b.generate(compiled)
+	}
+
+	if ctx.Bool("watch") {
+		return b.watch(existingFiles, compiled)
+	}
+
+	return b.failure
+}
+
+// handleValidate checks each file for errors without generating any code. Any issues are output
+// to progress.
+func handleValidate(ctx *cli.Context, results *resultWriter, progress io.Writer) (err error) {
+	if ctx.Bool("debug") {
+		amod.SetDebug(true)
+	}
+
+	existingFiles, err := inputFiles(ctx, progress)
+	if err != nil {
+		return
+	}
+
+	b := &batch{
+		failFast: ctx.Bool("fail-fast"),
+		results:  results,
+		progress: progress,
+	}
+
+	compiled, _ := b.compile(existingFiles)
+
+	for _, c := range compiled {
+		results.write(newModelResult(c, ""))
+	}
+
+	return b.failure
+}
+
+// initializeFrameworks initializes each framework and removes those which fail.
+// Their versions and any errors are output to progress. It returns the names of the ones which failed.
+func initializeFrameworks(frameworks framework.List, progress io.Writer) (initialized framework.List, failed []string) {
+	for name, f := range frameworks {
+		err := f.Initialize(progress)
+		if err != nil {
+			fmt.Fprintln(progress, err.Error())
+			delete(frameworks, name)
+			failed = append(failed, name)
+		}
+	}
+
+	sort.Strings(failed)
+
+	return frameworks, failed
+}
+
+// fail records a failure (if it is the first one) and returns whether we should stop.
+func (b *batch) fail(status int, err error) (stop bool) {
+	if b.failure == nil {
+		b.failure = cli.Exit(err, status)
+	}
+
+	return b.failFast
+}
+
+// compile generates a model from each amod file and outputs any issues. It returns a result for
+// each file (in the same order) and whether we stopped early because of "--fail-fast".
+func (b *batch) compile(files []string) (compiled []compiledFile, stopped bool) {
+	for _, file := range files {
+		fmt.Fprintf(b.progress, "Generating model for %s\n", file)
+		model, log, err := amod.GenerateModelFromFile(file)
+		if err != nil {
+			fmt.Fprint(b.progress, log)
+
+			compiled = append(compiled, compiledFile{file: file, log: log})
+
+			if b.fail(exitCompile, fmt.Errorf("%s has errors", file)) {
+				return compiled, true
+			}
+			continue
+		}
+
+		// When using "run" the goal must be initialized in the code.
+		validate.Goal(model, "", log)
+
+		fmt.Fprint(b.progress, log)
+
+		compiled = append(compiled, compiledFile{file: file, model: model, log: log})
+	}
+
+	return
+}
+
+// job generates the code for one model using one framework (and runs it if "run" is set).
+type job struct {
+	compiled      compiledFile
+	frameworkName string
+	run           int // run number when using "--repeat" (starting at 1)
+	outputDir     string
+	seed          *uint32 // random seed for this run (nil if it isn't seeded)
+
+	done   bool
+	status int              // exit status if it failed
+	trace  *framework.Trace // nil if it wasn't run or it failed
+	result modelResult      // result for "--output json"
+	output bytes.Buffer     // text output - this is output all at once so jobs don't interleave
+}
+
+// generate writes the code for each model using each framework. If "run" is set, it runs each
+// one after it is written. Up to b.jobs of these are done at the same time.
+func (b *batch) generate(compiled []compiledFile) {
+	jobs := b.createJobs(compiled)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var stop int32
+
+	limit := make(chan struct{}, b.jobs)
+
+	for _, j := range jobs {
+		if atomic.LoadInt32(&stop) != 0 {
+			break
+		}
+
+		limit <- struct{}{}
+		wg.Add(1)
+
+		go func(j *job) {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			b.runJob(j)
+
+			mutex.Lock()
+			fmt.Fprint(b.progress, j.output.String())
+			b.results.write(j.result)
+			mutex.Unlock()
+
+			if j.status != 0 && b.failFast {
+				atomic.StoreInt32(&stop, 1)
+			}
+		}(j)
+	}
+
+	wg.Wait()
+
+	// Record the failures in order so the exit status doesn't depend on which job finished first.
+	for _, j := range jobs {
+		if j.done && j.status != 0 {
+			b.fail(j.status, fmt.Errorf("%s failed using %s", j.compiled.file, j.frameworkName))
+		}
+	}
+
+	if b.run && (b.repeat > 1 || b.csvDir != "") {
+		b.outputStats(jobs)
+	}
+
+	if len(compiled) > 1 {
+		outputSummary(b.progress, compiled, b.frameworks.Names(), jobs)
+	}
+}
+
+// createJobs creates a job for each model & framework (and run if using "--repeat"). Models with
+// the same name would overwrite each other's code, so all but the first are written to a
+// sub-directory. Repeated runs are each written to their own sub-directory so they may be run at
+// the same time.
+func (b *batch) createJobs(compiled []compiledFile) (jobs []*job) {
+	names := b.frameworks.Names()
+	sort.Strings(names)
+
+	modelNames := map[string]int{}
+
+	for _, c := range compiled {
+		if c.model == nil {
+			continue
+		}
+
+		outputDir := b.outputDir
+		if count := modelNames[c.model.Name]; count > 0 {
+			outputDir = filepath.Join(b.outputDir, fmt.Sprintf("%s-%d", c.model.Name, count+1))
+		}
+		modelNames[c.model.Name]++
+
+		for _, name := range names {
+			for run := 1; run <= b.repeat; run++ {
+				j := &job{
+					compiled:      c,
+					frameworkName: name,
+					run:           run,
+					outputDir:     outputDir,
+				}
+
+				if b.repeat > 1 {
+					j.outputDir = filepath.Join(outputDir, fmt.Sprintf("run-%d", run))
+				}
+
+				j.seed = b.runSeed(c.model, run)
+
+				jobs = append(jobs, j)
+			}
+		}
+	}
+
+	return
+}
+
+// runSeed returns the random seed for a run of the model. "--seed" overrides the model's seed, and
+// repeated runs each get their own seed derived from it.
+func (b *batch) runSeed(model *actr.Model, run int) *uint32 {
+	base := model.RandomSeed
+	if b.seed != nil {
+		base = b.seed
+	}
+
+	if base == nil {
+		return nil
+	}
+
+	seed := actr.DeriveSeed(*base, run)
+	return &seed
+}
+
+// runJob validates the model for the job's framework, writes the code, and runs it.
+func (b *batch) runJob(j *job) {
+	c := j.compiled
+
+	model := c.model
+	if j.seed != nil {
+		model = model.WithRandomSeed(*j.seed)
+	}
+	if b.dumpState {
+		model = model.WithDumpState()
+	}
+
+	// Use a new instance of the framework for each job so concurrent jobs don't share state.
+	f := b.frameworks[j.frameworkName].Clone(j.outputDir)
+
+	details := []string{}
+	if b.repeat > 1 {
+		details = append(details, fmt.Sprintf("run %d of %d", j.run, b.repeat))
+	}
+	if j.seed != nil {
+		details = append(details, fmt.Sprintf("seed %d", *j.seed))
+	}
+
+	if len(details) > 0 {
+		fmt.Fprintf(&j.output, "\t- generating %s code for %s (%s)\n", j.frameworkName, c.file, strings.Join(details, ", "))
+	} else {
+		fmt.Fprintf(&j.output, "\t- generating %s code for %s\n", j.frameworkName, c.file)
+	}
+
+	j.result = newModelResult(c, j.frameworkName)
+
+	log := f.ValidateModel(model)
+	fmt.Fprint(&j.output, log)
+
+	if log.HasError() {
+		j.status = exitCompile
+	} else {
+		// With repeated runs, we only output the run if it fails - the stats summarize the rest.
+		out := &j.output
+		if b.repeat > 1 {
+			out = &bytes.Buffer{}
+		}
+
+		fileName, result, err := writeAndRun(f, model, j.outputDir, b.run, log, out)
+
+		if err != nil {
+			j.status = exitRun
+
+			if b.repeat > 1 {
+				j.output.Write(out.Bytes())
+			}
+		}
+
+		if result != nil {
+			j.trace = result.Trace
+			j.result.setOutput(fileName, result.Output)
+			j.result.Trace = result.Trace
+			j.result.Measures = result.Measures
+			j.result.State = result.State
+		} else {
+			j.result.setOutput(fileName, nil)
+		}
+	}
+
+	if b.repeat > 1 {
+		j.result.Run = j.run
+	}
+
+	j.result.Seed = j.seed
+
+	j.result.setIssues(c.log, log)
+	j.done = true
+}
+
+// writeAndRun writes the code for the model and runs it if "run" is set. Any errors are output
+// and added to the log.
+func writeAndRun(f framework.Framework, model *actr.Model, outputDir string, run bool, log *issues.Log, out io.Writer) (fileName string, result *framework.RunResult, err error) {
+	defer func() {
+		if err != nil {
+			fmt.Fprintln(out, err.Error())
+			log.Error(nil, err.Error())
+		}
+	}()
+
+	err = filesystem.CreateDir(outputDir)
+	if err != nil {
+		return
+	}
+
+	err = f.SetModel(model)
+	if err != nil {
+		return
+	}
+
+	fileName, err = f.WriteModel(outputDir, framework.InitialBuffers{})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(out, "\t- written to %s\n", fileName)
+
+	if !run {
+		return
+	}
+
+	result, err = f.Run(framework.InitialBuffers{})
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(out, "== %s ==\n", f.Info().Name)
+	fmt.Fprintln(out, string(result.Output))
+	framework.WriteMeasures(out, model, result.Measures)
+	framework.WriteState(out, model, result.State)
+	fmt.Fprintln(out)
+
+	return
+}
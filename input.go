@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// inputFiles returns the amod files from the command line. Directories are searched (recursively)
+// for amod files and globs (e.g. "models/*.amod") are expanded so we aren't limited by the shell.
+// Arguments which can't be expanded or read are output to progress and return a usage error once
+// they have all been checked.
+func inputFiles(ctx *cli.Context, progress io.Writer) (existingFiles []string, err error) {
+	args := ctx.Args().Slice()
+
+	if len(args) == 0 {
+		err = cli.Exit("error: no input files specified on command line", exitUsage)
+		return
+	}
+
+	seen := map[string]bool{}
+	numErrors := 0
+
+	for _, arg := range args {
+		files, expandErr := expandInput(arg)
+		if expandErr != nil {
+			fmt.Fprintf(progress, "error: %s\n", expandErr)
+			numErrors++
+			continue
+		}
+
+		for _, file := range files {
+			if !seen[file] {
+				seen[file] = true
+				existingFiles = append(existingFiles, file)
+			}
+		}
+	}
+
+	if numErrors > 0 {
+		err = cli.Exit(fmt.Sprintf("error: could not read %d of the input files", numErrors), exitUsage)
+		return
+	}
+
+	if len(existingFiles) == 0 {
+		err = cli.Exit("error: no files to process", exitUsage)
+		return
+	}
+
+	return
+}
+
+// expandInput returns the files for one command line argument.
+func expandInput(arg string) (files []string, err error) {
+	if !strings.ContainsAny(arg, "*?[") {
+		return expandPath(arg)
+	}
+
+	matches, err := filepath.Glob(arg)
+	if err != nil {
+		return
+	}
+
+	if len(matches) == 0 {
+		err = fmt.Errorf("no files match %q", arg)
+		return
+	}
+
+	for _, match := range matches {
+		matchFiles, err := expandPath(match)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, matchFiles...)
+	}
+
+	return
+}
+
+// expandPath returns the path itself if it is a file or the amod files in it if it is a directory.
+func expandPath(path string) (files []string, err error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = fmt.Errorf("file does not exist - %q", path)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		// make sure we can read it before doing any work
+		var file *os.File
+		file, err = os.Open(path)
+		if err != nil {
+			return
+		}
+		file.Close()
+
+		return []string{path}, nil
+	}
+
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && filepath.Ext(file) == ".amod" {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if len(files) == 0 {
+		err = fmt.Errorf("no amod files in %q", path)
+		return
+	}
+
+	sort.Strings(files)
+	return
+}
@@ -2,15 +2,14 @@ package main
 
 import (
 	"embed"
-	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
 
-	"github.com/asmaloney/gactar/actr"
 	"github.com/asmaloney/gactar/amod"
 	"github.com/asmaloney/gactar/framework"
 	"github.com/asmaloney/gactar/framework/ccm_pyactr"
@@ -22,7 +21,6 @@ import (
 	"github.com/asmaloney/gactar/util/clicontext"
 	"github.com/asmaloney/gactar/util/container"
 	"github.com/asmaloney/gactar/util/filesystem"
-	"github.com/asmaloney/gactar/util/validate"
 	"github.com/asmaloney/gactar/util/version"
 )
 
@@ -31,9 +29,6 @@ import (
 var amodExamples embed.FS
 
 func main() {
-	defaultPort := 8181
-	defaultFramework := cli.NewStringSlice("all")
-
 	app := &cli.App{
 		Name:        "gactar",
 		Usage:       "A command-line tool for working with ACT-R models",
@@ -48,101 +43,59 @@ func main() {
 		},
 		Copyright:            "©2021 Andy Maloney",
 		EnableBashCompletion: true,
-		Flags: []cli.Flag{
-			&cli.StringFlag{Name: "env", Value: "./env", Usage: "directory where ACT-R, pyactr, and other necessary files are installed", EnvVars: []string{"VIRTUAL_ENV"}},
-
-			&cli.BoolFlag{Name: "debug", Aliases: []string{"d"}, Usage: "turn on debugging output"},
-			&cli.BoolFlag{Name: "ebnf", Usage: "output amod EBNF to stdout and quit"},
-			&cli.PathFlag{Name: "temp", Value: "./gactar-temp", Usage: "directory for generated files (it will be created if it does not exist)"},
-
-			&cli.StringSliceFlag{
-				Name:    "framework",
-				Aliases: []string{"f"},
-				Value:   defaultFramework,
-				Usage:   fmt.Sprintf("add framework - valid frameworks: %s", strings.Join(framework.ValidFrameworks, ", ")),
-			},
+		Flags:                append(globalFlags(), deprecatedFlags()...),
+		Commands:             commands(),
+		Action:               handleDeprecatedFlags,
+	}
 
-			// CLI mode
-			&cli.BoolFlag{Name: "run", Aliases: []string{"r"}, Category: "Mode: CLI", Usage: "run the models after generating the code"},
+	// Used to output command line options for documentation.
+	// fmt.Println(app.ToMarkdown())
 
-			// CLI (interactive) mode
-			&cli.BoolFlag{Name: "interactive", Aliases: []string{"i"}, Category: "Mode: CLI (interactive)", Usage: "run an interactive shell"},
+	// Errors from our actions have already been output and exited with their status by the cli
+	// package, so anything left is a problem with the command line.
+	err := app.Run(os.Args)
+	if err != nil {
+		os.Exit(exitUsage)
+	}
+}
 
-			// Web mode
-			&cli.BoolFlag{Name: "web", Aliases: []string{"w"}, Category: "Mode: Web", Usage: "start a web server to run in a browser"},
-			&cli.IntFlag{Name: "port", Aliases: []string{"p"}, Category: "Mode: Web", Value: defaultPort, Usage: "port to run the web server on"},
-		},
-		Action: func(c *cli.Context) error {
-			err := setupVirtualEnvironment(c)
-			if err != nil {
-				fmt.Println(err.Error())
-				return err
-			}
-
-			if c.Bool("debug") {
-				amod.SetDebug(true)
-			}
-
-			if c.Bool("ebnf") {
-				amod.OutputEBNF()
-				return nil
-			}
-
-			if c.Bool("web") && c.Bool("interactive") {
-				err := errors.New("cannot run 'web' and 'interactive' at the same time")
-				fmt.Println(err.Error())
-				return err
-			}
-
-			// Create our temp dir. This will expand our "temp" to an absolute path.
-			err = clicontext.CreateTempDir(c)
-			if err != nil {
-				return err
-			}
-
-			frameworks, err := createFrameworks(c)
-			if err != nil {
-				fmt.Println(err.Error())
-				return err
-			}
-
-			if c.Bool("web") {
-				err := handleWeb(c, frameworks)
-				if err != nil {
-					fmt.Println(err.Error())
-					return err
-				}
-			}
-
-			if c.Int("port") != defaultPort {
-				fmt.Println("info: --port only applies when using --web")
-			}
-
-			if c.Bool("interactive") {
-				err := handleInteractive(c, frameworks)
-				if err != nil {
-					fmt.Println(err.Error())
-					return err
-				}
-
-				return err
-			}
-
-			// We are not interactive or web, so simply generate the output files.
-			err = handleDefault(c, frameworks)
-			if err != nil {
-				fmt.Println(err.Error())
-				return err
-			}
-
-			return nil
+// globalFlags returns the flags which apply to all commands.
+func globalFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "env", Value: "./env", Usage: "directory where ACT-R, pyactr, and other necessary files are installed", EnvVars: []string{"VIRTUAL_ENV"}},
+
+		&cli.BoolFlag{Name: "debug", Aliases: []string{"d"}, Usage: "turn on debugging output"},
+		&cli.PathFlag{Name: "temp", Value: "./gactar-temp", Usage: "directory for generated files (it will be created if it does not exist)"},
+
+		&cli.StringSliceFlag{
+			Name:    "framework",
+			Aliases: []string{"f"},
+			Value:   cli.NewStringSlice("all"),
+			Usage:   fmt.Sprintf("add framework - valid frameworks: %s", strings.Join(framework.ValidFrameworks, ", ")),
 		},
 	}
+}
 
-	// Used to output command line options for documentation.
-	// fmt.Println(app.ToMarkdown())
+// setup prepares the virtual environment & temp dir and creates the frameworks from the command line.
+// Any problems creating the frameworks are output to progress.
+func setup(c *cli.Context, progress io.Writer) (frameworks framework.List, err error) {
+	err = setupVirtualEnvironment(c)
+	if err != nil {
+		err = cli.Exit(err, exitFramework)
+		return
+	}
 
-	app.Run(os.Args)
+	if c.Bool("debug") {
+		amod.SetDebug(true)
+	}
+
+	// Create our temp dir. This will expand our "temp" to an absolute path.
+	err = clicontext.CreateTempDir(c)
+	if err != nil {
+		return
+	}
+
+	return createFrameworks(c, progress)
 }
 
 // setupVirtualEnvironment will set our paths to our virtual environment path.
@@ -167,10 +120,10 @@ func setupVirtualEnvironment(ctx *cli.Context) (err error) {
 	return
 }
 
-func createFrameworks(cli *cli.Context) (frameworks framework.List, err error) {
-	list := cli.StringSlice("framework")
+func createFrameworks(ctx *cli.Context, progress io.Writer) (frameworks framework.List, err error) {
+	list := ctx.StringSlice("framework")
 	if len(list) == 0 {
-		err = fmt.Errorf("no frameworks specified on command line")
+		err = cli.Exit("no frameworks specified on command line", exitUsage)
 		return
 	}
 
@@ -186,29 +139,35 @@ func createFrameworks(cli *cli.Context) (frameworks framework.List, err error) {
 		var createErr error
 		switch f {
 		case "ccm":
-			frameworks["ccm"], createErr = ccm_pyactr.New(cli)
+			frameworks["ccm"], createErr = ccm_pyactr.New(ctx)
 		case "pyactr":
-			frameworks["pyactr"], createErr = pyactr.New(cli)
+			frameworks["pyactr"], createErr = pyactr.New(ctx)
 		case "vanilla":
-			frameworks["vanilla"], createErr = vanilla_actr.New(cli)
+			frameworks["vanilla"], createErr = vanilla_actr.New(ctx)
 		default:
-			err = fmt.Errorf("unknown framework: %s", f)
+			err = cli.Exit(fmt.Sprintf("unknown framework: %s", f), exitUsage)
 			return framework.List{}, err
 		}
 
 		if createErr != nil {
-			fmt.Println(createErr.Error())
+			fmt.Fprintln(progress, createErr.Error())
 		}
 	}
 
 	if len(frameworks) == 0 {
-		err = fmt.Errorf("could not create any frameworks - please check your installation")
+		err = cli.Exit("could not create any frameworks - please check your installation", exitFramework)
 		return framework.List{}, err
 	}
 
 	return
 }
 
+// showVersion outputs the version the same way as cli.ShowVersion, but to progress instead of the
+// app's writer.
+func showVersion(ctx *cli.Context, progress io.Writer) {
+	fmt.Fprintf(progress, "%v version %v\n", ctx.App.Name, ctx.App.Version)
+}
+
 func handleWeb(ctx *cli.Context, frameworks framework.List) (err error) {
 	w, err := web.Initialize(ctx, frameworks, &amodExamples)
 	if err != nil {
@@ -236,115 +195,3 @@ func handleInteractive(ctx *cli.Context, frameworks framework.List) (err error)
 
 	return
 }
-
-func handleDefault(ctx *cli.Context, frameworks framework.List) (err error) {
-	cli.ShowVersion(ctx)
-
-	// Check if files exist first
-	files := ctx.Args().Slice()
-
-	if len(files) == 0 {
-		err = fmt.Errorf("error: no input files specified on command line")
-		return
-	}
-
-	existingFiles := files[:0]
-	for _, file := range files {
-		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
-			fmt.Printf("error: file does not exist - %q\n", file)
-			continue
-		}
-
-		existingFiles = append(existingFiles, file)
-	}
-
-	if len(existingFiles) == 0 {
-		err = fmt.Errorf("error: no files to process")
-		return
-	}
-
-	tempPath := ctx.Path("temp")
-	fmt.Printf("Intermediate file path: %q\n", tempPath)
-
-	err = generateCode(frameworks, existingFiles, tempPath, ctx.Bool("run"))
-	if err != nil {
-		return err
-	}
-
-	if ctx.Bool("run") {
-		runCode(frameworks)
-	}
-	return
-}
-
-func generateCode(frameworks framework.List, files []string, outputDir string, runCode bool) (err error) {
-	modelMap := map[string]*actr.Model{}
-
-	for _, file := range files {
-		fmt.Printf("Generating model for %s\n", file)
-		model, log, err := amod.GenerateModelFromFile(file)
-		if err != nil {
-			fmt.Print(log)
-			continue
-		}
-
-		// When using "-r" the goal must be initialized in the code.
-		validate.Goal(model, "", log)
-
-		fmt.Print(log)
-
-		modelMap[file] = model
-	}
-
-	if len(modelMap) == 0 {
-		err = errors.New("no valid models to run")
-		return
-	}
-
-	for _, f := range frameworks {
-		err = f.Initialize()
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
-
-		for file, model := range modelMap {
-			fmt.Printf("\t- generating code for %s\n", file)
-
-			log := f.ValidateModel(model)
-			fmt.Print(log)
-			if log.HasError() {
-				continue
-			}
-
-			err = f.SetModel(model)
-			if err != nil {
-				fmt.Println(err.Error())
-				continue
-			}
-
-			fileName, err := f.WriteModel(outputDir, framework.InitialBuffers{})
-			if err != nil {
-				fmt.Println(err.Error())
-				continue
-			}
-			fmt.Printf("\t- written to %s\n", fileName)
-		}
-	}
-
-	return
-}
-
-func runCode(frameworks framework.List) {
-	for _, f := range frameworks {
-		result, err := f.Run(framework.InitialBuffers{})
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
-
-		fmt.Printf("== %s ==\n", f.Info().Name)
-		fmt.Println(string(result.Output))
-		fmt.Println()
-	}
-}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/runstats"
+)
+
+// Formats for "--output".
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Values for modelResult.Status.
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// modelResult is output for each file & framework when using "--output json". It uses the same
+// field names as the web API's run results so tools can read both.
+type modelResult struct {
+	File      string            `json:"file"`                // amod file
+	Framework string            `json:"framework,omitempty"` // empty if the file failed to compile or we are only validating
+	ModelName string            `json:"modelName,omitempty"` // name of the model (from the amod file)
+	Issues    *issues.IssueList `json:"issues,omitempty"`    // compile issues & issues specific to this framework
+
+	FilePath *string `json:"filePath,omitempty"` // intermediate code file (full path)
+	Output   *string `json:"output,omitempty"`   // output of run (stdout + stderr)
+
+	Run      int                `json:"run,omitempty"`      // run number when using "--repeat"
+	Seed     *uint32            `json:"seed,omitempty"`     // random seed the model was generated with
+	Trace    *framework.Trace   `json:"trace,omitempty"`    // summary of the run parsed from the output
+	Measures framework.Measures `json:"measures,omitempty"` // values of the model's measures
+	State    *framework.State   `json:"state,omitempty"`    // buffers & memory at the end when using "--dump-state"
+	Stats    *runstats.Stats    `json:"stats,omitempty"`    // stats for all the runs when using "--repeat"
+
+	Status string `json:"status"` // "ok" or "error"
+}
+
+// newModelResult creates a result for a compiled file. frameworkName is empty if we aren't
+// using a framework.
+func newModelResult(c compiledFile, frameworkName string) (result modelResult) {
+	result = modelResult{
+		File:      c.file,
+		Framework: frameworkName,
+	}
+
+	if c.model != nil {
+		result.ModelName = c.model.Name
+	}
+
+	result.setIssues(c.log)
+
+	return
+}
+
+// setIssues combines the issues from the logs and sets the status based on them.
+func (r *modelResult) setIssues(logs ...*issues.Log) {
+	all := issues.IssueList{}
+	r.Status = statusOK
+
+	for _, log := range logs {
+		all = append(all, log.AllIssues()...)
+
+		if log.HasError() {
+			r.Status = statusError
+		}
+	}
+
+	r.Issues = nil
+	if len(all) > 0 {
+		r.Issues = &all
+	}
+}
+
+// setOutput sets the intermediate file and the output of the run (if any).
+func (r *modelResult) setOutput(fileName string, output []byte) {
+	if fileName != "" {
+		r.FilePath = &fileName
+	}
+
+	if len(output) > 0 {
+		outputStr := string(output)
+		r.Output = &outputStr
+	}
+}
+
+// resultWriter outputs a line of JSON for each result when using "--output json". With text
+// output, everything is output as we go so it does nothing.
+type resultWriter struct {
+	encoder *json.Encoder // nil when outputting text
+}
+
+// newResultWriter creates a writer for the format in the "output" flag.
+func newResultWriter(ctx *cli.Context) (w *resultWriter, err error) {
+	switch format := ctx.String("output"); format {
+	case "", outputText:
+		w = &resultWriter{}
+
+	case outputJSON:
+		w = &resultWriter{encoder: json.NewEncoder(os.Stdout)}
+
+	default:
+		err = cli.Exit(fmt.Sprintf("invalid output format %q (must be %q or %q)", format, outputText, outputJSON), exitUsage)
+	}
+
+	return
+}
+
+// progressWriter returns where to output everything other than the results (progress, framework
+// versions, issues, etc.). When using JSON, this is stderr so stdout only contains the results.
+func (w *resultWriter) progressWriter() io.Writer {
+	if w.encoder != nil {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+func (w *resultWriter) write(result modelResult) {
+	w.encode(result)
+}
+
+// encode outputs a value as a line of JSON when using "--output json".
+func (w *resultWriter) encode(value interface{}) {
+	if w.encoder == nil {
+		return
+	}
+
+	w.encoder.Encode(value)
+}
+
+// writeCompileErrors writes a result for each file which failed to compile.
+func (w *resultWriter) writeCompileErrors(compiled []compiledFile) {
+	for _, c := range compiled {
+		if c.model == nil {
+			w.write(newModelResult(c, ""))
+		}
+	}
+}
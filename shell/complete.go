@@ -0,0 +1,210 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+
+	"github.com/asmaloney/gactar/util/container"
+)
+
+// complete is called by the line editor when tab is pressed. It completes command names,
+// file names for "load", framework names for "frameworks", and chunks & slot values for "run" & "watch".
+func (s *Shell) complete(line string) (start int, candidates []string) {
+	start = strings.LastIndexAny(line, " \t") + 1
+	word := line[start:]
+
+	fields := strings.Fields(line[:start])
+	if len(fields) == 0 {
+		names := make([]string, 0, len(s.commands))
+		for name := range s.commands {
+			names = append(names, name)
+		}
+
+		return start, filterPrefix(names, word)
+	}
+
+	switch fields[0] {
+	case "load":
+		candidates = completePath(word)
+
+	case "frameworks":
+		candidates = filterPrefix(append(s.actrFrameworks.Names(), "all"), word)
+
+	case "dump":
+		candidates = filterPrefix([]string{"on", "off"}, word)
+
+	case "run", "watch":
+		candidates = s.completeGoal(line[len(fields[0]):start], word)
+
+	case "set":
+		if s.currentModel != nil && len(fields) == 1 {
+			names := []string{}
+			for _, module := range s.currentModel.Modules {
+				for _, param := range module.Params() {
+					names = append(names, module.ModuleName()+"."+param.Name)
+				}
+			}
+
+			candidates = filterPrefix(names, word)
+		}
+
+	case "init":
+		if s.currentModel != nil {
+			if len(fields) == 1 {
+				candidates = filterPrefix(s.initBufferNames(), word)
+			} else {
+				candidates = s.completeGoal(line[len(fields[0])+len(fields[1])+1:start], word)
+			}
+		}
+
+	case "show":
+		if s.currentModel != nil {
+			names := []string{}
+			for _, production := range s.currentModel.Productions {
+				names = append(names, production.Name)
+			}
+
+			candidates = filterPrefix(names, word)
+		}
+	}
+
+	return
+}
+
+// completeGoal completes the chunk name and slot values of a goal such as "[countFrom: 2 5 starting]".
+// "args" is everything after "run" up to the word being completed.
+func (s *Shell) completeGoal(args, word string) (candidates []string) {
+	if s.currentModel == nil {
+		return
+	}
+
+	open := strings.LastIndex(args, "[")
+
+	// chunk name
+	if open == -1 || strings.HasPrefix(word, "[") {
+		for _, chunk := range s.currentModel.Chunks {
+			if !chunk.IsInternal() {
+				candidates = append(candidates, "["+chunk.Name+":")
+			}
+		}
+
+		return filterPrefix(candidates, word)
+	}
+
+	// slot values
+	chunkName, slots, found := strings.Cut(args[open+1:], ":")
+	if !found {
+		return
+	}
+
+	chunk := s.currentModel.LookupChunk(strings.TrimSpace(chunkName))
+	if chunk == nil {
+		return
+	}
+
+	slotIndex := len(strings.Fields(slots))
+	if slotIndex >= chunk.NumSlots {
+		return
+	}
+
+	return filterPrefix(slotValues(s.currentModel, chunk, slotIndex), word)
+}
+
+// slotValues returns the IDs and numbers used in the given slot of a chunk anywhere in the model.
+func slotValues(model *actr.Model, chunk *actr.Chunk, slotIndex int) []string {
+	values := []string{"nil"}
+
+	addPattern := func(pattern *actr.Pattern) {
+		if pattern == nil || pattern.Chunk == nil || pattern.Chunk.Name != chunk.Name || slotIndex >= len(pattern.Slots) {
+			return
+		}
+
+		for _, item := range pattern.Slots[slotIndex].Items {
+			if item.ID != nil {
+				values = append(values, *item.ID)
+			} else if item.Num != nil {
+				values = append(values, *item.Num)
+			} else if item.ChunkRef != nil {
+				values = append(values, "&"+*item.ChunkRef)
+			}
+		}
+	}
+
+	for _, example := range model.Examples {
+		addPattern(example)
+	}
+
+	for _, init := range model.Initializers {
+		addPattern(init.Pattern)
+	}
+
+	for _, production := range model.Productions {
+		for _, match := range production.Matches {
+			addPattern(match.Pattern)
+		}
+
+		for _, statement := range production.DoStatements {
+			if statement.Set != nil {
+				addPattern(statement.Set.Pattern)
+			} else if statement.Recall != nil {
+				addPattern(statement.Recall.Pattern)
+			}
+		}
+	}
+
+	return container.UniqueAndSorted(values)
+}
+
+// completePath returns the files & directories which start with "word".
+// Directories have a trailing separator so completion can continue into them.
+func completePath(word string) (candidates []string) {
+	dir, prefix := filepath.Split(word)
+
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		// don't show hidden files unless asked for
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+
+		candidate := dir + name
+		if entry.IsDir() {
+			candidate += string(filepath.Separator)
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return
+}
+
+// filterPrefix returns the sorted strings from list which start with prefix.
+func filterPrefix(list []string, prefix string) (filtered []string) {
+	for _, str := range list {
+		if strings.HasPrefix(str, prefix) {
+			filtered = append(filtered, str)
+		}
+	}
+
+	sort.Strings(filtered)
+	return
+}
@@ -0,0 +1,204 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/asmaloney/gactar/actr"
+)
+
+// These commands let us look at the current model.
+
+func (s *Shell) requireModel() (err error) {
+	if s.currentModel == nil {
+		err = fmt.Errorf("no model loaded")
+	}
+
+	return
+}
+
+func (s *Shell) cmdBuffers(string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "  buffer\tmodule\tinitial contents")
+
+	for _, module := range s.currentModel.Modules {
+		name := module.BufferName()
+		if name == "" {
+			continue
+		}
+
+		contents := "(empty)"
+
+		// buffers which allow multiple inits (e.g. memory) are listed by the "memory" command
+		if module.AllowsMultipleInit() {
+			contents = "(see 'memory')"
+		} else if init := s.currentModel.LookupInitializer(name); init != nil {
+			contents = init.Pattern.String()
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", name, module.ModuleName(), contents)
+	}
+
+	w.Flush()
+
+	return
+}
+
+func (s *Shell) cmdChunks(string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	for _, chunk := range s.currentModel.Chunks {
+		if chunk.IsInternal() {
+			continue
+		}
+
+		fmt.Printf("  %s\n", chunk)
+	}
+
+	return
+}
+
+func (s *Shell) cmdMemory(string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	memoryName := s.currentModel.Memory.ModuleName()
+
+	count := 0
+	for _, init := range s.currentModel.Initializers {
+		if init.Module.ModuleName() != memoryName {
+			continue
+		}
+
+		chunk := init.Pattern.String()
+		if init.ChunkName != "" {
+			chunk = init.ChunkName + ": " + chunk
+		}
+
+		if init.Metadata != nil {
+			fmt.Printf("  %s %s\n", chunk, init.Metadata)
+		} else {
+			fmt.Printf("  %s\n", chunk)
+		}
+		count++
+	}
+
+	if count == 0 {
+		fmt.Println("  (empty)")
+	}
+
+	return
+}
+
+func (s *Shell) cmdParams(string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	names := s.actrFrameworks.Names()
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+
+	fmt.Fprint(w, "  param\tvalue")
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s", name)
+	}
+	fmt.Fprintln(w)
+
+	for _, module := range s.currentModel.Modules {
+		for _, param := range module.Params() {
+			value := param.Value
+			if value == "" {
+				value = "-"
+			}
+
+			fmt.Fprintf(w, "  %s.%s\t%s", module.ModuleName(), param.Name, value)
+
+			for _, name := range names {
+				def := param.Defaults[name]
+				if def == "" {
+					def = "(unsupported)"
+				}
+
+				fmt.Fprintf(w, "\t%s", def)
+			}
+
+			fmt.Fprintln(w)
+		}
+	}
+
+	w.Flush()
+
+	fmt.Println("  ('-' means the framework's default is used)")
+
+	return
+}
+
+func (s *Shell) cmdProductions(string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+
+	for _, production := range s.currentModel.Productions {
+		description := ""
+		if production.Description != nil {
+			description = *production.Description
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\n", production.Name, description)
+	}
+
+	w.Flush()
+
+	return
+}
+
+func (s *Shell) cmdShow(name string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	if name == "" {
+		err = fmt.Errorf("'show' requires a production name")
+		return
+	}
+
+	production := lookupProduction(s.currentModel, name)
+	if production == nil {
+		err = fmt.Errorf("production '%s' not found", name)
+		return
+	}
+
+	fmt.Printf("// amod line %d\n", production.AMODLineNumber)
+	fmt.Println(production)
+
+	return
+}
+
+func lookupProduction(model *actr.Model, name string) *actr.Production {
+	for _, production := range model.Productions {
+		if production.Name == name {
+			return production
+		}
+	}
+
+	return nil
+}
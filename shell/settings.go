@@ -0,0 +1,264 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/asmaloney/gactar/actr/modules"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+)
+
+// These commands let us change the current model without editing the amod file.
+
+// cmdSet sets a module parameter: set memory.retrieval_threshold -0.5
+func (s *Shell) cmdSet(args string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		err = fmt.Errorf("'set' requires a parameter and a value (e.g. set memory.retrieval_threshold -0.5)")
+		return
+	}
+
+	moduleName, key, found := strings.Cut(fields[0], ".")
+	if !found {
+		err = fmt.Errorf("parameter should be in the form 'module.param' (e.g. memory.retrieval_threshold)")
+		return
+	}
+
+	err = amod.SetParam(s.currentModel, moduleName, key, fields[1])
+	if err != nil {
+		return
+	}
+
+	fmt.Printf(" %s.%s set to %s\n", moduleName, key, fields[1])
+
+	return
+}
+
+// cmdInit sets the initial contents of a buffer for runs: init imaginal [sentence: Bill likes Mary]
+// With only a buffer name it removes the contents we set, and with no arguments it lists them.
+func (s *Shell) cmdInit(args string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	if args == "" {
+		s.outputInitialBuffers()
+		return
+	}
+
+	bufferName, contents, _ := strings.Cut(args, " ")
+	contents = strings.TrimSpace(contents)
+
+	buffer := s.currentModel.LookupBuffer(bufferName)
+	if buffer == nil {
+		err = fmt.Errorf("buffer '%s' not found in model. Valid values: %v", bufferName, s.initBufferNames())
+		return
+	}
+
+	if buffer.AllowsMultipleInit() {
+		err = fmt.Errorf("buffer '%s' cannot be set this way - use initializers in the amod file", bufferName)
+		return
+	}
+
+	if contents == "" {
+		delete(s.initialBuffers, bufferName)
+		fmt.Printf(" %s will use the amod file's initializer\n", bufferName)
+		return
+	}
+
+	_, err = amod.ParseChunk(s.currentModel, contents)
+	if err != nil {
+		return
+	}
+
+	s.initialBuffers[bufferName] = contents
+
+	fmt.Printf(" %s will be initialized to %s\n", bufferName, contents)
+
+	return
+}
+
+func (s *Shell) outputInitialBuffers() {
+	if len(s.initialBuffers) == 0 {
+		fmt.Println(" no initial buffers set (the amod file's initializers are used)")
+		return
+	}
+
+	names := make([]string, 0, len(s.initialBuffers))
+	for name := range s.initialBuffers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("  %s %s\n", name, s.initialBuffers[name])
+	}
+}
+
+// initBufferNames returns the names of the buffers which may be set using "init".
+func (s *Shell) initBufferNames() (names []string) {
+	for _, module := range s.currentModel.Modules {
+		if module.BufferName() != "" && !module.AllowsMultipleInit() {
+			names = append(names, module.BufferName())
+		}
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// runBuffers returns the initial buffers to use for a run. A goal passed to "run" overrides one set using "init".
+func (s *Shell) runBuffers(initialGoal string) framework.InitialBuffers {
+	initialBuffers := framework.InitialBuffers{}
+
+	for name, contents := range s.initialBuffers {
+		initialBuffers[name] = contents
+	}
+
+	initialGoal = strings.TrimSpace(initialGoal)
+	if initialGoal != "" {
+		initialBuffers["goal"] = initialGoal
+	}
+
+	return initialBuffers
+}
+
+// cmdDiff shows how the current model differs from the amod file on disk.
+func (s *Shell) cmdDiff(string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	fileModel, log, err := amod.GenerateModelFromFile(s.currentFile)
+	if err != nil {
+		fmt.Print(log)
+		err = fmt.Errorf("could not load '%s' to compare", s.currentFile)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+	fmt.Fprintf(w, "  \tfile\tcurrent\n")
+
+	numDiffs := 0
+	diff := func(name, fileValue, currentValue string) {
+		if fileValue == currentValue {
+			return
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", name, orDash(fileValue), orDash(currentValue))
+		numDiffs++
+	}
+
+	// parameters
+	for _, module := range s.currentModel.Modules {
+		fileModule := fileModel.LookupModule(module.ModuleName())
+
+		for _, param := range module.Params() {
+			fileValue := ""
+			if fileModule != nil {
+				fileValue = lookupParamValue(fileModule.Params(), param.Name)
+			}
+
+			diff(module.ModuleName()+"."+param.Name, fileValue, param.Value)
+		}
+	}
+
+	// initial buffers
+	for _, name := range s.initBufferNames() {
+		fileValue := ""
+		if init := fileModel.LookupInitializer(name); init != nil {
+			fileValue = init.Pattern.String()
+		}
+
+		currentValue, ok := s.initialBuffers[name]
+		if !ok {
+			continue
+		}
+
+		diff("init "+name, fileValue, currentValue)
+	}
+
+	// the file itself may have been changed since it was loaded
+	fileChunks := map[string]string{}
+	for _, chunk := range fileModel.Chunks {
+		fileChunks[chunk.Name] = chunk.String()
+	}
+
+	for _, chunk := range s.currentModel.Chunks {
+		diff("chunk "+chunk.Name, fileChunks[chunk.Name], chunk.String())
+		delete(fileChunks, chunk.Name)
+	}
+
+	for _, name := range sortedKeys(fileChunks) {
+		diff("chunk "+name, fileChunks[name], "")
+	}
+
+	fileProductions := map[string]string{}
+	for _, production := range fileModel.Productions {
+		fileProductions[production.Name] = production.String()
+	}
+
+	for _, production := range s.currentModel.Productions {
+		fileProduction, ok := fileProductions[production.Name]
+
+		switch {
+		case !ok:
+			diff("production "+production.Name, "", "(defined)")
+		case fileProduction != production.String():
+			diff("production "+production.Name, "(changed)", "(defined)")
+		}
+
+		delete(fileProductions, production.Name)
+	}
+
+	for _, name := range sortedKeys(fileProductions) {
+		diff("production "+name, "(defined)", "")
+	}
+
+	if numDiffs == 0 {
+		fmt.Printf(" no differences from %s\n", s.currentFile)
+		return
+	}
+
+	w.Flush()
+
+	return
+}
+
+func lookupParamValue(params []modules.ParamInfo, name string) string {
+	for _, param := range params {
+		if param.Name == name {
+			return param.Value
+		}
+	}
+
+	return ""
+}
+
+func orDash(str string) string {
+	if str == "" {
+		return "-"
+	}
+
+	return str
+}
+
+func sortedKeys(m map[string]string) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return
+}
@@ -3,9 +3,13 @@ package shell
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
@@ -15,7 +19,9 @@ import (
 	"github.com/asmaloney/gactar/amod"
 	"github.com/asmaloney/gactar/framework"
 
+	"github.com/asmaloney/gactar/util/filesystem"
 	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/lineedit"
 	"github.com/asmaloney/gactar/util/validate"
 )
 
@@ -24,10 +30,42 @@ type command struct {
 	method      func(string) error
 }
 
+// historyFileName is the name of the file in the user's home directory used to save the history.
+const historyFileName = ".gactar_history"
+
+// ExitError is returned from Start() when the shell exits with a non-zero status.
+// It implements cli.ExitCoder so the status is used as the program's exit code.
+type ExitError struct {
+	code int
+	err  error // the error which caused the exit (if any)
+}
+
+func (e ExitError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e ExitError) ExitCode() int {
+	return e.code
+}
+
+func (e ExitError) Unwrap() error {
+	return e.err
+}
+
 type Shell struct {
 	context          *cli.Context
-	history          []string
+	editor           *lineedit.Editor
+	interactive      bool // true if a person is typing commands at a prompt
 	currentModel     *actr.Model
+	currentFile      string                   // amod file the current model was loaded from
+	initialBuffers   framework.InitialBuffers // set using the "init" command
+	captureDir       string                   // directory to save run output in (set using the "capture" command)
+	runCount         int                      // used to name captured output files
+	dumpState        bool                     // output the buffers & memory after each run (set using the "dump" command)
 	actrFrameworks   framework.List
 	activeFrameworks map[string]bool
 	commands         map[string]command
@@ -38,12 +76,17 @@ func Initialize(cli *cli.Context, frameworks framework.List) (s *Shell, err erro
 		context:          cli,
 		actrFrameworks:   frameworks,
 		activeFrameworks: map[string]bool{},
+		initialBuffers:   framework.InitialBuffers{},
 	}
 
-	s.preamble()
+	s.interactive = cli.Path("script") == "" && lineedit.IsTerminal(os.Stdin)
+
+	if s.interactive {
+		s.preamble()
+	}
 
 	for name, framework := range frameworks {
-		err = framework.Initialize()
+		err = framework.Initialize(os.Stdout)
 		if err != nil {
 			return nil, err
 		}
@@ -51,6 +94,21 @@ func Initialize(cli *cli.Context, frameworks framework.List) (s *Shell, err erro
 		s.activeFrameworks[name] = true
 	}
 
+	s.initCommands()
+
+	s.editor, err = lineedit.New(historyFilePath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, " warning: could not read history: %s\n", err)
+		err = nil
+	}
+
+	s.editor.Complete = s.complete
+
+	return
+}
+
+// initCommands sets up the list of commands the shell understands.
+func (s *Shell) initCommands() {
 	s.commands = map[string]command{
 		"frameworks": {`choose frameworks to run (e.g. "ccm pyactr", "all")`, s.cmdFramework},
 		"history":    {"outputs your command history", s.cmdHistory},
@@ -59,26 +117,57 @@ func Initialize(cli *cli.Context, frameworks framework.List) (s *Shell, err erro
 		"run":        {"runs the current model: run [INITIAL STATE]", s.cmdRun},
 		"version":    {"outputs version info", s.cmdVersion},
 
-		"help": {"exits the program", s.cmdHelp},
-		"exit": {"exits the program", s.cmdExit},
-		"quit": {"exits the program", s.cmdExit},
-	}
+		"buffers":     {"lists the buffers and their initial contents", s.cmdBuffers},
+		"chunks":      {"lists the chunks declared in the current model", s.cmdChunks},
+		"memory":      {"lists the initial contents of memory", s.cmdMemory},
+		"params":      {"lists module parameters and the defaults for each framework", s.cmdParams},
+		"productions": {"lists the productions in the current model", s.cmdProductions},
+		"show":        {"shows a production: show [PRODUCTION]", s.cmdShow},
 
-	return
+		"diff": {"shows how the current model differs from the amod file", s.cmdDiff},
+		"init": {"sets the initial contents of a buffer for runs: init [BUFFER] [CONTENTS]", s.cmdInit},
+		"set":  {"sets a module parameter: set [MODULE.PARAM] [VALUE]", s.cmdSet},
+
+		"watch": {"reloads & reruns the model whenever its file changes: watch [INITIAL STATE]", s.cmdWatch},
+
+		"capture": {"saves the output of each run to a directory: capture [DIRECTORY] or capture off", s.cmdCapture},
+		"dump":    {"outputs the buffers & memory at the end of each run: dump on or dump off", s.cmdDump},
+		"echo":    {"outputs text (useful in scripts): echo [TEXT]", s.cmdEcho},
+
+		"help": {"outputs this list of commands", s.cmdHelp},
+		"exit": {"exits the program: exit [STATUS]", s.cmdExit},
+		"quit": {"exits the program: quit [STATUS]", s.cmdExit},
+	}
 }
 
+// Start runs commands until "exit" or the end of the input.
+// If a script was given using "--script" or the input is not a terminal, the commands are read
+// without prompting and the first one which fails stops the script.
 func (s *Shell) Start() (err error) {
-	if err != nil {
-		return
+	if scriptFile := s.context.Path("script"); scriptFile != "" {
+		file, err := os.Open(scriptFile)
+		if err != nil {
+			return ExitError{code: 1, err: err}
+		}
+		defer file.Close()
+
+		return s.runScript(file, scriptFile)
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Print("> ")
+	if !s.interactive {
+		return s.runScript(os.Stdin, "stdin")
+	}
 
-		cmd, err := reader.ReadString('\n')
+	for {
+		cmd, err := s.editor.ReadLine("> ")
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			return err
 		}
 
 		cmd = strings.TrimSpace(cmd)
@@ -87,15 +176,80 @@ func (s *Shell) Start() (err error) {
 			continue
 		}
 
-		s.history = append(s.history, cmd)
+		err = s.editor.AddHistory(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, " warning: could not save history: %s\n", err)
+		}
+
+		if isComment(cmd) {
+			continue
+		}
 
 		err = s.runCommand(cmd)
+
+		var exitErr ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.code == 0 {
+				return nil
+			}
+
+			return exitErr
+		}
+
 		if err != nil {
 			fmt.Fprintf(os.Stderr, " error: %s\n", err)
 		}
 	}
 }
 
+// runScript runs each line from "reader" as a command. "name" is used in error messages.
+func (s *Shell) runScript(reader io.Reader, name string) (err error) {
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		cmd := strings.TrimSpace(scanner.Text())
+		if cmd == "" || isComment(cmd) {
+			continue
+		}
+
+		err = s.runCommand(cmd)
+
+		var exitErr ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.code == 0 {
+				return nil
+			}
+
+			return exitErr
+		}
+
+		if err != nil {
+			err = fmt.Errorf("%s line %d: %w", name, lineNumber, err)
+			return ExitError{code: 1, err: err}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// isComment checks if the line is a comment (starts with '#').
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#")
+}
+
+// historyFilePath returns the path to the history file or "" if we can't find the home directory.
+func historyFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, historyFileName)
+}
+
 func (s *Shell) preamble() {
 	cli.ShowVersion(s.context)
 	fmt.Println("Type 'help' for a list of commands.")
@@ -158,7 +312,7 @@ func (s *Shell) cmdFramework(fNames string) (err error) {
 }
 
 func (s *Shell) cmdHistory(string) (err error) {
-	fmt.Println(strings.Join(s.history, "\n"))
+	fmt.Println(strings.Join(s.editor.History(), "\n"))
 	return
 }
 
@@ -175,6 +329,8 @@ func (s *Shell) cmdLoad(fileName string) (err error) {
 	}
 
 	s.currentModel = model
+	s.currentFile = fileName
+	s.initialBuffers = framework.InitialBuffers{}
 
 	fmt.Println(" model loaded")
 
@@ -207,6 +363,8 @@ func (s *Shell) cmdLoad(fileName string) (err error) {
 
 func (s *Shell) cmdReset(string) (err error) {
 	s.currentModel = nil
+	s.currentFile = ""
+	s.initialBuffers = framework.InitialBuffers{}
 	fmt.Println(" model reset")
 	return
 }
@@ -217,24 +375,40 @@ func (s *Shell) cmdRun(initialGoal string) (err error) {
 		return
 	}
 
+	initialBuffers := s.runBuffers(initialGoal)
+
 	log := issues.New()
-	validate.Goal(s.currentModel, initialGoal, log)
+	validate.Goal(s.currentModel, initialBuffers["goal"], log)
 	fmt.Print(log)
 
-	for name, f := range s.actrFrameworks {
+	if log.HasError() {
+		err = fmt.Errorf("invalid initial goal")
+		return
+	}
+
+	s.runCount++
+
+	// run them in a consistent order so output (especially from scripts) is reproducible
+	names := s.actrFrameworks.Names()
+	sort.Strings(names)
+
+	for _, name := range names {
 		if !s.activeFrameworks[name] {
 			continue
 		}
 
+		f := s.actrFrameworks[name]
+
 		fmt.Printf("== %s ==\n", f.Info().Name)
 
-		err = f.SetModel(s.currentModel)
-		if err != nil {
-			return err
+		model := s.currentModel
+		if s.dumpState {
+			model = model.WithDumpState()
 		}
 
-		initialBuffers := framework.InitialBuffers{
-			"goal": strings.TrimSpace(initialGoal),
+		err = f.SetModel(model)
+		if err != nil {
+			return err
 		}
 
 		result, err := f.Run(initialBuffers)
@@ -244,14 +418,93 @@ func (s *Shell) cmdRun(initialGoal string) (err error) {
 
 		fmt.Print(string(result.Output))
 
-		if result.Output[len(result.Output)-1] != '\n' {
+		if len(result.Output) > 0 && result.Output[len(result.Output)-1] != '\n' {
 			fmt.Println()
 		}
+
+		framework.WriteMeasures(os.Stdout, s.currentModel, result.Measures)
+		framework.WriteState(os.Stdout, s.currentModel, result.State)
+
+		err = s.captureOutput(name, result.Output)
+		if err != nil {
+			return err
+		}
 	}
 
 	return
 }
 
+// cmdCapture sets the directory to save run output in.
+func (s *Shell) cmdCapture(dir string) (err error) {
+	switch dir {
+	case "":
+		if s.captureDir == "" {
+			fmt.Println(" output is not being captured")
+		} else {
+			fmt.Printf(" capturing output in %s\n", s.captureDir)
+		}
+
+	case "off":
+		s.captureDir = ""
+		fmt.Println(" output capture off")
+
+	default:
+		err = filesystem.CreateDir(dir)
+		if err != nil {
+			return
+		}
+
+		s.captureDir = dir
+		fmt.Printf(" capturing output in %s\n", dir)
+	}
+
+	return
+}
+
+// cmdDump turns outputting the buffers & memory at the end of each run on or off.
+func (s *Shell) cmdDump(setting string) (err error) {
+	switch setting {
+	case "":
+
+	case "on":
+		s.dumpState = true
+
+	case "off":
+		s.dumpState = false
+
+	default:
+		err = fmt.Errorf("expected 'on' or 'off': %q", setting)
+		return
+	}
+
+	if s.dumpState {
+		fmt.Println(" dumping state after each run")
+	} else {
+		fmt.Println(" state dump off")
+	}
+
+	return
+}
+
+// captureOutput writes the output of a run to a file if "capture" is on.
+// Files are named <model>-<run number>-<framework>.txt.
+func (s *Shell) captureOutput(frameworkName string, output []byte) (err error) {
+	if s.captureDir == "" {
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-%03d-%s.txt", s.currentModel.Name, s.runCount, frameworkName)
+	path := filepath.Join(s.captureDir, fileName)
+
+	err = os.WriteFile(path, output, 0644)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf(" output saved to %s\n", path)
+	return
+}
+
 func (s *Shell) cmdVersion(string) (err error) {
 	cli.ShowVersion(s.context)
 	return
@@ -274,7 +527,21 @@ func (s *Shell) cmdHelp(string) (err error) {
 	return
 }
 
-func (s *Shell) cmdExit(string) (err error) {
-	os.Exit(0)
+func (s *Shell) cmdExit(status string) (err error) {
+	code := 0
+
+	if status != "" {
+		code, err = strconv.Atoi(status)
+		if err != nil {
+			err = fmt.Errorf("exit status must be a number: %q", status)
+			return
+		}
+	}
+
+	return ExitError{code: code}
+}
+
+func (s *Shell) cmdEcho(text string) (err error) {
+	fmt.Println(text)
 	return
 }
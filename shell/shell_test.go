@@ -0,0 +1,130 @@
+package shell
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+func newTestShell() *Shell {
+	s := &Shell{
+		actrFrameworks:   framework.List{},
+		activeFrameworks: map[string]bool{},
+		initialBuffers:   framework.InitialBuffers{},
+	}
+
+	s.initCommands()
+
+	return s
+}
+
+func TestRunScript(t *testing.T) {
+	script := `
+# comments and blank lines are ignored
+
+echo hello
+load ../examples/count.amod
+set memory.retrieval_threshold -0.5
+init goal [countFrom: 1 3 starting]
+`
+
+	s := newTestShell()
+
+	err := s.runScript(strings.NewReader(script), "test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if s.currentModel == nil {
+		t.Fatal("Expected model to be loaded")
+	}
+
+	if *s.currentModel.Memory.RetrievalThreshold != -0.5 {
+		t.Errorf("Expected retrieval_threshold to be set")
+	}
+
+	if s.initialBuffers["goal"] != "[countFrom: 1 3 starting]" {
+		t.Errorf("Expected goal to be set, got %q", s.initialBuffers["goal"])
+	}
+}
+
+func TestRunScriptFailure(t *testing.T) {
+	script := `echo one
+run
+echo not reached`
+
+	err := newTestShell().runScript(strings.NewReader(script), "test.txt")
+
+	var exitErr ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected ExitError, got %v", err)
+	}
+
+	if exitErr.ExitCode() != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitErr.ExitCode())
+	}
+
+	expected := "test.txt line 2: no model loaded"
+	if exitErr.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, exitErr.Error())
+	}
+}
+
+func TestRunScriptExit(t *testing.T) {
+	tests := []struct {
+		script string
+		code   int
+	}{
+		{"exit\necho not reached\nfoo", 0},
+		{"quit 3", 3},
+		{"foo", 1},
+	}
+
+	for _, tt := range tests {
+		err := newTestShell().runScript(strings.NewReader(tt.script), "test")
+
+		code := 0
+
+		var exitErr ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.script, err)
+		}
+
+		if code != tt.code {
+			t.Errorf("%q: expected exit code %d, got %d", tt.script, tt.code, code)
+		}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "output")
+
+	s := newTestShell()
+
+	err := s.runScript(strings.NewReader("load ../examples/count.amod\ncapture "+dir), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.runCount = 2
+
+	err = s.captureOutput("ccm", []byte("output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "count-002-ccm.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "output" {
+		t.Errorf("Unexpected captured output: %q", string(data))
+	}
+}
@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/asmaloney/gactar/amod"
+
+	"github.com/asmaloney/gactar/util/watch"
+)
+
+// cmdWatch reloads and reruns the current model each time its amod file or the data files it loads
+// change until ctrl-C is pressed: watch [INITIAL STATE]
+func (s *Shell) cmdWatch(initialGoal string) (err error) {
+	err = s.requireModel()
+	if err != nil {
+		return
+	}
+
+	if s.currentFile == "" {
+		err = fmt.Errorf("the current model was not loaded from a file")
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for {
+		// The data files may change each time the model is reloaded.
+		files := append([]string{s.currentFile}, s.currentModel.SourceFiles...)
+		watcher := watch.New(files, watch.DefaultInterval)
+
+		fmt.Printf(" watching %s for changes (press ctrl-C to stop)\n", strings.Join(files, ", "))
+
+		var changed []string
+		changed, err = watcher.Wait(ctx)
+		if errors.Is(err, context.Canceled) {
+			fmt.Println()
+			fmt.Println(" stopped watching")
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		fmt.Println(strings.Repeat("-", 80))
+		fmt.Printf(" [%s] %s changed\n", time.Now().Format("15:04:05"), strings.Join(changed, ", "))
+
+		// Only rerun if the model compiled - otherwise we keep the previous one around.
+		err = s.reload()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, " error: %s\n", err)
+			continue
+		}
+
+		err = s.cmdRun(initialGoal)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, " error: %s\n", err)
+		}
+	}
+}
+
+// reload loads the current file again, keeping any buffers set using "init" which are still valid.
+func (s *Shell) reload() (err error) {
+	initialBuffers := s.initialBuffers
+
+	err = s.cmdLoad(s.currentFile)
+	if err != nil {
+		return
+	}
+
+	for name, contents := range initialBuffers {
+		_, parseErr := amod.ParseChunk(s.currentModel, contents)
+		if s.currentModel.LookupBuffer(name) == nil || parseErr != nil {
+			fmt.Printf(" warning: %s is no longer valid for this model - not initializing it to %s\n", name, contents)
+			continue
+		}
+
+		s.initialBuffers[name] = contents
+	}
+
+	return
+}
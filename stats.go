@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/filesystem"
+	"github.com/asmaloney/gactar/util/runstats"
+)
+
+// outputStats aggregates the runs of each model on each framework. The stats are output when
+// using "--repeat", and the runs & stats are written as CSV files when using "--csv".
+func (b *batch) outputStats(jobs []*job) {
+	runs := []runstats.Run{}
+	results := []runstats.Result{}
+
+	// Jobs for the same file & framework are next to each other.
+	for start := 0; start < len(jobs); {
+		end := start + 1
+		for end < len(jobs) && jobs[end].compiled.file == jobs[start].compiled.file && jobs[end].frameworkName == jobs[start].frameworkName {
+			end++
+		}
+
+		key := runstats.Key{
+			File:      jobs[start].compiled.file,
+			Model:     jobs[start].compiled.model.Name,
+			Framework: jobs[start].frameworkName,
+		}
+
+		traces := []*framework.Trace{}
+		for _, j := range jobs[start:end] {
+			if !j.done {
+				continue
+			}
+
+			traces = append(traces, j.trace)
+			runs = append(runs, runstats.Run{Key: key, Run: j.run, Seed: j.seed, Trace: j.trace})
+		}
+
+		start = end
+
+		if len(traces) == 0 {
+			continue
+		}
+
+		stats := runstats.Compute(traces)
+		results = append(results, runstats.Result{Key: key, Stats: stats})
+
+		if b.repeat > 1 {
+			outputRunStats(b.progress, key, stats)
+
+			result := newModelResult(jobs[end-1].compiled, key.Framework)
+			result.Stats = stats
+			if stats.Failures > 0 {
+				result.Status = statusError
+			}
+			b.results.write(result)
+		}
+	}
+
+	if b.csvDir != "" {
+		err := writeStatsCSV(b.csvDir, runs, results)
+		if err != nil {
+			fmt.Fprintln(b.progress, err.Error())
+			b.fail(exitGeneral, err)
+			return
+		}
+
+		fmt.Fprintf(b.progress, "Run data written to %s\n", b.csvDir)
+	}
+}
+
+// outputRunStats outputs the stats for one model on one framework to progress.
+func outputRunStats(progress io.Writer, key runstats.Key, stats *runstats.Stats) {
+	fmt.Fprintln(progress)
+	fmt.Fprintf(progress, "Stats for %s (%s) using %s - %d runs, %d failed:\n", key.File, key.Model, key.Framework, stats.Runs+stats.Failures, stats.Failures)
+
+	if stats.Runs == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(progress, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "  MEASURE\tMEAN\tSD\tMIN\tMAX")
+
+	writeSummary := func(name string, s runstats.Summary) {
+		fmt.Fprintf(w, "  %s\t%.4g\t%.4g\t%.4g\t%.4g\n", name, s.Mean, s.SD, s.Min, s.Max)
+	}
+
+	writeSummary("end time", stats.EndTime)
+	writeSummary("retrievals", stats.Retrievals)
+	writeSummary("retrieval failures", stats.RetrievalFailures)
+
+	if stats.RetrievalSuccessRate != nil {
+		writeSummary("retrieval success rate", *stats.RetrievalSuccessRate)
+	}
+
+	names := make([]string, 0, len(stats.Productions))
+	for name := range stats.Productions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		writeSummary("fired: "+name, stats.Productions[name])
+	}
+
+	w.Flush()
+
+	if len(stats.Outputs) == 0 {
+		return
+	}
+
+	fmt.Fprintln(progress, "  Output:")
+	for _, output := range stats.Outputs {
+		printed := strings.ReplaceAll(output.Printed, "\n", " | ")
+		fmt.Fprintf(progress, "    %d of %d (%.0f%%): %q\n", output.Count, stats.Runs, output.Proportion*100, printed)
+	}
+}
+
+// writeStatsCSV writes runs.csv, stats.csv, and outputs.csv to dir.
+func writeStatsCSV(dir string, runs []runstats.Run, results []runstats.Result) (err error) {
+	err = filesystem.CreateDir(dir)
+	if err != nil {
+		return
+	}
+
+	writeFile := func(name string, write func(f *os.File) error) (err error) {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return
+		}
+		defer f.Close()
+
+		return write(f)
+	}
+
+	err = writeFile("runs.csv", func(f *os.File) error { return runstats.WriteRunsCSV(f, runs) })
+	if err != nil {
+		return
+	}
+
+	err = writeFile("stats.csv", func(f *os.File) error { return runstats.WriteStatsCSV(f, results) })
+	if err != nil {
+		return
+	}
+
+	return writeFile("outputs.csv", func(f *os.File) error { return runstats.WriteOutputsCSV(f, results) })
+}
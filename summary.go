@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputSummary outputs a table with the result of each model on each framework to progress.
+func outputSummary(progress io.Writer, compiled []compiledFile, frameworkNames []string, jobs []*job) {
+	sort.Strings(frameworkNames)
+
+	type key struct{ file, framework string }
+
+	// There is more than one job for each file & framework when using "--repeat".
+	jobMap := make(map[key][]*job, len(jobs))
+	for _, j := range jobs {
+		k := key{j.compiled.file, j.frameworkName}
+		jobMap[k] = append(jobMap[k], j)
+	}
+
+	fmt.Fprintln(progress)
+	fmt.Fprintln(progress, "Summary:")
+
+	w := tabwriter.NewWriter(progress, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "  FILE\tMODEL\t%s\n", strings.ToUpper(strings.Join(frameworkNames, "\t")))
+
+	numOK := 0
+	numRun := 0
+	numCompileErrors := 0
+
+	for _, c := range compiled {
+		if c.model == nil {
+			fmt.Fprintf(w, "  %s\t-\tcompile error\n", c.file)
+			numCompileErrors++
+			continue
+		}
+
+		statuses := make([]string, len(frameworkNames))
+		for i, name := range frameworkNames {
+			runs := jobMap[key{c.file, name}]
+			statuses[i] = summaryStatus(runs)
+
+			for _, j := range runs {
+				if j.done {
+					numRun++
+					if j.status == 0 {
+						numOK++
+					}
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", c.file, c.model.Name, strings.Join(statuses, "\t"))
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(progress, "%d of %d succeeded", numOK, numRun)
+	if numCompileErrors > 0 {
+		fmt.Fprintf(progress, " (%d of %d files failed to compile)", numCompileErrors, len(compiled))
+	}
+	fmt.Fprintln(progress)
+}
+
+// summaryStatus returns the status of the runs of one model on one framework.
+func summaryStatus(runs []*job) string {
+	if len(runs) > 1 {
+		done, failed := 0, 0
+		for _, j := range runs {
+			if j.done {
+				done++
+				if j.status != 0 {
+					failed++
+				}
+			}
+		}
+
+		switch {
+		case done == 0:
+			return "skipped"
+		case runs[0].status == exitCompile:
+			return "invalid"
+		case failed > 0:
+			return fmt.Sprintf("%d/%d failed", failed, done)
+		default:
+			return "ok"
+		}
+	}
+
+	j := runs[0]
+
+	switch {
+	case !j.done:
+		return "skipped"
+	case j.status == exitCompile:
+		return "invalid"
+	case j.status != 0:
+		return "failed"
+	default:
+		return "ok"
+	}
+}
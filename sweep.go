@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/sweep"
+
+	"github.com/asmaloney/gactar/util/container"
+	"github.com/asmaloney/gactar/util/validate"
+)
+
+// sweepAction is used by the "sweep" command.
+func sweepAction(c *cli.Context) (err error) {
+	// Unless we are writing to a file, the CSV goes to stdout so everything else goes to stderr.
+	var progress io.Writer = os.Stdout
+	if c.Path("csv") == "" {
+		progress = os.Stderr
+	}
+
+	frameworks, err := setup(c, progress)
+	if err != nil {
+		return
+	}
+
+	return handleSweep(c, frameworks, os.Stdout, progress)
+}
+
+// handleSweep runs the model using each combination of parameter values on each framework and
+// writes the stats as a CSV file to out (or the "--csv" file). Everything else is output to progress.
+func handleSweep(ctx *cli.Context, frameworks framework.List, out, progress io.Writer) (err error) {
+	showVersion(ctx, progress)
+
+	if ctx.Args().Len() != 1 {
+		err = cli.Exit("sweep requires one amod file", exitUsage)
+		return
+	}
+
+	file := ctx.Args().First()
+
+	s := sweep.Sweep{
+		Repeat: ctx.Int("repeat"),
+	}
+
+	s.Seed, err = seedFlagValue(ctx)
+	if err != nil {
+		return
+	}
+
+	if params, ok := ctx.Generic("param").(*paramFlag); ok {
+		s.Params = params.params
+	}
+
+	if len(s.Params) == 0 {
+		err = cli.Exit("sweep requires at least one --param", exitUsage)
+		return
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	s.Source = string(source)
+	s.SourceDir = filepath.Dir(file)
+
+	fmt.Fprintf(progress, "Generating model for %s\n", file)
+	model, log, err := amod.GenerateModelInDir(s.Source, s.SourceDir)
+	if err == nil {
+		// The goal must be initialized in the code.
+		validate.Goal(model, "", log)
+	}
+	fmt.Fprint(progress, log)
+
+	if err != nil {
+		err = cli.Exit(fmt.Sprintf("%s has errors", file), exitCompile)
+		return
+	}
+
+	frameworks, failed := initializeFrameworks(frameworks, progress)
+	if len(frameworks) == 0 {
+		err = cli.Exit("could not initialize any frameworks - please check your installation", exitFramework)
+		return
+	}
+
+	// If frameworks were chosen on the command line, they must all work.
+	if len(failed) > 0 && !container.Contains("all", ctx.StringSlice("framework")) {
+		err = cli.Exit(fmt.Sprintf("could not initialize %s", strings.Join(failed, ", ")), exitFramework)
+		return
+	}
+
+	points, err := s.Points()
+	if err != nil {
+		err = cli.Exit(err, exitUsage)
+		return
+	}
+
+	// Create the file before running so we don't lose the results if we can't write it.
+	if path := ctx.Path("csv"); path != "" {
+		var f *os.File
+		f, err = os.Create(path)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	names := frameworks.Names()
+	sort.Strings(names)
+
+	tasks := s.Tasks(points, names, filepath.Join(ctx.Path("temp"), "sweep"))
+
+	fmt.Fprintf(progress, "Sweeping %d combinations of parameters using %s (%d runs)\n", len(points), strings.Join(names, ", "), len(tasks))
+
+	runTasks(tasks, frameworks, ctx.Int("jobs"))
+
+	results := sweep.Collect(points, tasks)
+
+	numFailed := 0
+	for _, task := range tasks {
+		if task.Err != nil {
+			numFailed++
+		}
+	}
+
+	// The errors are usually the same for every run, so only output the first one in full.
+	firstError := ""
+	for _, result := range results {
+		if result.Stats.Failures == 0 {
+			continue
+		}
+
+		fmt.Fprintf(progress, "%s failed using %s (%d of %d runs)\n", formatValues(s.Params, result.Values), result.Framework, result.Stats.Failures, result.Stats.Runs+result.Stats.Failures)
+
+		if firstError == "" {
+			firstError = result.Errors[0]
+		}
+	}
+
+	if firstError != "" {
+		fmt.Fprintf(progress, "First error:\n%s\n", firstError)
+	}
+
+	err = sweep.WriteCSV(out, s.Params, results)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(progress, "%d of %d runs succeeded\n", len(tasks)-numFailed, len(tasks))
+
+	if numFailed > 0 {
+		err = cli.Exit(fmt.Sprintf("%d runs failed", numFailed), exitRun)
+	}
+
+	return
+}
+
+// paramFlag collects the "--param" values. We can't use a string slice since it splits values on
+// commas, which we use for lists of values.
+type paramFlag struct {
+	params []sweep.Param
+}
+
+// serializedParamsPrefix marks the value the cli package uses to copy the params between the
+// flag's name & alias.
+const serializedParamsPrefix = "serialized-params:"
+
+func (p *paramFlag) Set(value string) (err error) {
+	if strings.HasPrefix(value, serializedParamsPrefix) {
+		return json.Unmarshal([]byte(strings.TrimPrefix(value, serializedParamsPrefix)), &p.params)
+	}
+
+	param, err := sweep.ParseParam(value)
+	if err != nil {
+		return
+	}
+
+	p.params = append(p.params, param)
+	return
+}
+
+func (p *paramFlag) String() string {
+	specs := make([]string, len(p.params))
+	for i, param := range p.params {
+		specs[i] = fmt.Sprintf("%s=%s", param.Name, strings.Join(param.Values, ","))
+	}
+
+	return strings.Join(specs, " ")
+}
+
+// Serialize implements cli.Serializer so the params are copied properly.
+func (p *paramFlag) Serialize() string {
+	data, _ := json.Marshal(p.params)
+	return serializedParamsPrefix + string(data)
+}
+
+// runTasks executes the tasks with up to "jobs" of them at the same time.
+func runTasks(tasks []*sweep.Task, frameworks framework.List, jobs int) {
+	runParallel(len(tasks), jobs, func(i int) {
+		tasks[i].Execute(frameworks[tasks[i].Framework])
+	})
+}
+
+// runParallel calls run for each index from 0 to n-1 with up to "jobs" of them at the same time.
+func runParallel(n, jobs int, run func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, jobs)
+
+	for i := 0; i < n; i++ {
+		limit <- struct{}{}
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			run(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// formatValues returns the parameter values as "name=value" for output.
+func formatValues(params []sweep.Param, values []string) string {
+	list := make([]string, len(params))
+	for i, param := range params {
+		list[i] = fmt.Sprintf("%s=%s", param.Name, values[i])
+	}
+
+	return strings.Join(list, " ")
+}
@@ -0,0 +1,49 @@
+package sweep
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/asmaloney/gactar/util/runstats"
+)
+
+// WriteCSV writes the results as a tidy CSV file: one column for each parameter followed by the
+// framework and one row for each measure. Results where every run failed have a single row with
+// the measure "error".
+func WriteCSV(w io.Writer, params []Param, results []Result) (err error) {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, 0, len(params)+7)
+	for _, param := range params {
+		header = append(header, param.Name)
+	}
+	header = append(header, "framework", "measure", "runs", "failures", "mean", "sd", "min", "max")
+
+	writer.Write(header)
+
+	for _, result := range results {
+		stats := result.Stats
+
+		row := func(measure string, summary []string) []string {
+			row := append([]string{}, result.Values...)
+			row = append(row, result.Framework, measure, strconv.Itoa(stats.Runs), strconv.Itoa(stats.Failures))
+			return append(row, summary...)
+		}
+
+		if stats.Runs == 0 {
+			writer.Write(row("error", []string{"", "", "", ""}))
+			continue
+		}
+
+		for _, measure := range stats.Measures() {
+			writer.Write(row(measure.Name, []string{
+				runstats.FormatFloat(measure.Mean), runstats.FormatFloat(measure.SD),
+				runstats.FormatFloat(measure.Min), runstats.FormatFloat(measure.Max),
+			}))
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,108 @@
+package sweep
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/util/numbers"
+)
+
+// maxRangeValues is the most values a range may produce. This catches mistakes in the step.
+const maxRangeValues = 1000
+
+// Param is a module parameter and the values to try.
+type Param struct {
+	Name   string   `json:"name"`   // module & parameter name (e.g. "memory.latency_factor")
+	Values []string `json:"values"` // values as they would be written in an amod file
+}
+
+// ParseParam parses a parameter from the command line. It is in the form "name=values" where
+// values is either a list ("-1,-0.5,0") or an inclusive range with a step ("0.1:1.0:0.1").
+func ParseParam(spec string) (param Param, err error) {
+	name, values, found := strings.Cut(spec, "=")
+	if !found || name == "" || values == "" {
+		err = fmt.Errorf("parameter %q should be in the form 'module.param=values' (e.g. memory.latency_factor=0.1:1.0:0.1)", spec)
+		return
+	}
+
+	if !strings.Contains(name, ".") {
+		err = fmt.Errorf("parameter %q should be in the form 'module.param' (e.g. memory.latency_factor)", name)
+		return
+	}
+
+	param.Name = name
+
+	if strings.Contains(values, ":") {
+		param.Values, err = parseRange(values)
+		if err != nil {
+			err = fmt.Errorf("parameter %q: %w", name, err)
+		}
+		return
+	}
+
+	for _, value := range strings.Split(values, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			err = fmt.Errorf("parameter %q has an empty value", name)
+			return
+		}
+
+		param.Values = append(param.Values, value)
+	}
+
+	return
+}
+
+// parseRange parses "from:to:step".
+func parseRange(spec string) (values []string, err error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		err = fmt.Errorf("range %q should be in the form 'from:to:step'", spec)
+		return
+	}
+
+	limits := make([]float64, 3)
+	for i, part := range parts {
+		limits[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			err = fmt.Errorf("%q in range %q is not a number", part, spec)
+			return
+		}
+	}
+
+	return Range(limits[0], limits[1], limits[2])
+}
+
+// Range returns the values from "from" to "to" (inclusive) in increments of "step".
+func Range(from, to, step float64) (values []string, err error) {
+	if step <= 0 {
+		err = fmt.Errorf("step must be greater than 0")
+		return
+	}
+
+	if to < from {
+		err = fmt.Errorf("range must go from a lower value to a higher one")
+		return
+	}
+
+	// Allow for rounding errors so "0.1:0.3:0.1" includes 0.3.
+	count := int(math.Floor((to-from)/step+1e-9)) + 1
+	if count > maxRangeValues {
+		err = fmt.Errorf("range has too many values (%d) - the maximum is %d", count, maxRangeValues)
+		return
+	}
+
+	values = make([]string, count)
+	for i := range values {
+		value := from + float64(i)*step
+
+		// Remove rounding errors so we don't get values like 0.30000000000000004
+		value = math.Round(value*1e9) / 1e9
+
+		values[i] = numbers.Float64Str(value)
+	}
+
+	return
+}
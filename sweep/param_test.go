@@ -0,0 +1,51 @@
+package sweep
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParam(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected Param
+	}{
+		{"memory.latency_factor=0.1:0.5:0.1", Param{"memory.latency_factor", []string{"0.1", "0.2", "0.3", "0.4", "0.5"}}},
+		{"memory.retrieval_threshold=-1, -0.5,0", Param{"memory.retrieval_threshold", []string{"-1", "-0.5", "0"}}},
+		{"procedural.default_action_time=0.05", Param{"procedural.default_action_time", []string{"0.05"}}},
+		{"memory.latency_factor=0:1:0.3", Param{"memory.latency_factor", []string{"0", "0.3", "0.6", "0.9"}}},
+	}
+
+	for _, test := range tests {
+		param, err := ParseParam(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.spec, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(param, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.spec, test.expected, param)
+		}
+	}
+}
+
+func TestParseParamInvalid(t *testing.T) {
+	specs := []string{
+		"memory.latency_factor",
+		"latency_factor=0.1",
+		"memory.latency_factor=",
+		"memory.latency_factor=0.1,,0.2",
+		"memory.latency_factor=0.1:0.5",
+		"memory.latency_factor=0.5:0.1:0.1",
+		"memory.latency_factor=0.1:0.5:0",
+		"memory.latency_factor=a:b:c",
+		"memory.latency_factor=0:1000:0.001",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseParam(spec)
+		if err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package sweep runs a model using each combination of a set of module parameter values.
+//
+// A sweep is split into tasks - one for each run of each combination on each framework - so the
+// caller can decide how to schedule them (e.g. the command line limits them using "--jobs" and
+// the web server uses its run queue). The results are then collected into stats for each
+// combination & framework.
+package sweep
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/asmaloney/gactar/actr"
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/container"
+	"github.com/asmaloney/gactar/util/filesystem"
+	"github.com/asmaloney/gactar/util/runstats"
+)
+
+// MaxPoints is the most combinations of values a sweep may have.
+const MaxPoints = 10000
+
+// Sweep describes the model, the parameters to vary, and how to run it.
+type Sweep struct {
+	Source         string // text of the amod file
+	SourceDir      string // directory data files loaded by the amod are relative to (empty if it may not load any)
+	InitialBuffers framework.InitialBuffers
+	Params         []Param
+	Repeat         int     // number of times to run each combination on each framework
+	Seed           *uint32 // if set, overrides the model's random seed
+}
+
+// Point is one combination of parameter values.
+type Point struct {
+	Values []string    // one for each of the sweep's params (in the same order)
+	Model  *actr.Model // model with the values set
+}
+
+// NumPoints returns the number of combinations of the parameter values without generating any
+// models. It returns an error if there are no values to sweep or too many combinations.
+func (s Sweep) NumPoints() (count int, err error) {
+	if len(s.Params) == 0 {
+		err = errors.New("no parameters to sweep")
+		return
+	}
+
+	count = 1
+	for _, param := range s.Params {
+		if len(param.Values) == 0 {
+			err = fmt.Errorf("parameter %q has no values", param.Name)
+			return
+		}
+
+		count *= len(param.Values)
+		if count > MaxPoints {
+			err = fmt.Errorf("too many combinations of parameters - the maximum is %d", MaxPoints)
+			return
+		}
+	}
+
+	return
+}
+
+// NumTasks returns the number of tasks Tasks will create for the frameworks without generating
+// any models, so callers can check the size of a sweep before doing any work.
+func (s Sweep) NumTasks(numFrameworks int) (count int, err error) {
+	count, err = s.NumPoints()
+	if err != nil {
+		return
+	}
+
+	count *= numFrameworks * s.repeat()
+	return
+}
+
+// Points returns each combination of the parameter values with a model using them. The last
+// parameter varies fastest. It returns an error if the amod has errors or a value is not valid.
+func (s Sweep) Points() (points []Point, err error) {
+	count, err := s.NumPoints()
+	if err != nil {
+		return
+	}
+
+	indices := make([]int, len(s.Params))
+
+	for i := 0; i < count; i++ {
+		values := make([]string, len(s.Params))
+		for p, param := range s.Params {
+			values[p] = param.Values[indices[p]]
+		}
+
+		var model *actr.Model
+		model, err = s.model(values)
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, Point{Values: values, Model: model})
+
+		// next combination
+		for p := len(indices) - 1; p >= 0; p-- {
+			indices[p]++
+			if indices[p] < len(s.Params[p].Values) {
+				break
+			}
+			indices[p] = 0
+		}
+	}
+
+	return
+}
+
+// model generates the model from the source and sets the parameter values. Each point needs its
+// own model since setting parameters changes the model's modules.
+func (s Sweep) model(values []string) (model *actr.Model, err error) {
+	model, log, err := amod.GenerateModelInDir(s.Source, s.SourceDir)
+	if err != nil {
+		err = fmt.Errorf("%s", strings.TrimSpace(log.String()))
+		return
+	}
+
+	for p, param := range s.Params {
+		moduleName, key, _ := strings.Cut(param.Name, ".")
+
+		err = amod.SetParam(model, moduleName, key, values[p])
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// repeat returns the number of times to run each combination on each framework.
+func (s Sweep) repeat() int {
+	if s.Repeat < 1 {
+		return 1
+	}
+
+	return s.Repeat
+}
+
+// Task is one run of the model at a point using one framework.
+type Task struct {
+	Point     int    // index of the point
+	Framework string // name of the framework
+	Run       int    // run number (starting at 1)
+	Dir       string // directory to write the generated code to
+
+	Model          *actr.Model // model for the point (with the seed for this run)
+	InitialBuffers framework.InitialBuffers
+
+	Trace *framework.Trace // set by Execute if it succeeded
+	Err   error            // set by Execute if it failed
+}
+
+// Tasks creates a task for each run of each point on each framework. Each one writes its code to
+// its own directory under "dir" so they may be run at the same time.
+func (s Sweep) Tasks(points []Point, frameworkNames []string, dir string) (tasks []*Task) {
+	repeat := s.repeat()
+
+	for p, point := range points {
+		// Each point uses the same seeds so the differences are due to the parameters.
+		baseSeed := s.Seed
+		if baseSeed == nil {
+			baseSeed = point.Model.RandomSeed
+		}
+
+		for _, name := range frameworkNames {
+			for run := 1; run <= repeat; run++ {
+				task := &Task{
+					Point:     p,
+					Framework: name,
+					Run:       run,
+					Dir:       filepath.Join(dir, fmt.Sprintf("point-%d", p+1), fmt.Sprintf("%s-run-%d", name, run)),
+					Model:     point.Model,
+
+					InitialBuffers: s.InitialBuffers,
+				}
+
+				if baseSeed != nil {
+					task.Model = point.Model.WithRandomSeed(actr.DeriveSeed(*baseSeed, run))
+				}
+
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
+	return
+}
+
+// Execute runs the task using a new instance of the framework and sets its Trace or Err.
+func (t *Task) Execute(prototype framework.Framework) {
+	f := prototype.Clone(t.Dir)
+
+	log := f.ValidateModel(t.Model)
+	if log.HasError() {
+		t.Err = fmt.Errorf("%s", strings.TrimSpace(log.String()))
+		return
+	}
+
+	t.Err = filesystem.CreateDir(t.Dir)
+	if t.Err != nil {
+		return
+	}
+
+	t.Err = f.SetModel(t.Model)
+	if t.Err != nil {
+		return
+	}
+
+	result, err := f.Run(t.InitialBuffers)
+	if err != nil {
+		t.Err = err
+		return
+	}
+
+	t.Trace = result.Trace
+}
+
+// Result is the stats for one point on one framework.
+type Result struct {
+	Values    []string        `json:"values"` // one for each of the sweep's params (in the same order)
+	Framework string          `json:"framework"`
+	Stats     *runstats.Stats `json:"stats"`
+	Errors    []string        `json:"errors,omitempty"` // unique errors from the runs which failed
+}
+
+// Collect computes the stats for each point & framework from the tasks (in the order they were
+// created by Tasks).
+func Collect(points []Point, tasks []*Task) (results []Result) {
+	for start := 0; start < len(tasks); {
+		end := start + 1
+		for end < len(tasks) && tasks[end].Point == tasks[start].Point && tasks[end].Framework == tasks[start].Framework {
+			end++
+		}
+
+		result := Result{
+			Values:    points[tasks[start].Point].Values,
+			Framework: tasks[start].Framework,
+		}
+
+		traces := make([]*framework.Trace, 0, end-start)
+		for _, task := range tasks[start:end] {
+			traces = append(traces, task.Trace)
+
+			if task.Err != nil && !container.Contains(task.Err.Error(), result.Errors) {
+				result.Errors = append(result.Errors, task.Err.Error())
+			}
+		}
+
+		result.Stats = runstats.Compute(traces)
+
+		results = append(results, result)
+
+		start = end
+	}
+
+	return
+}
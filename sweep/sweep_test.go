@@ -0,0 +1,174 @@
+package sweep
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+const testSource = `
+==model==
+name: Test
+==config==
+gactar { random_seed: 42 }
+chunks { [count: first second] }
+==init==
+==productions==
+start {
+	match { goal [count: * *] }
+	do { clear goal }
+}`
+
+func testSweep() Sweep {
+	return Sweep{
+		Source: testSource,
+		Params: []Param{
+			{"memory.latency_factor", []string{"0.1", "0.2"}},
+			{"memory.retrieval_threshold", []string{"-1", "0", "1"}},
+		},
+		Repeat: 2,
+	}
+}
+
+func TestPoints(t *testing.T) {
+	points, err := testSweep().Points()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(points) != 6 {
+		t.Fatalf("expected 6 points, got %d", len(points))
+	}
+
+	if !reflect.DeepEqual(points[1].Values, []string{"0.1", "0"}) {
+		t.Errorf("expected the last parameter to vary fastest, got %v", points[1].Values)
+	}
+
+	last := points[5]
+	if *last.Model.Memory.LatencyFactor != 0.2 || *last.Model.Memory.RetrievalThreshold != 1 {
+		t.Errorf("parameters not set on model: %v %v", *last.Model.Memory.LatencyFactor, *last.Model.Memory.RetrievalThreshold)
+	}
+
+	if *points[0].Model.Memory.LatencyFactor != 0.1 {
+		t.Errorf("points should not share a model")
+	}
+}
+
+func TestPointsInvalid(t *testing.T) {
+	s := testSweep()
+	s.Params = []Param{{"memory.foo", []string{"1"}}}
+
+	_, err := s.Points()
+	if err == nil {
+		t.Errorf("expected error for unknown parameter")
+	}
+
+	s.Params = []Param{{"memory.latency_factor", []string{"-1"}}}
+
+	_, err = s.Points()
+	if err == nil {
+		t.Errorf("expected error for invalid value")
+	}
+
+	s.Source = "==model=="
+
+	_, err = s.Points()
+	if err == nil {
+		t.Errorf("expected error for invalid amod")
+	}
+}
+
+func TestNumTasks(t *testing.T) {
+	s := testSweep()
+	s.Source = "" // doesn't generate any models
+
+	count, err := s.NumTasks(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if count != 6*2*2 {
+		t.Errorf("expected 24 tasks, got %d", count)
+	}
+}
+
+func TestTasks(t *testing.T) {
+	s := testSweep()
+
+	points, err := s.Points()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tasks := s.Tasks(points, []string{"ccm", "pyactr"}, "/tmp")
+	if len(tasks) != 6*2*2 {
+		t.Fatalf("expected 24 tasks, got %d", len(tasks))
+	}
+
+	// Each point uses the same seeds.
+	if *tasks[0].Model.RandomSeed != 42 || *tasks[1].Model.RandomSeed == 42 {
+		t.Errorf("unexpected seeds: %d %d", *tasks[0].Model.RandomSeed, *tasks[1].Model.RandomSeed)
+	}
+
+	if *tasks[4].Model.RandomSeed != *tasks[0].Model.RandomSeed || *tasks[5].Model.RandomSeed != *tasks[1].Model.RandomSeed {
+		t.Errorf("expected each point to use the same seeds")
+	}
+
+	if tasks[0].Dir == tasks[1].Dir || tasks[0].Dir == tasks[2].Dir {
+		t.Errorf("expected each task to have its own directory")
+	}
+}
+
+func TestCollectAndWriteCSV(t *testing.T) {
+	s := testSweep()
+	s.Params = s.Params[:1]
+
+	points, err := s.Points()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tasks := s.Tasks(points, []string{"ccm"}, "/tmp")
+
+	trace := framework.NewTrace()
+	trace.ProductionFired("start", 0.05)
+
+	tasks[0].Trace = trace
+	tasks[1].Trace = trace
+	tasks[2].Err = errors.New("failed")
+	tasks[3].Err = errors.New("failed")
+
+	results := Collect(points, tasks)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	if results[0].Stats.Runs != 2 || results[1].Stats.Failures != 2 || !reflect.DeepEqual(results[1].Errors, []string{"failed"}) {
+		t.Errorf("unexpected results: %+v %+v", results[0], results[1])
+	}
+
+	out := &bytes.Buffer{}
+	err = WriteCSV(out, s.Params, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	expected := []string{
+		"memory.latency_factor,framework,measure,runs,failures,mean,sd,min,max",
+		"0.1,ccm,end_time,2,0,0.05,0,0.05,0.05",
+		"0.1,ccm,retrievals,2,0,0,0,0,0",
+		"0.1,ccm,retrieval_failures,2,0,0,0,0,0",
+		"0.1,ccm,fired:start,2,0,1,0,1,1",
+		"0.2,ccm,error,0,2,,,,",
+	}
+
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("unexpected CSV:\n%s", out.String())
+	}
+}
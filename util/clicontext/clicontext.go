@@ -9,18 +9,21 @@ import (
 )
 
 // ExpandPath expands the given path and sets it back in the context.
+// If we are in a command, the flag may belong to one of its parents so we set it where it is found.
 func ExpandPath(ctx *cli.Context, flag string) (path string, err error) {
 	path, err = filepath.Abs(ctx.Path(flag))
 	if err != nil {
 		return
 	}
 
-	err = ctx.Set(flag, path)
-	if err != nil {
-		return "", err
+	for _, c := range ctx.Lineage() {
+		err = c.Set(flag, path)
+		if err == nil {
+			return
+		}
 	}
 
-	return
+	return "", err
 }
 
 // CreateTempDir looks up the "temp" flag in our context, expands the path, and creates the dir.
@@ -0,0 +1,421 @@
+// Package lineedit provides a small line editor for interactive terminals with history
+// (optionally saved to a file) and tab completion.
+// If the input is not a terminal, lines are read as-is.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxHistory is the number of history entries kept in the history file.
+const DefaultMaxHistory = 1000
+
+// ErrInterrupted is returned by ReadLine when the user presses ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// CompleteFunc is called when the user presses tab. It is passed the line up to the cursor and
+// returns the (byte) index where the word being completed starts along with the possible
+// replacements for that word.
+type CompleteFunc func(line string) (start int, candidates []string)
+
+type Editor struct {
+	in     *os.File
+	out    io.Writer
+	reader *bufio.Reader
+
+	history     []string
+	historyFile string // if empty, history is not saved
+	maxHistory  int
+
+	Complete CompleteFunc
+}
+
+// New creates an editor reading from stdin and loads the history from historyFile (if set).
+func New(historyFile string) (e *Editor, err error) {
+	e = &Editor{
+		in:          os.Stdin,
+		out:         os.Stdout,
+		reader:      bufio.NewReader(os.Stdin),
+		historyFile: historyFile,
+		maxHistory:  DefaultMaxHistory,
+	}
+
+	err = e.loadHistory()
+
+	return
+}
+
+// IsTerminal returns true if the file is a terminal which we can edit lines on.
+func IsTerminal(file *os.File) bool {
+	return isTerminal(int(file.Fd()))
+}
+
+// History returns the list of lines entered (oldest first).
+func (e Editor) History() []string {
+	return e.history
+}
+
+// AddHistory adds a line to the history and appends it to the history file.
+// Lines which are the same as the previous one are not added.
+func (e *Editor) AddHistory(line string) (err error) {
+	if line == "" {
+		return
+	}
+
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+
+	if len(e.history) > e.maxHistory {
+		e.history = e.history[len(e.history)-e.maxHistory:]
+	}
+
+	if e.historyFile == "" {
+		return
+	}
+
+	file, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintln(file, line)
+	return
+}
+
+// loadHistory reads the history file. If it has grown past maxHistory entries, it is trimmed.
+func (e *Editor) loadHistory() (err error) {
+	if e.historyFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(e.historyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+
+	if len(e.history) > e.maxHistory {
+		e.history = e.history[len(e.history)-e.maxHistory:]
+
+		err = os.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+
+	return
+}
+
+// ReadLine outputs the prompt and reads a line. It returns io.EOF if the input is closed
+// (or ctrl-D is pressed on an empty line) and ErrInterrupted if ctrl-C is pressed.
+func (e *Editor) ReadLine(prompt string) (line string, err error) {
+	fd := int(e.in.Fd())
+
+	if !isTerminal(fd) {
+		return e.readPlain(prompt)
+	}
+
+	state, err := makeRaw(fd)
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore(fd, state)
+
+	return e.edit(prompt)
+}
+
+// readPlain is used when we cannot put the terminal in raw mode (or are not reading from one).
+func (e *Editor) readPlain(prompt string) (line string, err error) {
+	fmt.Fprint(e.out, prompt)
+
+	line, err = e.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+	return
+}
+
+func ctrl(r rune) rune {
+	return r & 0x1f
+}
+
+const (
+	keyBackspace = 127
+	keyEscape    = 27
+)
+
+// edit implements the editing itself. The terminal must already be in raw mode.
+func (e *Editor) edit(prompt string) (line string, err error) {
+	var buf []rune
+	pos := 0
+
+	historyIndex := len(e.history)
+	current := "" // saves the line being edited while moving through the history
+
+	moveHistory := func(delta int) {
+		index := historyIndex + delta
+		if index < 0 || index > len(e.history) {
+			return
+		}
+
+		if historyIndex == len(e.history) {
+			current = string(buf)
+		}
+
+		historyIndex = index
+
+		if index == len(e.history) {
+			buf = []rune(current)
+		} else {
+			buf = []rune(e.history[index])
+		}
+
+		pos = len(buf)
+	}
+
+	refresh := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+
+	fmt.Fprint(e.out, prompt)
+
+	lastWasTab := false
+
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		isTab := false
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+
+		case ctrl('C'):
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+
+		case ctrl('D'):
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+
+		case keyBackspace, ctrl('H'):
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+
+		case ctrl('A'):
+			pos = 0
+
+		case ctrl('E'):
+			pos = len(buf)
+
+		case ctrl('B'):
+			if pos > 0 {
+				pos--
+			}
+
+		case ctrl('F'):
+			if pos < len(buf) {
+				pos++
+			}
+
+		case ctrl('K'):
+			buf = buf[:pos]
+
+		case ctrl('U'):
+			buf = buf[pos:]
+			pos = 0
+
+		case ctrl('W'):
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+
+		case ctrl('L'):
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+
+		case ctrl('P'):
+			moveHistory(-1)
+
+		case ctrl('N'):
+			moveHistory(1)
+
+		case '\t':
+			isTab = true
+			buf, pos = e.complete(buf, pos, lastWasTab)
+
+		case keyEscape:
+			switch e.readEscape() {
+			case 'A':
+				moveHistory(-1)
+			case 'B':
+				moveHistory(1)
+			case 'C':
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			case 'X': // delete
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+
+		default:
+			if unicode.IsPrint(r) {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+			}
+		}
+
+		lastWasTab = isTab
+
+		refresh()
+	}
+}
+
+// readEscape reads the rest of an escape sequence and returns a simplified key:
+// 'A' (up), 'B' (down), 'C' (right), 'D' (left), 'H' (home), 'F' (end), 'X' (delete),
+// or 0 if we don't handle it.
+func (e *Editor) readEscape() rune {
+	r, _, err := e.reader.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+
+	r, _, err = e.reader.ReadRune()
+	if err != nil {
+		return 0
+	}
+
+	if r < '0' || r > '9' {
+		return r
+	}
+
+	// sequences such as ESC [ 3 ~
+	num := r
+	for {
+		next, _, err := e.reader.ReadRune()
+		if err != nil || next == '~' {
+			break
+		}
+	}
+
+	switch num {
+	case '1', '7':
+		return 'H'
+	case '4', '8':
+		return 'F'
+	case '3':
+		return 'X'
+	}
+
+	return 0
+}
+
+// complete asks the CompleteFunc for candidates and replaces the word before the cursor.
+// If there is more than one candidate, a second tab lists them.
+func (e *Editor) complete(buf []rune, pos int, listCandidates bool) ([]rune, int) {
+	if e.Complete == nil {
+		return buf, pos
+	}
+
+	line := string(buf[:pos])
+
+	start, candidates := e.Complete(line)
+	if len(candidates) == 0 || start < 0 || start > len(line) {
+		fmt.Fprint(e.out, "\a")
+		return buf, pos
+	}
+
+	wordStart := utf8.RuneCountInString(line[:start])
+	word := string(buf[wordStart:pos])
+
+	replacement := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(replacement, "/") {
+		replacement += " "
+	}
+
+	if len(replacement) > len(word) {
+		newBuf := append([]rune{}, buf[:wordStart]...)
+		newBuf = append(newBuf, []rune(replacement)...)
+		newPos := len(newBuf)
+		newBuf = append(newBuf, buf[pos:]...)
+
+		return newBuf, newPos
+	}
+
+	if listCandidates {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	} else {
+		fmt.Fprint(e.out, "\a")
+	}
+
+	return buf, pos
+}
+
+// commonPrefix returns the longest prefix shared by all the strings in list.
+func commonPrefix(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+
+	prefix := list[0]
+
+	for _, str := range list[1:] {
+		for !strings.HasPrefix(str, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+
+	return prefix
+}
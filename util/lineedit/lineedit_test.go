@@ -0,0 +1,156 @@
+package lineedit
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string) *Editor {
+	return &Editor{
+		out:        io.Discard,
+		reader:     bufio.NewReader(strings.NewReader(input)),
+		maxHistory: DefaultMaxHistory,
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "load foo\r", "load foo"},
+		{"backspace", "loaf\x7fd\r", "load"},
+		{"left & insert", "lad\x1b[D\x1b[Do\r", "load"},
+		{"home & end", "oad\x01l\x05 x\r", "load x"},
+		{"kill to end", "load foo\x01\x06\x06\x06\x06\x0b\r", "load"},
+		{"kill to start", "xyz load\x1b[D\x1b[D\x1b[D\x1b[D\x15\x05\r", "load"},
+		{"delete word", "load foo bar\x17\x17\r", "load "},
+		{"delete key", "lxoad\x01\x1b[C\x1b[3~\r", "load"},
+		{"unicode", "run é\x7fe\r", "run e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := newTestEditor(tt.input).edit("> ")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			if line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestEditControlKeys(t *testing.T) {
+	_, err := newTestEditor("abc\x03").edit("> ")
+	if err != ErrInterrupted {
+		t.Errorf("Expected ErrInterrupted, got %v", err)
+	}
+
+	_, err = newTestEditor("\x04").edit("> ")
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+
+	// ctrl-D deletes when the line is not empty
+	line, err := newTestEditor("abc\x01\x04\r").edit("> ")
+	if err != nil || line != "bc" {
+		t.Errorf("Expected \"bc\", got %q (%v)", line, err)
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	e := newTestEditor("\x1b[A\x1b[A\r" + "par\x1b[A\x1b[B\x1b[B\r")
+	e.history = []string{"first", "second"}
+
+	line, _ := e.edit("> ")
+	if line != "first" {
+		t.Errorf("Expected \"first\", got %q", line)
+	}
+
+	// moving back down restores what was being typed
+	line, _ = e.edit("> ")
+	if line != "par" {
+		t.Errorf("Expected \"par\", got %q", line)
+	}
+}
+
+func TestEditComplete(t *testing.T) {
+	complete := func(line string) (start int, candidates []string) {
+		start = strings.LastIndex(line, " ") + 1
+		word := line[start:]
+
+		for _, c := range []string{"frameworks", "history", "help"} {
+			if strings.HasPrefix(c, word) {
+				candidates = append(candidates, c)
+			}
+		}
+		return
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fr\t\r", "frameworks "},
+		{"h\t\r", "h"},
+		{"he\t\r", "help "},
+		{"x\t\r", "x"},
+	}
+
+	for _, tt := range tests {
+		e := newTestEditor(tt.input)
+		e.Complete = complete
+
+		line, _ := e.edit("> ")
+		if line != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, line)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "history")
+
+	e := &Editor{historyFile: fileName, maxHistory: 3}
+
+	for _, line := range []string{"one", "two", "two", "three", "four"} {
+		err := e.AddHistory(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "one\ntwo\nthree\nfour\n" {
+		t.Errorf("Unexpected history file contents: %q", string(data))
+	}
+
+	// loading trims the file to the maximum
+	e = &Editor{historyFile: fileName, maxHistory: 3}
+
+	err = e.loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(e.History(), ",") != "two,three,four" {
+		t.Errorf("Unexpected history: %v", e.History())
+	}
+
+	data, _ = os.ReadFile(fileName)
+	if string(data) != "two\nthree\nfour\n" {
+		t.Errorf("History file was not trimmed: %q", string(data))
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package lineedit
+
+import "errors"
+
+// Line editing is not supported on this platform, so we always read plain lines.
+
+type termState struct{}
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (state *termState, err error) {
+	return nil, errors.New("line editing not supported")
+}
+
+func restore(fd int, state *termState) error {
+	return nil
+}
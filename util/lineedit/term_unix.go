@@ -0,0 +1,65 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package lineedit
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type termState struct {
+	termios syscall.Termios
+}
+
+func getTermios(fd int) (termios *syscall.Termios, err error) {
+	termios = &syscall.Termios{}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return
+}
+
+func setTermios(fd int, termios *syscall.Termios) (err error) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+
+	return
+}
+
+func isTerminal(fd int) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// makeRaw turns off echo & line buffering so we can handle each key press.
+// Output processing is left on so newlines written elsewhere still work.
+func makeRaw(fd int) (state *termState, err error) {
+	termios, err := getTermios(fd)
+	if err != nil {
+		return
+	}
+
+	state = &termState{termios: *termios}
+
+	termios.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INLCR | syscall.ISTRIP | syscall.IXON
+	termios.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	termios.Cflag |= syscall.CS8
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+
+	err = setTermios(fd, termios)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func restore(fd int, state *termState) error {
+	return setTermios(fd, &state.termios)
+}
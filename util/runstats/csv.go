@@ -0,0 +1,133 @@
+package runstats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+// Key identifies a model run on a framework.
+type Key struct {
+	File      string // amod file
+	Model     string // model name
+	Framework string
+}
+
+// Run is the raw data from one run.
+type Run struct {
+	Key
+	Run   int              // run number (starting at 1)
+	Seed  *uint32          // random seed (nil if it wasn't seeded)
+	Trace *framework.Trace // nil if the run failed
+}
+
+// Result is the stats for one model on one framework.
+type Result struct {
+	Key
+	Stats *Stats
+}
+
+// WriteRunsCSV writes one row for each run. Production firings are written as "name=count"
+// separated by ";".
+func WriteRunsCSV(w io.Writer, runs []Run) (err error) {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"file", "model", "framework", "run", "seed", "status", "end_time", "retrievals", "retrieval_failures", "productions", "printed"})
+
+	for _, run := range runs {
+		seed := ""
+		if run.Seed != nil {
+			seed = strconv.FormatUint(uint64(*run.Seed), 10)
+		}
+
+		row := []string{run.File, run.Model, run.Framework, strconv.Itoa(run.Run), seed}
+
+		if run.Trace == nil {
+			row = append(row, "error", "", "", "", "", "")
+		} else {
+			row = append(row,
+				"ok",
+				FormatFloat(run.Trace.EndTime),
+				strconv.Itoa(run.Trace.Retrievals),
+				strconv.Itoa(run.Trace.RetrievalFailures),
+				formatProductions(run.Trace.Productions),
+				strings.Join(run.Trace.Printed, "\n"),
+			)
+		}
+
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteStatsCSV writes one row for each measure of each result. Production firings use the
+// measure "fired:<production name>".
+func WriteStatsCSV(w io.Writer, results []Result) (err error) {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"file", "model", "framework", "measure", "runs", "failures", "mean", "sd", "min", "max"})
+
+	for _, result := range results {
+		stats := result.Stats
+		if stats.Runs == 0 {
+			continue
+		}
+
+		for _, measure := range stats.Measures() {
+			writer.Write([]string{
+				result.File, result.Model, result.Framework, measure.Name,
+				strconv.Itoa(stats.Runs), strconv.Itoa(stats.Failures),
+				FormatFloat(measure.Mean), FormatFloat(measure.SD), FormatFloat(measure.Min), FormatFloat(measure.Max),
+			})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteOutputsCSV writes the distribution of the printed output for each result.
+func WriteOutputsCSV(w io.Writer, results []Result) (err error) {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"file", "model", "framework", "printed", "count", "proportion"})
+
+	for _, result := range results {
+		for _, output := range result.Stats.Outputs {
+			writer.Write([]string{
+				result.File, result.Model, result.Framework,
+				output.Printed, strconv.Itoa(output.Count), FormatFloat(output.Proportion),
+			})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// FormatFloat formats a number for a CSV file using as few digits as possible.
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func formatProductions(productions map[string]int) string {
+	names := make([]string, 0, len(productions))
+	for name := range productions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fired := make([]string, len(names))
+	for i, name := range names {
+		fired[i] = fmt.Sprintf("%s=%d", name, productions[name])
+	}
+
+	return strings.Join(fired, ";")
+}
@@ -0,0 +1,199 @@
+// Package runstats aggregates the traces from repeated runs of a model.
+package runstats
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+// Summary describes the distribution of a value over the runs.
+type Summary struct {
+	Mean float64 `json:"mean"`
+	SD   float64 `json:"sd"` // sample standard deviation (0 if there is only one run)
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// OutputCount is the number of runs which printed the same thing.
+type OutputCount struct {
+	Printed    string  `json:"printed"`    // lines printed during the run (joined with newlines)
+	Count      int     `json:"count"`      // number of runs
+	Proportion float64 `json:"proportion"` // proportion of successful runs
+}
+
+// Stats aggregates the traces from repeated runs of a model on one framework.
+type Stats struct {
+	Runs     int `json:"runs"`     // number of successful runs
+	Failures int `json:"failures"` // number of failed runs (these aren't included in the stats)
+
+	EndTime           Summary `json:"endTime"`           // simulated end time (seconds)
+	Retrievals        Summary `json:"retrievals"`        // successful retrievals per run
+	RetrievalFailures Summary `json:"retrievalFailures"` // failed retrievals per run
+
+	// RetrievalSuccessRate only includes runs with at least one retrieval.
+	RetrievalSuccessRate *Summary `json:"retrievalSuccessRate,omitempty"`
+
+	Productions map[string]Summary `json:"productions,omitempty"` // number of times each production fired per run
+
+	Outputs []OutputCount `json:"outputs,omitempty"` // distribution of printed output (most common first)
+}
+
+// Measure is one of the summaries in the stats with a name to use when outputting it.
+type Measure struct {
+	Name string
+	Summary
+}
+
+// Measures returns the summaries in a fixed order. Production firings use the name
+// "fired:<production name>".
+func (s Stats) Measures() (measures []Measure) {
+	if s.Runs == 0 {
+		return
+	}
+
+	measures = []Measure{
+		{"end_time", s.EndTime},
+		{"retrievals", s.Retrievals},
+		{"retrieval_failures", s.RetrievalFailures},
+	}
+
+	if s.RetrievalSuccessRate != nil {
+		measures = append(measures, Measure{"retrieval_success_rate", *s.RetrievalSuccessRate})
+	}
+
+	names := make([]string, 0, len(s.Productions))
+	for name := range s.Productions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		measures = append(measures, Measure{"fired:" + name, s.Productions[name]})
+	}
+
+	return
+}
+
+// Compute aggregates the traces. Failed runs are nil.
+func Compute(traces []*framework.Trace) (stats *Stats) {
+	stats = &Stats{}
+
+	successful := []*framework.Trace{}
+	for _, trace := range traces {
+		if trace == nil {
+			stats.Failures++
+			continue
+		}
+
+		successful = append(successful, trace)
+	}
+
+	stats.Runs = len(successful)
+	if stats.Runs == 0 {
+		return
+	}
+
+	stats.EndTime = summarize(successful, func(t *framework.Trace) float64 { return t.EndTime })
+	stats.Retrievals = summarize(successful, func(t *framework.Trace) float64 { return float64(t.Retrievals) })
+	stats.RetrievalFailures = summarize(successful, func(t *framework.Trace) float64 { return float64(t.RetrievalFailures) })
+
+	rates := []float64{}
+	for _, trace := range successful {
+		if total := trace.Retrievals + trace.RetrievalFailures; total > 0 {
+			rates = append(rates, float64(trace.Retrievals)/float64(total))
+		}
+	}
+
+	if len(rates) > 0 {
+		rate := summarizeValues(rates)
+		stats.RetrievalSuccessRate = &rate
+	}
+
+	// A production which didn't fire in a run counts as 0 for that run.
+	names := map[string]bool{}
+	for _, trace := range successful {
+		for name := range trace.Productions {
+			names[name] = true
+		}
+	}
+
+	if len(names) > 0 {
+		stats.Productions = make(map[string]Summary, len(names))
+
+		for name := range names {
+			stats.Productions[name] = summarize(successful, func(t *framework.Trace) float64 { return float64(t.Productions[name]) })
+		}
+	}
+
+	stats.Outputs = countOutputs(successful)
+
+	return
+}
+
+func summarize(traces []*framework.Trace, value func(*framework.Trace) float64) Summary {
+	values := make([]float64, len(traces))
+	for i, trace := range traces {
+		values[i] = value(trace)
+	}
+
+	return summarizeValues(values)
+}
+
+func summarizeValues(values []float64) (s Summary) {
+	s.Min = math.Inf(1)
+	s.Max = math.Inf(-1)
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+	}
+
+	s.Mean = sum / float64(len(values))
+
+	if len(values) > 1 {
+		sumSquares := 0.0
+		for _, v := range values {
+			sumSquares += (v - s.Mean) * (v - s.Mean)
+		}
+
+		s.SD = math.Sqrt(sumSquares / float64(len(values)-1))
+	}
+
+	return
+}
+
+// countOutputs returns how many runs printed each distinct output (most common first).
+func countOutputs(traces []*framework.Trace) (outputs []OutputCount) {
+	counts := map[string]int{}
+	for _, trace := range traces {
+		counts[strings.Join(trace.Printed, "\n")]++
+	}
+
+	// Nothing was printed.
+	if len(counts) == 1 && counts[""] > 0 {
+		return nil
+	}
+
+	for printed, count := range counts {
+		outputs = append(outputs, OutputCount{
+			Printed:    printed,
+			Count:      count,
+			Proportion: float64(count) / float64(len(traces)),
+		})
+	}
+
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].Count != outputs[j].Count {
+			return outputs[i].Count > outputs[j].Count
+		}
+
+		return outputs[i].Printed < outputs[j].Printed
+	})
+
+	return
+}
@@ -0,0 +1,118 @@
+package runstats
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/framework"
+)
+
+func testTraces() []*framework.Trace {
+	return []*framework.Trace{
+		{
+			Printed:     []string{"2", "3"},
+			Productions: map[string]int{"start": 1, "increment": 2},
+			Retrievals:  2,
+			EndTime:     0.3,
+		},
+		nil, // failed
+		{
+			Printed:           []string{"2"},
+			Productions:       map[string]int{"start": 1},
+			Retrievals:        1,
+			RetrievalFailures: 1,
+			EndTime:           0.5,
+		},
+		{
+			Printed:     []string{"2", "3"},
+			Productions: map[string]int{"start": 1, "increment": 2},
+			Retrievals:  2,
+			EndTime:     0.4,
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	stats := Compute(testTraces())
+
+	if stats.Runs != 3 || stats.Failures != 1 {
+		t.Fatalf("expected 3 runs & 1 failure, got %d & %d", stats.Runs, stats.Failures)
+	}
+
+	if !closeTo(stats.EndTime.Mean, 0.4) || !closeTo(stats.EndTime.SD, 0.1) {
+		t.Errorf("incorrect end time: %+v", stats.EndTime)
+	}
+
+	if stats.EndTime.Min != 0.3 || stats.EndTime.Max != 0.5 {
+		t.Errorf("incorrect end time range: %+v", stats.EndTime)
+	}
+
+	if stats.RetrievalSuccessRate == nil || !closeTo(stats.RetrievalSuccessRate.Mean, 2.5/3) {
+		t.Errorf("incorrect retrieval success rate: %+v", stats.RetrievalSuccessRate)
+	}
+
+	// "increment" didn't fire in one run, so that counts as 0
+	increment := stats.Productions["increment"]
+	if !closeTo(increment.Mean, 4.0/3) || increment.Min != 0 || increment.Max != 2 {
+		t.Errorf("incorrect production count: %+v", increment)
+	}
+
+	if len(stats.Outputs) != 2 {
+		t.Fatalf("expected 2 distinct outputs, got %d", len(stats.Outputs))
+	}
+
+	if stats.Outputs[0].Printed != "2\n3" || stats.Outputs[0].Count != 2 || !closeTo(stats.Outputs[0].Proportion, 2.0/3) {
+		t.Errorf("incorrect most common output: %+v", stats.Outputs[0])
+	}
+}
+
+func TestComputeAllFailed(t *testing.T) {
+	stats := Compute([]*framework.Trace{nil, nil})
+
+	if stats.Runs != 0 || stats.Failures != 2 {
+		t.Errorf("expected 0 runs & 2 failures, got %d & %d", stats.Runs, stats.Failures)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	key := Key{File: "count.amod", Model: "count", Framework: "ccm"}
+
+	seed := uint32(42)
+
+	runs := []Run{}
+	for i, trace := range testTraces() {
+		runs = append(runs, Run{Key: key, Run: i + 1, Trace: trace})
+	}
+	runs[0].Seed = &seed
+
+	out := &bytes.Buffer{}
+	err := WriteRunsCSV(out, runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if lines[1] != `count.amod,count,ccm,1,42,ok,0.3,2,0,increment=2;start=1,"2` {
+		t.Errorf("incorrect first run: %q", lines[1])
+	}
+
+	if lines[3] != "count.amod,count,ccm,2,,error,,,,," {
+		t.Errorf("incorrect failed run: %q", lines[3])
+	}
+
+	out.Reset()
+	err = WriteStatsCSV(out, []Result{{Key: key, Stats: Compute(testTraces())}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "count.amod,count,ccm,fired:start,3,1,1,0,1,1\n") {
+		t.Errorf("missing production stats in:\n%s", out.String())
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
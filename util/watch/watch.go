@@ -0,0 +1,114 @@
+// Package watch polls files for changes.
+// (This polls rather than using OS notifications so it works the same everywhere and handles
+// editors which save by replacing the file.)
+package watch
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultInterval is how often files are checked.
+const DefaultInterval = 500 * time.Millisecond
+
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+type Watcher struct {
+	files    []string
+	interval time.Duration
+	states   map[string]fileState
+}
+
+// New creates a watcher for the files and records their current state.
+func New(files []string, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	w := &Watcher{
+		files:    files,
+		interval: interval,
+		states:   map[string]fileState{},
+	}
+
+	for _, file := range files {
+		w.states[file] = stat(file)
+	}
+
+	return w
+}
+
+// Wait blocks until at least one file has changed and returns the list of changed files.
+// Files which are removed are not reported until they exist again (editors often save by
+// removing & recreating a file). It returns early with ctx.Err() if the context is done.
+func (w *Watcher) Wait(ctx context.Context) (changed []string, err error) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-ticker.C:
+		}
+
+		if !w.hasChanges() {
+			continue
+		}
+
+		// wait for the writes to finish before reporting
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(w.interval):
+		}
+
+		for _, file := range w.files {
+			current := stat(file)
+			if !current.exists {
+				continue
+			}
+
+			if current != w.states[file] {
+				changed = append(changed, file)
+			}
+
+			w.states[file] = current
+		}
+
+		if len(changed) > 0 {
+			return
+		}
+	}
+}
+
+func (w *Watcher) hasChanges() bool {
+	for _, file := range w.files {
+		current := stat(file)
+		if current.exists && current != w.states[file] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stat(file string) fileState {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{
+		exists:  true,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
@@ -0,0 +1,54 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	dir := t.TempDir()
+
+	file1 := filepath.Join(dir, "one.amod")
+	file2 := filepath.Join(dir, "two.amod")
+
+	for _, file := range []string{file1, file2} {
+		err := os.WriteFile(file, []byte("==model=="), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New([]string{file1, file2}, 10*time.Millisecond)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		os.WriteFile(file2, []byte("==model== changed"), 0644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changed, err := w.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changed) != 1 || changed[0] != file2 {
+		t.Errorf("Expected %q to change, got %v", file2, changed)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	w := New([]string{filepath.Join(t.TempDir(), "missing.amod")}, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := w.Wait(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
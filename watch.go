@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/asmaloney/gactar/util/container"
+	"github.com/asmaloney/gactar/util/lineedit"
+	"github.com/asmaloney/gactar/util/watch"
+)
+
+// watch regenerates the code (and reruns the models if "run" is set) whenever one of the amod
+// files or the data files they load changes. Nothing is regenerated until all the files compile.
+func (b *batch) watch(files []string, compiled []compiledFile) (err error) {
+	// If a file doesn't compile, we keep watching the data files from the last time it did.
+	dataFiles := map[string][]string{}
+
+	for {
+		for _, c := range compiled {
+			if c.model != nil {
+				dataFiles[c.file] = c.model.SourceFiles
+			}
+		}
+
+		watched := watchedFiles(files, dataFiles)
+		watcher := watch.New(watched, watch.DefaultInterval)
+
+		fmt.Fprintf(b.progress, "\nWatching %s for changes (press ctrl-C to stop)...\n", strings.Join(watched, ", "))
+
+		changed, err := watcher.Wait(context.Background())
+		if err != nil {
+			return err
+		}
+
+		clearOutput(b.progress)
+
+		fmt.Fprintf(b.progress, "[%s] changed: %s\n", time.Now().Format("15:04:05"), strings.Join(changed, ", "))
+
+		// Each time is a fresh start.
+		b.failure = nil
+
+		compiled, _ = b.compile(files)
+		if b.failure != nil {
+			b.results.writeCompileErrors(compiled)
+			fmt.Fprintln(b.progress, "Not generating code until all files compile")
+			continue
+		}
+
+		b.generate(compiled)
+	}
+}
+
+// watchedFiles returns the amod files followed by the data files each one loads (without duplicates).
+func watchedFiles(files []string, dataFiles map[string][]string) (watched []string) {
+	watched = append(watched, files...)
+
+	for _, file := range files {
+		for _, dataFile := range dataFiles[file] {
+			if !container.Contains(dataFile, watched) {
+				watched = append(watched, dataFile)
+			}
+		}
+	}
+
+	return
+}
+
+// clearOutput clears the terminal so the previous output is not confused with the new output.
+// If progress isn't a terminal, we output a separator instead.
+func clearOutput(progress io.Writer) {
+	if file, ok := progress.(*os.File); ok && lineedit.IsTerminal(file) {
+		fmt.Fprint(progress, "\x1b[H\x1b[2J")
+		return
+	}
+
+	fmt.Fprintln(progress, strings.Repeat("-", 80))
+}
@@ -0,0 +1,290 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/asmaloney/gactar/framework"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/version"
+)
+
+type archiveRequest struct {
+	SessionID  int                      `json:"sessionID"`
+	ModelID    int                      `json:"modelID"`
+	Buffers    framework.InitialBuffers `json:"buffers,omitempty"`    // set the initial buffers
+	Frameworks []string                 `json:"frameworks,omitempty"` // list of frameworks to include (if empty, "all")
+	Run        bool                     `json:"run"`                  // run the model and include the output
+}
+
+// archiveManifest is written to the archive as manifest.json so the contents may be reproduced.
+type archiveManifest struct {
+	GactarVersion  string                              `json:"gactarVersion"`
+	Created        string                              `json:"created"`
+	ModelName      string                              `json:"modelName"`
+	AMODFile       string                              `json:"amodFile"`
+	InitialBuffers framework.InitialBuffers            `json:"initialBuffers,omitempty"`
+	Frameworks     map[string]archiveFrameworkManifest `json:"frameworks"`
+}
+
+type archiveFrameworkManifest struct {
+	Info   framework.Info    `json:"info"`
+	Files  []string          `json:"files,omitempty"`  // files for this framework (relative to the archive root using '/')
+	Issues *issues.IssueList `json:"issues,omitempty"` // issues specific to this framework
+	Output string            `json:"output,omitempty"` // file containing the output if the model was run
+	Failed bool              `json:"failed,omitempty"` // true if the code could not be generated or run
+}
+
+func initArchive(w *Web) {
+	w.handleFunc("/api/model/archive", w.archiveModelHandler)
+}
+
+// archiveModelHandler returns a zip file containing the amod source, the code generated for
+// each framework, any support files needed to run it, and (optionally) the output of running it.
+func (w *Web) archiveModelHandler(rw http.ResponseWriter, req *http.Request) {
+	var data archiveRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	user := requestUser(req)
+
+	session := w.lookupSession(user, data.SessionID)
+	if session == nil {
+		err := fmt.Errorf("invalid session id '%d'", data.SessionID)
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	model := session.lookupModel(data.ModelID)
+	if model == nil {
+		err := fmt.Errorf("invalid model id '%d'", data.ModelID)
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	data.Frameworks = w.normalizeFrameworkList(data.Frameworks)
+
+	err = w.verifyFrameworkList(data.Frameworks)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	archive, err := w.createArchive(user, model, data.Buffers, data.Frameworks, data.Run)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/zip")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", model.actrModel.Name+".zip"))
+	rw.Write(archive)
+}
+
+// createArchive generates the code for each framework in a new directory and zips it up.
+func (w Web) createArchive(user string, model *Model, initialBuffers framework.InitialBuffers, frameworkNames []string, run bool) (archive []byte, err error) {
+	userPath, err := w.createUserTempDir(user)
+	if err != nil {
+		return
+	}
+
+	dir, err := os.MkdirTemp(userPath, "archive-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	modelName := model.actrModel.Name
+
+	manifest := archiveManifest{
+		GactarVersion:  version.BuildVersion,
+		Created:        time.Now().Format(time.RFC3339),
+		ModelName:      modelName,
+		AMODFile:       modelName + ".amod",
+		InitialBuffers: initialBuffers,
+		Frameworks:     map[string]archiveFrameworkManifest{},
+	}
+
+	err = os.WriteFile(filepath.Join(dir, manifest.AMODFile), []byte(model.amodFile), 0660)
+	if err != nil {
+		return
+	}
+
+	for _, name := range frameworkNames {
+		err = os.Mkdir(filepath.Join(dir, name), 0750)
+		if err != nil {
+			return
+		}
+	}
+
+	var tickets []*queueTicket
+	if run {
+		tickets, err = w.queue.enqueue(len(frameworkNames), "")
+		if err != nil {
+			return
+		}
+	}
+
+	for i, name := range frameworkNames {
+		f := w.actrFrameworks[name].Clone(filepath.Join(dir, name))
+
+		var ticket *queueTicket
+		if run {
+			ticket = tickets[i]
+		}
+
+		manifest.Frameworks[name] = w.writeFrameworkFiles(f, model, initialBuffers, ticket, dir)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "manifest.json"), manifestJSON, 0660)
+	if err != nil {
+		return
+	}
+
+	return zipDirectory(dir)
+}
+
+// writeFrameworkFiles writes the generated code & support files for one framework and runs it if we
+// have a queue ticket.
+func (w Web) writeFrameworkFiles(f framework.Framework, model *Model, initialBuffers framework.InitialBuffers, ticket *queueTicket, root string) (entry archiveFrameworkManifest) {
+	if ticket != nil {
+		ticket.wait()
+		defer w.queue.done()
+	}
+
+	entry.Info = *f.Info()
+
+	frameworkPath := filepath.Join(root, entry.Info.Name)
+
+	log := f.ValidateModel(model.actrModel)
+	defer func() {
+		if log.HasIssues() {
+			all := log.AllIssues()
+			entry.Issues = &all
+		}
+	}()
+
+	if log.HasError() {
+		entry.Failed = true
+		return
+	}
+
+	err := f.SetModel(model.actrModel)
+	if err != nil {
+		log.Error(nil, err.Error())
+		entry.Failed = true
+		return
+	}
+
+	if ticket != nil {
+		// Run() writes the model itself
+		start := time.Now()
+
+		var result *framework.RunResult
+		result, err = f.Run(initialBuffers)
+
+		w.metrics.recordRun(entry.Info.Name, time.Since(start), err != nil)
+
+		output := []byte{}
+		if result != nil {
+			output = result.Output
+		}
+
+		if err != nil {
+			log.Error(nil, "run failed")
+			entry.Failed = true
+			output = []byte(err.Error())
+		}
+
+		entry.Output = entry.Info.Name + "/output.txt"
+
+		writeErr := os.WriteFile(filepath.Join(frameworkPath, "output.txt"), output, 0660)
+		if writeErr != nil {
+			log.Error(nil, writeErr.Error())
+		}
+	} else {
+		_, err = f.WriteModel(frameworkPath, initialBuffers)
+	}
+
+	if err == nil {
+		_, err = f.WriteSupportFiles(frameworkPath)
+	}
+
+	if err != nil && !entry.Failed {
+		log.Error(nil, err.Error())
+		entry.Failed = true
+	}
+
+	files, _ := os.ReadDir(frameworkPath)
+	for _, file := range files {
+		entry.Files = append(entry.Files, entry.Info.Name+"/"+file.Name())
+	}
+
+	return
+}
+
+// zipDirectory returns a zip archive of all the files in dir using paths relative to dir.
+func zipDirectory(dir string) (archive []byte, err error) {
+	buffer := new(bytes.Buffer)
+	writer := zip.NewWriter(buffer)
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(relPath)
+		header.Method = zip.Deflate
+
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(entry, file)
+		return err
+	})
+	if err != nil {
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+
+	archive = buffer.Bytes()
+	return
+}
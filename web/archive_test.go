@@ -0,0 +1,167 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/framework/vanilla_actr"
+)
+
+func TestZipDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"Test.amod":             "==model==",
+		"vanilla/Test.lisp":     "(clear-all)",
+		"vanilla/Test_run.lisp": "(load \"Test.lisp\")",
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		err := os.MkdirAll(filepath.Dir(path), 0750)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(path, []byte(contents), 0660)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, err := zipDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reader.File) != len(files) {
+		t.Errorf("Expected %d files in archive, got %d", len(files), len(reader.File))
+	}
+
+	for _, file := range reader.File {
+		expected, ok := files[file.Name]
+		if !ok {
+			t.Errorf("Unexpected file in archive: '%s'", file.Name)
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(contents) != expected {
+			t.Errorf("Unexpected contents for '%s': %q", file.Name, string(contents))
+		}
+	}
+}
+
+// TestArchiveRunFileIsPortable checks that the vanilla run file in an archive doesn't refer to the
+// server's environment or to the temporary directory the archive was created in.
+func TestArchiveRunFileIsPortable(t *testing.T) {
+	tmpPath := t.TempDir()
+	envPath := filepath.Join(t.TempDir(), "env")
+	t.Setenv("VIRTUAL_ENV", envPath)
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("temp", tmpPath, "")
+	ctx := cli.NewContext(cli.NewApp(), flags, nil)
+
+	vanilla, err := vanilla_actr.New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := Web{
+		context:        ctx,
+		actrFrameworks: framework.List{"vanilla": vanilla},
+		queue:          newRunQueue(1, 0),
+		metrics:        newMetrics(),
+	}
+
+	amodFile := `
+	==model==
+	name: Test
+	==config==
+	chunks { [count: first second] }
+	==init==
+	memory { [count: 0 1] }
+	goal [count: 0 *]
+	==productions==
+	done {
+		match { goal [count: 0 *] }
+		do { clear goal }
+	}`
+
+	actrModel, log, err := amod.GenerateModel(amodFile)
+	if err != nil {
+		t.Fatalf("could not compile model: %s", log)
+	}
+
+	archive, err := w.createArchive("", &Model{actrModel: actrModel, amodFile: amodFile}, nil, []string{"vanilla"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var runFile string
+
+	for _, file := range reader.File {
+		if file.Name != "vanilla/vanilla_Test_run.lisp" {
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		runFile = string(contents)
+	}
+
+	if runFile == "" {
+		t.Fatal("archive does not contain vanilla/vanilla_Test_run.lisp")
+	}
+
+	for _, path := range []string{tmpPath, envPath, os.TempDir()} {
+		if strings.Contains(runFile, path) {
+			t.Errorf("run file refers to %q:\n%s", path, runFile)
+		}
+	}
+
+	if !strings.Contains(runFile, `(load (merge-pathnames "vanilla_Test.lisp" *load-truename*))`) {
+		t.Errorf("run file does not load the model relative to itself:\n%s", runFile)
+	}
+}
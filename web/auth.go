@@ -0,0 +1,164 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const defaultMaxBodySize = 1 << 20 // 1 MiB
+
+// validUserName restricts user names so they may be used as directory names.
+var validUserName = regexp.MustCompile(`^[a-zA-Z0-9_\-.]+$`)
+
+type userContextKey struct{}
+
+// authenticator checks the bearer token on API requests when the server is run with "--auth-tokens".
+// If it is nil, authentication is off and everything behaves as a single local user.
+type authenticator struct {
+	users       []authUser
+	maxBodySize int64
+}
+
+type authUser struct {
+	name  string
+	token string
+}
+
+// loadAuthTokens reads a file containing one user per line in the form "<user name> <token>".
+// Blank lines and lines starting with '#' are ignored.
+func loadAuthTokens(fileName string, maxBodySize int64) (auth *authenticator, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	auth = &authenticator{maxBodySize: maxBodySize}
+
+	names := map[string]bool{}
+	tokens := map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			err = fmt.Errorf("%s line %d: expected '<user name> <token>'", fileName, lineNumber)
+			return nil, err
+		}
+
+		name, token := fields[0], fields[1]
+
+		if !validUserName.MatchString(name) || name == "." || name == ".." {
+			err = fmt.Errorf("%s line %d: invalid user name %q (use letters, numbers, '_', '-', and '.')", fileName, lineNumber, name)
+			return nil, err
+		}
+
+		if names[name] {
+			err = fmt.Errorf("%s line %d: duplicate user name %q", fileName, lineNumber, name)
+			return nil, err
+		}
+
+		if tokens[token] {
+			err = fmt.Errorf("%s line %d: duplicate token for user %q", fileName, lineNumber, name)
+			return nil, err
+		}
+
+		names[name] = true
+		tokens[token] = true
+
+		auth.users = append(auth.users, authUser{name: name, token: token})
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(auth.users) == 0 {
+		err = fmt.Errorf("%s: no users found", fileName)
+		return nil, err
+	}
+
+	return
+}
+
+// lookupUser returns the name of the user with the given token or "" if not found.
+func (a authenticator) lookupUser(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	for _, user := range a.users {
+		if subtle.ConstantTimeCompare([]byte(user.token), []byte(token)) == 1 {
+			return user.name
+		}
+	}
+
+	return ""
+}
+
+// wrap returns a handler which checks the request's token and caps the size of its body before
+// calling "handler". The user's name is stored in the request's context (see requestUser()).
+func (a *authenticator) wrap(handler http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return handler
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		// Only accept the header - tokens in URLs end up in access logs, proxy logs, & browser history.
+		token := ""
+		if header := req.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+
+		user := a.lookupUser(token)
+		if user == "" {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			encodeErrorStatus(rw, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(rw, req.Body, a.maxBodySize)
+		}
+
+		ctx := context.WithValue(req.Context(), userContextKey{}, user)
+		handler(rw, req.WithContext(ctx))
+	}
+}
+
+// requestUser returns the name of the authenticated user or "" if authentication is off.
+func requestUser(req *http.Request) string {
+	user, _ := req.Context().Value(userContextKey{}).(string)
+	return user
+}
+
+// handleFunc registers an API handler, adding authentication if it is turned on.
+func (w *Web) handleFunc(pattern string, handler http.HandlerFunc) {
+	w.handlePublicFunc(pattern, w.auth.wrap(handler))
+}
+
+// handlePublicFunc registers an API handler which doesn't require authentication.
+func (w *Web) handlePublicFunc(pattern string, handler http.HandlerFunc) {
+	w.mux.HandleFunc(pattern, handler)
+	w.routes = append(w.routes, pattern)
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func createTokensFile(t *testing.T, contents string) string {
+	fileName := filepath.Join(t.TempDir(), "tokens.txt")
+
+	err := os.WriteFile(fileName, []byte(contents), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+func TestLoadAuthTokens(t *testing.T) {
+	fileName := createTokensFile(t, `# users for the class
+alice  a1b2c3
+
+bob    d4e5f6
+`)
+
+	auth, err := loadAuthTokens(fileName, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if len(auth.users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(auth.users))
+	}
+
+	if auth.maxBodySize != defaultMaxBodySize {
+		t.Errorf("Expected default max body size, got %d", auth.maxBodySize)
+	}
+
+	if user := auth.lookupUser("d4e5f6"); user != "bob" {
+		t.Errorf("Expected 'bob', got '%s'", user)
+	}
+
+	if user := auth.lookupUser("nope"); user != "" {
+		t.Errorf("Expected no user, got '%s'", user)
+	}
+}
+
+func TestLoadAuthTokensInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing token":   "alice\n",
+		"bad user name":   "../alice a1b2c3\n",
+		"duplicate user":  "alice a1b2c3\nalice d4e5f6\n",
+		"duplicate token": "alice a1b2c3\nbob a1b2c3\n",
+		"no users":        "# nobody\n",
+	}
+
+	for name, contents := range tests {
+		_, err := loadAuthTokens(createTokensFile(t, contents), 0)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAuthWrap(t *testing.T) {
+	auth := &authenticator{
+		users:       []authUser{{name: "alice", token: "a1b2c3"}},
+		maxBodySize: 8,
+	}
+
+	var seenUser string
+	handler := auth.wrap(func(rw http.ResponseWriter, req *http.Request) {
+		seenUser = requestUser(req)
+
+		var data endSessionRequest
+		err := decodeBody(req, &data)
+		if err != nil {
+			encodeErrorResponse(rw, err)
+		}
+	})
+
+	// no token
+	request := httptest.NewRequest("PUT", "/api/session/end", nil)
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, request)
+
+	if status := responseRecorder.Code; status != http.StatusUnauthorized {
+		t.Errorf("handler returned incorrect status code: expected '%v' got '%v'",
+			http.StatusUnauthorized, status)
+	}
+
+	// tokens in the URL are not accepted
+	request = httptest.NewRequest("PUT", "/api/session/end?token=a1b2c3", nil)
+	responseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, request)
+
+	if status := responseRecorder.Code; status != http.StatusUnauthorized {
+		t.Errorf("handler returned incorrect status code for token in URL: expected '%v' got '%v'",
+			http.StatusUnauthorized, status)
+	}
+
+	// valid token, body too large
+	request = httptest.NewRequest("PUT", "/api/session/end", bytes.NewBufferString(`{"sessionID":12345}`))
+	request.Header.Set("Authorization", "Bearer a1b2c3")
+	responseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, request)
+
+	if seenUser != "alice" {
+		t.Errorf("Expected user 'alice', got '%s'", seenUser)
+	}
+
+	expected := `{"issues":[{"level":"error","text":"http: request body too large"`
+	responseStr := strings.TrimSpace(responseRecorder.Body.String())
+	if !strings.HasPrefix(responseStr, expected) {
+		t.Errorf("handler returned unexpected body: expected to start with '%v' got '%v'",
+			expected, responseStr)
+	}
+}
+
+func TestSessionIsolation(t *testing.T) {
+	session := webTest.newSession("alice")
+
+	if webTest.lookupSession("bob", session.id) != nil {
+		t.Errorf("Session should not be visible to another user")
+	}
+
+	if webTest.endSession("bob", session.id) == nil {
+		t.Errorf("Session should not be ended by another user")
+	}
+
+	if webTest.lookupSession("alice", session.id) == nil {
+		t.Errorf("Session should be visible to its owner")
+	}
+
+	webTest.clearSessions()
+}
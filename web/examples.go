@@ -2,18 +2,18 @@ package web
 
 import "net/http"
 
+type exampleListResponse struct {
+	List []string `json:"exampleList"`
+}
+
 func initExamples(w *Web) {
 	exampleHandler := assetHandler(w.examples, "/api/", "")
-	http.HandleFunc("/api/examples/", exampleHandler.ServeHTTP)
-	http.HandleFunc("/api/examples/list", w.listExamples)
+	w.handleFunc("/api/examples/", exampleHandler.ServeHTTP)
+	w.handleFunc("/api/examples/list", w.listExamples)
 }
 
 // listExamples simply returns a list of the examples included in the build.
 func (w *Web) listExamples(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		List []string `json:"exampleList"`
-	}
-
 	entries, err := w.examples.ReadDir("examples")
 	if err != nil {
 		encodeErrorResponse(rw, err)
@@ -26,7 +26,7 @@ func (w *Web) listExamples(rw http.ResponseWriter, req *http.Request) {
 		list = append(list, entry.Name())
 	}
 
-	encodeResponse(rw, response{
+	encodeResponse(rw, exampleListResponse{
 		List: list,
 	})
 }
@@ -1,12 +1,90 @@
-import axios, { AxiosInstance } from 'axios'
+import axios, { AxiosError, AxiosInstance, AxiosRequestConfig } from 'axios'
 
 let gactarHTTP: AxiosInstance
 
+// If the server was started with --auth-tokens, every request needs the user's token. It is
+// read from the "token" parameter in the URL's fragment (which browsers don't send to the server)
+// or the user is asked for it when the server rejects a request. It is kept in session storage so reloading the page doesn't ask again.
+const tokenStorageKey = 'gactar-token'
+
+// requests which have already been retried with a new token
+const retriedRequests = new WeakSet<AxiosRequestConfig>()
+
+// the prompt for a token if we are already asking (so simultaneous requests only ask once)
+let tokenPrompt: Promise<string | null> | null = null
+
 function init(port: number) {
   gactarHTTP = axios.create({
     headers: { 'Content-Type': 'application/json' },
     baseURL: `http://localhost:${port}`,
   })
+
+  setToken(initialToken())
+
+  gactarHTTP.interceptors.response.use(undefined, retryWithToken)
+}
+
+// initialToken returns the token from the URL's fragment (removing it so it isn't left in the
+// address bar or history) or the one we stored earlier.
+function initialToken(): string {
+  const url = new URL(window.location.href)
+  const params = new URLSearchParams(url.hash.slice(1))
+  const token = params.get('token')
+
+  if (token === null) {
+    return sessionStorage.getItem(tokenStorageKey) ?? ''
+  }
+
+  params.delete('token')
+  url.hash = params.toString()
+  window.history.replaceState(window.history.state, '', url.toString())
+
+  return token
+}
+
+// setToken sets the token sent with every request.
+function setToken(token: string) {
+  if (token === '') {
+    sessionStorage.removeItem(tokenStorageKey)
+    delete gactarHTTP.defaults.headers.common['Authorization']
+    return
+  }
+
+  sessionStorage.setItem(tokenStorageKey, token)
+  gactarHTTP.defaults.headers.common['Authorization'] = `Bearer ${token}`
+}
+
+// retryWithToken asks for a token if the server rejects a request because it is missing or
+// invalid, and then tries the request again.
+async function retryWithToken(error: AxiosError) {
+  const config = error.config
+
+  if (error.response?.status !== 401 || retriedRequests.has(config)) {
+    throw error
+  }
+
+  if (tokenPrompt === null) {
+    tokenPrompt = Promise.resolve(
+      window.prompt('This gactar server requires a token. Please enter yours:')
+    ).finally(() => {
+      tokenPrompt = null
+    })
+  }
+
+  const token = await tokenPrompt
+  if (!token) {
+    throw error
+  }
+
+  setToken(token.trim())
+
+  retriedRequests.add(config)
+  config.headers = {
+    ...config.headers,
+    Authorization: `Bearer ${token.trim()}`,
+  }
+
+  return gactarHTTP.request(config)
 }
 
 // version
@@ -36,6 +114,9 @@ export interface FrameworkInfo {
   // Name of the executable that was run.
   executableName: string
 
+  // Version reported by the executable (if it could be determined).
+  executableVersion?: string
+
   // (Python only) List of packages this framework requires.
   pythonRequiredPackages?: string[]
 }
@@ -53,6 +134,67 @@ async function getFrameworks(): Promise<FrameworkInfoList> {
   return response.data.frameworks
 }
 
+// status
+export interface QueueStatus {
+  // Number of framework runs currently executing.
+  activeRuns: number
+
+  // Number of framework runs waiting to execute.
+  queuedRuns: number
+
+  // Maximum number of runs which may execute at once.
+  maxActiveRuns: number
+
+  // Maximum number of runs which may wait.
+  maxQueuedRuns: number
+}
+
+async function getStatus(): Promise<QueueStatus> {
+  const response = await gactarHTTP.get<QueueStatus>('/api/status')
+  return response.data
+}
+
+// health
+export interface FrameworkHealth {
+  // Name (id) of the framework.
+  name: string
+
+  // True if the framework can be used to run models.
+  ready: boolean
+
+  // Version reported by the executable during initialization.
+  executableVersion?: string
+
+  // Reason the framework is not ready.
+  error?: string
+}
+
+export interface HealthResponse {
+  // "ok", "degraded" (some frameworks are not ready), or "unavailable" (none are ready).
+  status: string
+
+  // Current version tag when gactar was built.
+  version: string
+
+  frameworks: FrameworkHealth[]
+
+  queue: QueueStatus
+}
+
+async function getHealth(): Promise<HealthResponse> {
+  // a 503 still contains the details, so don't treat it as an error
+  const response = await gactarHTTP.get<HealthResponse>('/api/health', {
+    validateStatus: (status) => status === 200 || status === 503,
+  })
+  return response.data
+}
+
+// A full run queue returns 503 (with a Retry-After header) along with the issue explaining why,
+// so don't treat it as an error when running.
+const runStatus = {
+  validateStatus: (status: number) => status === 200 || status === 503,
+}
+
 // run
 export interface RunParams {
   // The text of the amod to run.
@@ -63,6 +205,18 @@ export interface RunParams {
 
   // An optional list of frameworks ("all" if not set).
   frameworks?: string[]
+
+  // Number of times to run the model on each framework (1 if not set).
+  repeat?: number
+
+  // Random seed (overrides random_seed in the amod).
+  seed?: number
+
+  // Include the contents of the buffers & memory at the end of each run.
+  dumpState?: boolean
+
+  // ID to look up the position in the run queue while waiting (see /api/queue/).
+  runID?: string
 }
 
 // Location of an issue in the source code.
@@ -100,6 +254,125 @@ export interface FrameworkResult {
 
   // Output of run (stdout + stderr).
   output?: string
+
+  // Position in the run queue if the run had to wait for others to finish.
+  queuePosition?: number
+
+  // Summary of the run parsed from the output.
+  trace?: Trace
+
+  // Values of the measures declared in the amod file.
+  measures?: Measures
+
+  // Contents of the buffers & memory at the end of the run (if dumpState was set).
+  state?: State
+
+  // Random seed the model was run with (if it was seeded).
+  seed?: number
+
+  // When using "repeat", the fields above are from the first run.
+
+  // Stats for all the runs.
+  stats?: Stats
+
+  // Result of each run.
+  runs?: RepeatedRun[]
+}
+
+// Measures maps the names of the measures declared in the amod file to their values. A value is
+// null if the measure doesn't have one (e.g. the production never fired).
+export type Measures = { [key: string]: number | string | null }
+
+// Chunk in a buffer or memory at the end of a run.
+export interface ChunkState {
+  // Name of the chunk (if the framework names them).
+  name?: string
+
+  // Chunk type (not set if it doesn't match one in the model).
+  type?: string
+
+  // Value of each slot ("nil" if it is empty).
+  slots: { [key: string]: string }
+
+  // Activation of chunks in memory (if the framework provides it).
+  activation?: number
+}
+
+export interface State {
+  // Contents of each buffer (null if it is empty).
+  buffers: { [key: string]: ChunkState | null }
+
+  // Chunks in declarative memory.
+  memory: ChunkState[]
+}
+
+// Trace summarizes what happened during a run. Production firings & retrievals
+// are only included if the model's log_level is 'info' or 'detail'.
+export interface Trace {
+  // Lines output by the model's print statements.
+  printed?: string[]
+
+  // Number of times each production fired.
+  productions?: { [key: string]: number }
+
+  // Simulated time each production first fired (in seconds).
+  firedAt?: { [key: string]: number }
+
+  retrievals: number
+  retrievalFailures: number
+
+  // Simulated time of the last event (in seconds).
+  endTime: number
+}
+
+// Distribution of a value over the runs.
+export interface Summary {
+  mean: number
+  sd: number
+  min: number
+  max: number
+}
+
+// Number of runs which printed the same thing.
+export interface OutputCount {
+  printed: string
+  count: number
+  proportion: number
+}
+
+export interface Stats {
+  // Number of successful runs.
+  runs: number
+
+  // Number of failed runs (these aren't included in the stats).
+  failures: number
+
+  endTime: Summary
+  retrievals: Summary
+  retrievalFailures: Summary
+
+  // Only includes runs with at least one retrieval.
+  retrievalSuccessRate?: Summary
+
+  // Number of times each production fired per run.
+  productions?: { [key: string]: Summary }
+
+  // Distribution of printed output (most common first).
+  outputs?: OutputCount[]
+}
+
+export interface RepeatedRun {
+  issues?: IssueList
+  output?: string
+
+  // Not set if the run failed.
+  trace?: Trace
+
+  measures?: Measures
+
+  state?: State
+
+  seed?: number
 }
 
 export type FrameworkResultMap = { [key: string]: FrameworkResult }
@@ -110,7 +383,11 @@ export interface RunResult {
 }
 
 async function run(params: RunParams): Promise<RunResult> {
-  const response = await gactarHTTP.post<RunResult>('/api/run', params)
+  const response = await gactarHTTP.post<RunResult>(
+    '/api/run',
+    params,
+    runStatus
+  )
   return response.data
 }
 
@@ -154,6 +431,18 @@ export interface SessionRunParams {
 
   // Whether to include the generated code as part of the response.
   includeCode: boolean
+
+  // Number of times to run the model on each framework (1 if not set).
+  repeat?: number
+
+  // Random seed (overrides random_seed in the amod).
+  seed?: number
+
+  // Include the contents of the buffers & memory at the end of each run.
+  dumpState?: boolean
+
+  // ID to look up the position in the run queue while waiting (see /api/queue/).
+  runID?: string
 }
 
 export interface SessionRunResult extends FrameworkResult {
@@ -185,7 +474,8 @@ async function sessionRun(
 ): Promise<SessionRunResults> {
   const response = await gactarHTTP.post<SessionRunResults>(
     '/api/session/runModel',
-    params
+    params,
+    runStatus
   )
   return response.data
 }
@@ -218,15 +508,108 @@ async function modelLoad(params: ModelParams): Promise<ModelLoadResult> {
   return response.data
 }
 
+export interface ArchiveParams {
+  // The id of the session.
+  sessionID: number
+
+  // The ID of the model to archive.
+  modelID: number
+
+  // Initial contents of buffers (optional).
+  buffers?: { [key: string]: string }
+
+  // List of frameworks to include (if empty, "all").
+  frameworks?: string[]
+
+  // Whether to run the model and include the output.
+  run: boolean
+}
+
+async function modelArchive(params: ArchiveParams): Promise<Blob> {
+  const response = await gactarHTTP.post<Blob>('/api/model/archive', params, {
+    responseType: 'blob',
+  })
+  return response.data
+}
+
+// sweep
+
+// A parameter to sweep. Either "values" or "from", "to", & "step" must be set.
+export interface SweepParam {
+  // Module & parameter name (e.g. "memory.latency_factor").
+  name: string
+
+  // List of values to use.
+  values?: number[]
+
+  // Range of values (inclusive).
+  from?: number
+  to?: number
+  step?: number
+}
+
+export interface SweepParams {
+  // The text of an amod file.
+  amod: string
+
+  // The initial goal.
+  goal: string
+
+  // List of frameworks to run on (if empty, "all").
+  frameworks?: string[]
+
+  // Parameters to sweep.
+  params: SweepParam[]
+
+  // Number of times to run each combination on each framework (default 1).
+  repeat?: number
+
+  // Random seed (overrides random_seed in the amod).
+  seed?: number
+
+  // "json" (default) or "csv".
+  format?: string
+}
+
+// Stats for one combination of parameter values on one framework.
+export interface SweepResult {
+  // Value of each parameter.
+  values: { [key: string]: string }
+
+  framework: string
+  stats: Stats
+
+  // Errors from the runs which failed.
+  errors?: string[]
+}
+
+export interface SweepResponse {
+  issues?: IssueList
+  results?: SweepResult[]
+}
+
+async function sweep(params: SweepParams): Promise<SweepResponse> {
+  const response = await gactarHTTP.post<SweepResponse>(
+    '/api/sweep',
+    params,
+    runStatus
+  )
+  return response.data
+}
+
 export default {
   getExample,
   getExampleList,
   getFrameworks,
+  getHealth,
+  getStatus,
   getVersion,
   init,
+  modelArchive,
   modelLoad,
   run,
   sessionBegin,
   sessionEnd,
   sessionRun,
+  sweep,
 }
@@ -11,6 +11,7 @@ interface State {
 CodeMirror.defineMode('amod', function () {
   const section_regex = /^={2}(model|config|init|productions)={2}/
   const variable_regex = /[?][a-zA-Z0-9_]*/
+  const chunk_ref_regex = /[&][a-zA-Z0-9_]*/
 
   const keywords = {
     authors: true,
@@ -19,8 +20,11 @@ CodeMirror.defineMode('amod', function () {
     description: true,
     do: true,
     examples: true,
+    from: true,
     gactar: true,
+    load: true,
     match: true,
+    measures: true,
     modules: true,
     name: true,
     print: true,
@@ -89,6 +93,16 @@ CodeMirror.defineMode('amod', function () {
       }
     }
 
+    if (ch === '&') {
+      if (state.inPattern) {
+        stream.backUp(1)
+
+        if (stream.match(chunk_ref_regex)) {
+          return 'chunk-name'
+        }
+      }
+    }
+
     if (ch === ']') {
       state.inPattern = false
       return 'bracket'
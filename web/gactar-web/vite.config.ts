@@ -8,7 +8,7 @@ export default defineConfig({
   server: {
     // This lets us run gactar to serve the endpoints, but run the UI through
     // vite for testing. When running "npm run dev", the frontend will be updated
-    // live and the backend will be served by running "gactar -w".
+    // live and the backend will be served by running "gactar serve".
     proxy: {
       '/api': 'http://localhost:8181',
     },
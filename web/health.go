@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/asmaloney/gactar/util/filesystem"
+	"github.com/asmaloney/gactar/util/version"
+)
+
+type frameworkHealth struct {
+	Name              string `json:"name"`
+	Ready             bool   `json:"ready"`                       // true if the framework can be used to run models
+	ExecutableVersion string `json:"executableVersion,omitempty"` // version reported by the executable during initialization
+	Error             string `json:"error,omitempty"`             // reason the framework is not ready
+}
+
+type healthResponse struct {
+	Status     string            `json:"status"` // "ok", "degraded" (some frameworks not ready), or "unavailable"
+	Version    string            `json:"version"`
+	Frameworks []frameworkHealth `json:"frameworks"`
+	Queue      queueStatus       `json:"queue"`
+}
+
+// getHealthHandler reports the status of each framework. It returns 503 if no frameworks are
+// usable so a process supervisor may restart or flag the server.
+func (w Web) getHealthHandler(rw http.ResponseWriter, req *http.Request) {
+	response := healthResponse{
+		Version:    version.BuildVersion,
+		Frameworks: []frameworkHealth{},
+		Queue:      w.queue.status(),
+	}
+
+	numReady := 0
+
+	for name, f := range w.actrFrameworks {
+		info := f.Info()
+
+		health := frameworkHealth{
+			Name:              name,
+			Ready:             true,
+			ExecutableVersion: info.ExecutableVersion,
+		}
+
+		// the executable may have been removed or the environment changed since we started
+		_, err := filesystem.CheckForExecutable(info.ExecutableName)
+		if err != nil {
+			health.Ready = false
+			health.Error = err.Error()
+		} else {
+			numReady++
+		}
+
+		response.Frameworks = append(response.Frameworks, health)
+	}
+
+	for name, initErr := range w.initErrors {
+		response.Frameworks = append(response.Frameworks, frameworkHealth{
+			Name:  name,
+			Error: initErr,
+		})
+	}
+
+	sort.Slice(response.Frameworks, func(i, j int) bool {
+		return response.Frameworks[i].Name < response.Frameworks[j].Name
+	})
+
+	switch {
+	case numReady == 0:
+		response.Status = "unavailable"
+	case numReady < len(response.Frameworks):
+		response.Status = "degraded"
+	default:
+		response.Status = "ok"
+	}
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if numReady == 0 {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(rw).Encode(response)
+}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/asmaloney/gactar/util/version"
+)
+
+// runDurationBuckets are the upper bounds (in seconds) of the run duration histogram.
+var runDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// metrics keeps track of runs so they may be exported in the Prometheus text format.
+type metrics struct {
+	mutex sync.Mutex
+
+	frameworks map[string]*frameworkMetrics
+}
+
+type frameworkMetrics struct {
+	runs     uint64
+	failures uint64
+
+	durationBuckets []uint64 // count of runs which took <= the corresponding runDurationBuckets entry
+	durationSum     float64  // total of all run durations in seconds
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		frameworks: map[string]*frameworkMetrics{},
+	}
+}
+
+// recordRun adds one run of framework "name" to our metrics.
+func (m *metrics) recordRun(name string, duration time.Duration, failed bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fm := m.lookup(name)
+
+	fm.runs++
+	if failed {
+		fm.failures++
+	}
+
+	seconds := duration.Seconds()
+	fm.durationSum += seconds
+
+	for i, bound := range runDurationBuckets {
+		if seconds <= bound {
+			fm.durationBuckets[i]++
+		}
+	}
+}
+
+// lookup returns the metrics for framework "name", creating them if necessary.
+// The mutex must be held by the caller.
+func (m *metrics) lookup(name string) *frameworkMetrics {
+	fm, ok := m.frameworks[name]
+	if !ok {
+		fm = &frameworkMetrics{
+			durationBuckets: make([]uint64, len(runDurationBuckets)),
+		}
+		m.frameworks[name] = fm
+	}
+
+	return fm
+}
+
+// write outputs the metrics for the frameworks in "names" using the Prometheus text format.
+func (m *metrics) write(out io.Writer, names []string, activeSessions int, queue queueStatus) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	names = append([]string{}, names...)
+	sort.Strings(names)
+
+	writeHeader := func(name, metricType, help string) {
+		fmt.Fprintf(out, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(out, "# TYPE %s %s\n", name, metricType)
+	}
+
+	writeHeader("gactar_build_info", "gauge", "Version of gactar being run.")
+	fmt.Fprintf(out, "gactar_build_info{version=%q} 1\n", version.BuildVersion)
+
+	writeHeader("gactar_runs_total", "counter", "Number of models run on each framework.")
+	for _, name := range names {
+		fmt.Fprintf(out, "gactar_runs_total{framework=%q} %d\n", name, m.lookup(name).runs)
+	}
+
+	writeHeader("gactar_run_failures_total", "counter", "Number of runs on each framework which resulted in an error.")
+	for _, name := range names {
+		fmt.Fprintf(out, "gactar_run_failures_total{framework=%q} %d\n", name, m.lookup(name).failures)
+	}
+
+	writeHeader("gactar_run_duration_seconds", "histogram", "Time taken to run a model on each framework.")
+	for _, name := range names {
+		fm := m.lookup(name)
+
+		for i, bound := range runDurationBuckets {
+			fmt.Fprintf(out, "gactar_run_duration_seconds_bucket{framework=%q,le=\"%g\"} %d\n", name, bound, fm.durationBuckets[i])
+		}
+
+		fmt.Fprintf(out, "gactar_run_duration_seconds_bucket{framework=%q,le=\"+Inf\"} %d\n", name, fm.runs)
+		fmt.Fprintf(out, "gactar_run_duration_seconds_sum{framework=%q} %g\n", name, fm.durationSum)
+		fmt.Fprintf(out, "gactar_run_duration_seconds_count{framework=%q} %d\n", name, fm.runs)
+	}
+
+	writeHeader("gactar_active_sessions", "gauge", "Number of sessions which have not been ended.")
+	fmt.Fprintf(out, "gactar_active_sessions %d\n", activeSessions)
+
+	writeHeader("gactar_queue_active_runs", "gauge", "Number of framework runs currently executing.")
+	fmt.Fprintf(out, "gactar_queue_active_runs %d\n", queue.ActiveRuns)
+
+	writeHeader("gactar_queue_waiting_runs", "gauge", "Number of framework runs waiting in the queue.")
+	fmt.Fprintf(out, "gactar_queue_waiting_runs %d\n", queue.QueuedRuns)
+}
+
+func (w Web) getMetricsHandler(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	w.metrics.write(rw, w.actrFrameworks.Names(), len(w.sessionList), w.queue.status())
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := newMetrics()
+
+	m.recordRun("ccm", 300*time.Millisecond, false)
+	m.recordRun("ccm", 3*time.Second, true)
+
+	var out bytes.Buffer
+	m.write(&out, []string{"vanilla", "ccm"}, 2, queueStatus{ActiveRuns: 1, QueuedRuns: 3})
+
+	expected := []string{
+		`gactar_runs_total{framework="ccm"} 2`,
+		`gactar_runs_total{framework="vanilla"} 0`,
+		`gactar_run_failures_total{framework="ccm"} 1`,
+		`gactar_run_duration_seconds_bucket{framework="ccm",le="0.25"} 0`,
+		`gactar_run_duration_seconds_bucket{framework="ccm",le="0.5"} 1`,
+		`gactar_run_duration_seconds_bucket{framework="ccm",le="5"} 2`,
+		`gactar_run_duration_seconds_bucket{framework="ccm",le="+Inf"} 2`,
+		`gactar_run_duration_seconds_sum{framework="ccm"} 3.3`,
+		`gactar_run_duration_seconds_count{framework="ccm"} 2`,
+		`gactar_active_sessions 2`,
+		`gactar_queue_active_runs 1`,
+		`gactar_queue_waiting_runs 3`,
+	}
+
+	lines := strings.Split(out.String(), "\n")
+
+	for _, line := range expected {
+		found := false
+		for _, l := range lines {
+			if l == line {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("Missing line in metrics: %s", line)
+		}
+	}
+
+	// ccm should be output before vanilla
+	if strings.Index(out.String(), `framework="ccm"`) > strings.Index(out.String(), `framework="vanilla"`) {
+		t.Errorf("Expected frameworks to be sorted by name")
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	request, err := http.NewRequest("GET", "/api/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.getHealthHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	var response healthResponse
+	err = json.Unmarshal(responseRecorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Frameworks) != len(webTest.actrFrameworks)+len(webTest.initErrors) {
+		t.Errorf("Expected %d frameworks, got %d", len(webTest.actrFrameworks)+len(webTest.initErrors), len(response.Frameworks))
+	}
+
+	for _, f := range response.Frameworks {
+		if !f.Ready && f.Error == "" {
+			t.Errorf("Framework %q is not ready but has no error", f.Name)
+		}
+	}
+
+	// If nothing is usable, let the supervisor know
+	if response.Status == "unavailable" && responseRecorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, responseRecorder.Code)
+	}
+}
@@ -14,45 +14,47 @@ var currentModelID = 1
 type Model struct {
 	id        int
 	actrModel *actr.Model
+	amodFile  string // the source the model was compiled from
+}
+
+type loadModelRequest struct {
+	SessionID int    `json:"sessionID"`
+	AMODFile  string `json:"amod"`
+}
+
+type loadModelResponse struct {
+	ModelID   int    `json:"modelID"`
+	ModelName string `json:"modelName"`
+	SessionID int    `json:"sessionID"`
 }
 
 func initModels(w *Web) {
-	http.HandleFunc("/api/model/load", w.loadModelHandler)
+	w.handleFunc("/api/model/load", w.loadModelHandler)
 }
 
 func (w *Web) loadModelHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		SessionID int    `json:"sessionID"`
-		AMODFile  string `json:"amod"`
-	}
-	type response struct {
-		ModelID   int    `json:"modelID"`
-		ModelName string `json:"modelName"`
-		SessionID int    `json:"sessionID"`
-	}
-
-	var data request
+	var data loadModelRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	model, err := w.loadModel(data.SessionID, data.AMODFile)
+	model, err := w.loadModel(requestUser(req), data.SessionID, data.AMODFile)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	encodeResponse(rw, response{
+	encodeResponse(rw, loadModelResponse{
 		ModelID:   model.id,
 		ModelName: model.actrModel.Name,
 		SessionID: data.SessionID,
 	})
 }
 
-func (w *Web) loadModel(sessionID int, amodFile string) (model *Model, err error) {
-	session := w.lookupSession(sessionID)
+func (w *Web) loadModel(user string, sessionID int, amodFile string) (model *Model, err error) {
+	session := w.lookupSession(user, sessionID)
 	if session == nil {
 		err = fmt.Errorf("invalid session id '%d'", sessionID)
 		return
@@ -66,6 +68,7 @@ func (w *Web) loadModel(sessionID int, amodFile string) (model *Model, err error
 	model = &Model{
 		id:        currentModelID,
 		actrModel: actrModel,
+		amodFile:  amodFile,
 	}
 	currentModelID++
 
@@ -10,13 +10,13 @@ import (
 )
 
 func TestAddModel(t *testing.T) {
-	session := webTest.newSession()
+	session := webTest.newSession("")
 
 	if session == nil {
 		t.Fatalf("Could not create session")
 	}
 
-	err := webTest.endSession(session.id)
+	err := webTest.endSession("", session.id)
 
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
@@ -28,7 +28,7 @@ func TestAddModel(t *testing.T) {
 }
 
 func TestLoadModelHandler(t *testing.T) {
-	session := webTest.newSession()
+	session := webTest.newSession("")
 
 	src := `==model==
 	name: Test
@@ -0,0 +1,314 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/asmaloney/gactar/util/version"
+)
+
+// The OpenAPI document is generated from the request & response structs used by the handlers.
+// A copy is checked in as openapi.json so client generators have something to work from without
+// running a server. TestOpenAPISpec fails if the two differ.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// apiEndpoint describes one endpoint for the OpenAPI document.
+type apiEndpoint struct {
+	path        string
+	method      string
+	summary     string
+	request     interface{} // nil if the endpoint does not take a body
+	response    interface{} // nil if the endpoint does not return JSON
+	contentType string      // content type if the response is not JSON
+	queued      bool        // true if it runs frameworks using the run queue (returns 503 if it is full)
+}
+
+// apiEndpoints lists all the endpoints we serve. TestRoutesInOpenAPISpec checks that each route
+// registered by registerRoutes() is here.
+var apiEndpoints = []apiEndpoint{
+	{path: "/api/version", method: "get", summary: "Get the version of gactar being run", response: versionResponse{}},
+	{path: "/api/frameworks", method: "get", summary: "Get a list of frameworks supported by the server", response: frameworksResponse{}},
+	{path: "/api/openapi.json", method: "get", summary: "Get this OpenAPI description of the API", contentType: "application/json"},
+	{path: "/api/health", method: "get", summary: "Get the status of each framework (returns 503 if none are usable)", response: healthResponse{}},
+	{path: "/metrics", method: "get", summary: "Get run metrics in the Prometheus text format", contentType: "text/plain"},
+	{path: "/api/status", method: "get", summary: "Get the status of the run queue", response: queueStatus{}},
+	{path: "/api/queue/{runID}", method: "get", summary: "Get the position in the run queue of a request made with a runID", response: queuePositionResponse{}},
+	{path: "/api/run", method: "post", summary: "Compile and run amod code", request: runRequest{}, response: runResult{}, queued: true},
+	{path: "/api/sweep", method: "post", summary: "Run amod code using each combination of parameter values (returns CSV if format is 'csv')", request: sweepRequest{}, response: sweepResponse{}, queued: true},
+	{path: "/api/examples/list", method: "get", summary: "Get a list of the examples built in to the server", response: exampleListResponse{}},
+	{path: "/api/examples/{name}", method: "get", summary: "Get the amod code of a built-in example", contentType: "text/plain"},
+	{path: "/api/session/begin", method: "get", summary: "Begin a new session", response: beginSessionResponse{}},
+	{path: "/api/session/runModel", method: "post", summary: "Run a model which was loaded into a session", request: sessionRunRequest{}, response: sessionRunResponse{}, queued: true},
+	{path: "/api/session/end", method: "put", summary: "End a session", request: endSessionRequest{}, response: endSessionResponse{}},
+	{path: "/api/model/load", method: "put", summary: "Compile amod code and store it in a session", request: loadModelRequest{}, response: loadModelResponse{}},
+	{path: "/api/model/archive", method: "post", summary: "Download the amod source, generated code, and outputs as a zip file", request: archiveRequest{}, contentType: "application/zip", queued: true},
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                            `json:"openapi"`
+	Info       openAPIInfo                       `json:"info"`
+	Paths      map[string]map[string]openAPIOp   `json:"paths"`
+	Components map[string]map[string]*jsonSchema `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIOp struct {
+	Summary     string                     `json:"summary"`
+	Parameters  []openAPIParam             `json:"parameters,omitempty"`
+	RequestBody *openAPIBody               `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParam struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// generateOpenAPI builds the OpenAPI 3 document from apiEndpoints.
+func generateOpenAPI() *openAPIDoc {
+	schemas := map[string]*jsonSchema{}
+
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "gactar",
+			Description: "HTTP API provided by gactar when run as a web server. Errors are returned as a RunResult containing only issues.",
+			Version:     "1",
+		},
+		Paths:      map[string]map[string]openAPIOp{},
+		Components: map[string]map[string]*jsonSchema{"schemas": schemas},
+	}
+
+	for _, endpoint := range apiEndpoints {
+		op := openAPIOp{
+			Summary:   endpoint.summary,
+			Responses: map[string]openAPIResponse{},
+		}
+
+		if _, rest, found := strings.Cut(endpoint.path, "{"); found {
+			name, _, _ := strings.Cut(rest, "}")
+			op.Parameters = []openAPIParam{
+				{Name: name, In: "path", Required: true, Schema: &jsonSchema{Type: "string"}},
+			}
+		}
+
+		if endpoint.request != nil {
+			op.RequestBody = &openAPIBody{
+				Required: true,
+				Content: map[string]openAPIMediaType{
+					"application/json": {Schema: schemaFor(reflect.TypeOf(endpoint.request), schemas)},
+				},
+			}
+		}
+
+		if endpoint.response != nil {
+			op.Responses["200"] = openAPIResponse{
+				Description: "OK",
+				Content: map[string]openAPIMediaType{
+					"application/json": {Schema: schemaFor(reflect.TypeOf(endpoint.response), schemas)},
+				},
+			}
+		} else {
+			schema := &jsonSchema{Type: "string"}
+			if endpoint.contentType == "application/json" {
+				schema = &jsonSchema{} // JSON without a Go type may be anything
+			} else if endpoint.contentType != "text/plain" {
+				schema.Format = "binary"
+			}
+
+			op.Responses["200"] = openAPIResponse{
+				Description: "OK",
+				Content: map[string]openAPIMediaType{
+					endpoint.contentType: {Schema: schema},
+				},
+			}
+		}
+
+		if endpoint.queued {
+			op.Responses["503"] = openAPIResponse{
+				Description: fmt.Sprintf("The run queue is full - try again after the number of seconds in the Retry-After header (%d)", queueFullRetryAfter),
+				Content: map[string]openAPIMediaType{
+					"application/json": {Schema: schemaFor(reflect.TypeOf(runResult{}), schemas)},
+				},
+			}
+		}
+
+		if _, ok := doc.Paths[endpoint.path]; !ok {
+			doc.Paths[endpoint.path] = map[string]openAPIOp{}
+		}
+		doc.Paths[endpoint.path][endpoint.method] = op
+	}
+
+	return doc
+}
+
+// schemaFor returns the schema for type "t". Structs are added to "schemas" and referenced by name.
+func schemaFor(t reflect.Type, schemas map[string]*jsonSchema) *jsonSchema {
+	// Types which do their own JSON encoding (e.g. measure values which are numbers or strings)
+	// may be any value.
+	if t == rawMessageType || (t.Kind() == reflect.Struct && t.Implements(marshalerType)) {
+		return &jsonSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), schemas)
+
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number", Format: "double"}
+
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: schemaFor(t.Elem(), schemas)}
+
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: schemaFor(t.Elem(), schemas)}
+
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, ok := schemas[name]; !ok {
+			// add a placeholder first in case the type refers to itself
+			schemas[name] = &jsonSchema{}
+			*schemas[name] = *structSchema(t, schemas)
+		}
+
+		return &jsonSchema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &jsonSchema{}
+}
+
+// structSchema creates an object schema using the same rules as encoding/json.
+func structSchema(t reflect.Type, schemas map[string]*jsonSchema) *jsonSchema {
+	schema := &jsonSchema{
+		Type:       "object",
+		Properties: map[string]*jsonSchema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		omitEmpty := strings.Contains(options, "omitempty")
+
+		// embedded structs without a name have their fields promoted
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				promoted := structSchema(embedded, schemas)
+				for k, v := range promoted.Properties {
+					schema.Properties[k] = v
+				}
+				schema.Required = append(schema.Required, promoted.Required...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = schemaFor(field.Type, schemas)
+
+		if !omitEmpty && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	sort.Strings(schema.Required)
+
+	return schema
+}
+
+// schemaName converts a Go type name to the name we use in the components section.
+// e.g. runRequest -> RunRequest
+func schemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	if len(name) == 0 {
+		return "Anonymous"
+	}
+
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+func (Web) getOpenAPIHandler(rw http.ResponseWriter, req *http.Request) {
+	// fill in our version so clients can tell which build they are talking to
+	var doc map[string]interface{}
+
+	err := json.Unmarshal(openAPISpec, &doc)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	if info, ok := doc["info"].(map[string]interface{}); ok && version.BuildVersion != "" {
+		info["version"] = version.BuildVersion
+	}
+
+	encodeResponse(rw, doc)
+}
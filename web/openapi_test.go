@@ -0,0 +1,80 @@
+package web
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+var updateSpec = flag.Bool("update", false, "update openapi.json from the Go types")
+
+func TestOpenAPISpec(t *testing.T) {
+	generated, err := json.MarshalIndent(generateOpenAPI(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated = append(generated, '\n')
+
+	if *updateSpec {
+		err = os.WriteFile("openapi.json", generated, 0660)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if !bytes.Equal(generated, openAPISpec) {
+		t.Errorf("openapi.json does not match the request/response types - if the change is intended, run 'go test ./web -run TestOpenAPISpec -update'")
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	request, err := http.NewRequest("GET", "/api/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.getOpenAPIHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	if status := responseRecorder.Code; status != http.StatusOK {
+		t.Errorf("handler returned incorrect status code: expected '%v' got '%v'",
+			http.StatusOK, status)
+	}
+
+	expected := `{"components":`
+	responseStr := strings.TrimSpace(responseRecorder.Body.String())
+	if !strings.HasPrefix(responseStr, expected) {
+		t.Errorf("handler returned unexpected body: expected to start with '%v' got '%v'",
+			expected, responseStr)
+	}
+}
+
+func TestRoutesInOpenAPISpec(t *testing.T) {
+	w := &Web{mux: http.NewServeMux(), examples: &embed.FS{}}
+	w.registerRoutes()
+
+	for _, route := range w.routes {
+		found := false
+
+		for _, endpoint := range apiEndpoints {
+			// routes ending in "/" handle paths with a parameter (e.g. "/api/examples/{name}")
+			if endpoint.path == route || (strings.HasSuffix(route, "/") && strings.HasPrefix(endpoint.path, route+"{")) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("route %q is not described in apiEndpoints", route)
+		}
+	}
+}
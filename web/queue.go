@@ -0,0 +1,218 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const defaultMaxActiveRuns = 4
+
+// queueFullRetryAfter is the number of seconds clients are asked to wait (using the Retry-After
+// header) before trying again when the queue is full.
+const queueFullRetryAfter = 5
+
+// runQueue limits the number of framework processes running at the same time.
+// Runs which cannot start immediately wait in FIFO order. If the queue is full,
+// new runs are rejected.
+type runQueue struct {
+	mutex sync.Mutex
+
+	maxActive int // maximum number of runs executing at once
+	maxQueued int // maximum number of runs waiting to execute
+
+	active  int
+	waiting []*queueTicket
+
+	tracked map[string][]*queueTicket // tickets of requests which gave a run ID (see position())
+}
+
+// queueTicket is handed out for each run. The run may start once "ready" is closed.
+type queueTicket struct {
+	position int // position in the queue when it was added (0 means it started immediately)
+	ready    chan struct{}
+}
+
+// queuePositionResponse is the live position of a request's runs in the queue.
+type queuePositionResponse struct {
+	Position int `json:"position"` // position of the request's first waiting run (0 once they have all started)
+}
+
+type queueStatus struct {
+	ActiveRuns    int `json:"activeRuns"`    // number of framework runs currently executing
+	QueuedRuns    int `json:"queuedRuns"`    // number of framework runs waiting to execute
+	MaxActiveRuns int `json:"maxActiveRuns"` // maximum number of runs which may execute at once
+	MaxQueuedRuns int `json:"maxQueuedRuns"` // maximum number of runs which may wait
+}
+
+// queueFullError is returned when there is no room in the queue for a request.
+type queueFullError struct {
+	queued int
+}
+
+func (e queueFullError) Error() string {
+	return fmt.Sprintf("server is busy (%d runs waiting) - please try again later", e.queued)
+}
+
+func newRunQueue(maxActive, maxQueued int) *runQueue {
+	if maxActive < 1 {
+		maxActive = defaultMaxActiveRuns
+	}
+
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+
+	return &runQueue{
+		maxActive: maxActive,
+		maxQueued: maxQueued,
+		tracked:   map[string][]*queueTicket{},
+	}
+}
+
+// capacity returns the most runs one request may reserve.
+func (q *runQueue) capacity() int {
+	return q.maxActive + q.maxQueued
+}
+
+// enqueue reserves "num" runs. Either all of them are added or none are so that
+// a request for several frameworks is not partially run. If key is not empty, the position of the
+// runs may be looked up using it until untrack() is called.
+func (q *runQueue) enqueue(num int, key string) (tickets []*queueTicket, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.tracked[key]; ok && key != "" {
+		err = fmt.Errorf("run ID is already in use")
+		return
+	}
+
+	available := q.maxActive - q.active
+	if available < 0 {
+		available = 0
+	}
+
+	needToWait := num - available
+	if len(q.waiting) > 0 {
+		// someone is already waiting, so to keep things FIFO we can't jump ahead
+		needToWait = num
+	}
+
+	if needToWait > 0 && len(q.waiting)+needToWait > q.maxQueued {
+		err = queueFullError{queued: len(q.waiting)}
+		return
+	}
+
+	tickets = make([]*queueTicket, num)
+
+	for i := range tickets {
+		ticket := &queueTicket{ready: make(chan struct{})}
+
+		if len(q.waiting) == 0 && q.active < q.maxActive {
+			q.active++
+			close(ticket.ready)
+		} else {
+			q.waiting = append(q.waiting, ticket)
+			ticket.position = len(q.waiting)
+		}
+
+		tickets[i] = ticket
+	}
+
+	if key != "" {
+		q.tracked[key] = tickets
+	}
+
+	return
+}
+
+// position returns the current position of the first waiting run of the request with the given
+// key (0 if they have all started). It returns false if there is no request with the key.
+func (q *runQueue) position(key string) (position int, found bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	tickets, found := q.tracked[key]
+	if !found {
+		return
+	}
+
+	for i, waiting := range q.waiting {
+		for _, ticket := range tickets {
+			if ticket == waiting {
+				return i + 1, true
+			}
+		}
+	}
+
+	return
+}
+
+// untrack stops tracking the position of the request with the given key.
+func (q *runQueue) untrack(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.tracked, key)
+}
+
+// wait blocks until the ticket may run.
+func (t *queueTicket) wait() {
+	<-t.ready
+}
+
+// done must be called when a run is finished to let the next one start.
+func (q *runQueue) done() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.waiting) > 0 {
+		next := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		close(next.ready)
+		return
+	}
+
+	q.active--
+}
+
+func (q *runQueue) status() queueStatus {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return queueStatus{
+		ActiveRuns:    q.active,
+		QueuedRuns:    len(q.waiting),
+		MaxActiveRuns: q.maxActive,
+		MaxQueuedRuns: q.maxQueued,
+	}
+}
+
+func (w Web) getStatusHandler(rw http.ResponseWriter, req *http.Request) {
+	encodeResponse(rw, w.queue.status())
+}
+
+// getQueuePositionHandler returns the live queue position of a request which is waiting to run.
+// The run ID is chosen by the client when making the request ("runID").
+func (w Web) getQueuePositionHandler(rw http.ResponseWriter, req *http.Request) {
+	runID := strings.TrimPrefix(req.URL.Path, "/api/queue/")
+
+	position, found := w.queue.position(queueKey(requestUser(req), runID))
+	if !found {
+		encodeErrorStatus(rw, http.StatusNotFound, fmt.Errorf("no run with ID %q is waiting or running", runID))
+		return
+	}
+
+	encodeResponse(rw, queuePositionResponse{Position: position})
+}
+
+// queueKey returns the key used to track a request's runs in the queue. Run IDs are chosen by
+// clients, so they are kept separate for each user.
+func queueKey(user, runID string) string {
+	if runID == "" {
+		return ""
+	}
+
+	return user + "/" + runID
+}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunQueueStartsImmediately(t *testing.T) {
+	q := newRunQueue(2, 2)
+
+	tickets, err := q.enqueue(2, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	for _, ticket := range tickets {
+		if ticket.position != 0 {
+			t.Errorf("Expected ticket to start immediately, but it is at position %d", ticket.position)
+		}
+	}
+
+	status := q.status()
+	if status.ActiveRuns != 2 || status.QueuedRuns != 0 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestRunQueueFIFO(t *testing.T) {
+	q := newRunQueue(1, 4)
+
+	first, _ := q.enqueue(1, "")
+
+	waiting, err := q.enqueue(2, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if waiting[0].position != 1 || waiting[1].position != 2 {
+		t.Errorf("Unexpected queue positions: %d %d", waiting[0].position, waiting[1].position)
+	}
+
+	select {
+	case <-waiting[0].ready:
+		t.Fatalf("Ticket should not be ready before the first run is done")
+	default:
+	}
+
+	first[0].wait()
+	q.done()
+
+	select {
+	case <-waiting[0].ready:
+	default:
+		t.Fatalf("First waiting ticket should be ready")
+	}
+
+	select {
+	case <-waiting[1].ready:
+		t.Fatalf("Second waiting ticket should not be ready yet")
+	default:
+	}
+
+	q.done()
+	q.done()
+
+	status := q.status()
+	if status.ActiveRuns != 0 || status.QueuedRuns != 0 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestRunQueuePosition(t *testing.T) {
+	q := newRunQueue(1, 4)
+
+	first, _ := q.enqueue(1, "")
+	q.enqueue(1, "")
+
+	_, err := q.enqueue(2, "user/run-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	_, err = q.enqueue(1, "user/run-1")
+	if err == nil {
+		t.Errorf("Expected error for a run ID which is in use")
+	}
+
+	if position, found := q.position("user/run-1"); !found || position != 2 {
+		t.Errorf("Expected position 2, got %d (found: %v)", position, found)
+	}
+
+	// the position changes as runs finish
+	first[0].wait()
+	q.done()
+
+	if position, _ := q.position("user/run-1"); position != 1 {
+		t.Errorf("Expected position 1, got %d", position)
+	}
+
+	q.done() // first of the request's runs starts
+	q.done() // second starts
+
+	if position, found := q.position("user/run-1"); !found || position != 0 {
+		t.Errorf("Expected position 0 once running, got %d (found: %v)", position, found)
+	}
+
+	q.untrack("user/run-1")
+
+	if _, found := q.position("user/run-1"); found {
+		t.Errorf("Expected run ID not to be found after it is untracked")
+	}
+}
+
+func TestQueuePositionHandler(t *testing.T) {
+	w := Web{queue: newRunQueue(1, 1)}
+	w.queue.enqueue(1, "")
+	w.queue.enqueue(1, queueKey("", "abc"))
+
+	request := httptest.NewRequest("GET", "/api/queue/abc", nil)
+	rw := httptest.NewRecorder()
+	w.getQueuePositionHandler(rw, request)
+
+	if body := strings.TrimSpace(rw.Body.String()); body != `{"position":1}` {
+		t.Errorf("Unexpected body: %s", body)
+	}
+
+	request = httptest.NewRequest("GET", "/api/queue/other", nil)
+	rw = httptest.NewRecorder()
+	w.getQueuePositionHandler(rw, request)
+
+	if rw.Code != http.StatusNotFound || !strings.HasPrefix(rw.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("Unexpected response for unknown run ID: %d %v", rw.Code, rw.Header())
+	}
+}
+
+func TestRunQueueFull(t *testing.T) {
+	q := newRunQueue(1, 1)
+
+	_, err := q.enqueue(2, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	_, err = q.enqueue(1, "")
+
+	var fullErr queueFullError
+	if !errors.As(err, &fullErr) {
+		t.Fatalf("Expected queue full error, got: %v", err)
+	}
+
+	status := q.status()
+	if status.ActiveRuns != 1 || status.QueuedRuns != 1 {
+		t.Errorf("Rejected request should not change the queue: %+v", status)
+	}
+}
+
+func TestQueueFullResponse(t *testing.T) {
+	rw := httptest.NewRecorder()
+	encodeErrorResponse(rw, queueFullError{queued: 2})
+
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rw.Code)
+	}
+
+	if retry := rw.Header().Get("Retry-After"); retry != strconv.Itoa(queueFullRetryAfter) {
+		t.Errorf("Unexpected Retry-After header: %q", retry)
+	}
+
+	if contentType := rw.Result().Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Unexpected Content-Type header: %q", contentType)
+	}
+
+	// other errors are still returned with the issue
+	rw = httptest.NewRecorder()
+	encodeErrorResponse(rw, errors.New("some other error"))
+
+	if rw.Code != http.StatusOK || rw.Header().Get("Retry-After") != "" {
+		t.Errorf("Unexpected response for other errors: %d %v", rw.Code, rw.Header())
+	}
+}
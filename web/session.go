@@ -1,7 +1,6 @@
 package web
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -10,49 +9,64 @@ import (
 
 type Session struct {
 	id     int
+	owner  string // name of the user who created the session ("" if authentication is off)
 	models []*Model
 }
 
 type SessionList []*Session
 
+type beginSessionResponse struct {
+	SessionID int `json:"session_id"`
+}
+
+type sessionRunRequest struct {
+	SessionID   int                      `json:"sessionID"`
+	ModelID     int                      `json:"modelID"`
+	Buffers     framework.InitialBuffers `json:"buffers"`              // set the initial buffers
+	Frameworks  []string                 `json:"frameworks,omitempty"` // list of frameworks to run on (if empty, "all")
+	IncludeCode bool                     `json:"includeCode"`          // include generated code in the result
+	Repeat      int                      `json:"repeat,omitempty"`     // number of times to run the model on each framework (default 1)
+	Seed        *uint32                  `json:"seed,omitempty"`       // random seed (overrides random_seed in the amod)
+	DumpState   bool                     `json:"dumpState,omitempty"`  // include the buffers & memory at the end of each run
+	RunID       string                   `json:"runID,omitempty"`      // ID to look up the position in the run queue while waiting (see /api/queue/)
+}
+
+type sessionRunResponse struct {
+	Results frameworkRunResultMap `json:"results"`
+}
+
+type endSessionRequest struct {
+	SessionID int `json:"sessionID"`
+}
+
+type endSessionResponse struct {
+}
+
 func initSessions(w *Web) {
-	http.HandleFunc("/api/session/begin", w.beginSessionHandler)
-	http.HandleFunc("/api/session/runModel", w.runModelSessionHandler)
-	http.HandleFunc("/api/session/end", w.endSessionHandler)
+	w.handleFunc("/api/session/begin", w.beginSessionHandler)
+	w.handleFunc("/api/session/runModel", w.runModelSessionHandler)
+	w.handleFunc("/api/session/end", w.endSessionHandler)
 }
 
 func (w *Web) beginSessionHandler(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		SessionID int `json:"session_id"`
-	}
+	session := w.newSession(requestUser(req))
 
-	session := w.newSession()
-
-	encodeResponse(rw, response{
+	encodeResponse(rw, beginSessionResponse{
 		SessionID: session.id,
 	})
 }
 
 func (w *Web) runModelSessionHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		SessionID   int                      `json:"sessionID"`
-		ModelID     int                      `json:"modelID"`
-		Buffers     framework.InitialBuffers `json:"buffers"`              // set the initial buffers
-		Frameworks  []string                 `json:"frameworks,omitempty"` // list of frameworks to run on (if empty, "all")
-		IncludeCode bool                     `json:"includeCode"`          // include generated code in the result
-	}
-	type response struct {
-		Results json.RawMessage `json:"results"`
-	}
-
-	var data request
+	var data sessionRunRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	session := w.lookupSession(data.SessionID)
+	user := requestUser(req)
+
+	session := w.lookupSession(user, data.SessionID)
 	if session == nil {
 		err := fmt.Errorf("invalid session id '%d'", data.SessionID)
 		encodeErrorResponse(rw, err)
@@ -74,7 +88,11 @@ func (w *Web) runModelSessionHandler(rw http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	resultMap := w.runModel(model.actrModel, data.Buffers, data.Frameworks)
+	resultMap, err := w.runModel(user, model.actrModel, data.Buffers, data.Frameworks, runOptions{repeat: data.Repeat, seed: data.Seed, dumpState: data.DumpState, runID: data.RunID})
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
 
 	for key := range resultMap {
 		result := resultMap[key]
@@ -90,38 +108,26 @@ func (w *Web) runModelSessionHandler(rw http.ResponseWriter, req *http.Request)
 		resultMap[key] = result
 	}
 
-	results, err := json.Marshal(resultMap)
-	if err != nil {
-		encodeErrorResponse(rw, err)
-		return
-	}
-
-	encodeResponse(rw, response{
-		Results: json.RawMessage(string(results)),
+	encodeResponse(rw, sessionRunResponse{
+		Results: resultMap,
 	})
 }
 
 func (w *Web) endSessionHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		SessionID int `json:"sessionID"`
-	}
-	type response struct {
-	}
-
-	var data request
+	var data endSessionRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	err = w.endSession(data.SessionID)
+	err = w.endSession(requestUser(req), data.SessionID)
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	encodeResponse(rw, response{})
+	encodeResponse(rw, endSessionResponse{})
 }
 
 func (s *Session) addModel(model *Model) {
@@ -142,9 +148,10 @@ func (s *Session) end() {
 	s.models = []*Model{}
 }
 
-func (w *Web) newSession() *Session {
+func (w *Web) newSession(owner string) *Session {
 	session := &Session{
-		id: w.currentSessionID,
+		id:    w.currentSessionID,
+		owner: owner,
 	}
 	w.currentSessionID++
 
@@ -153,9 +160,10 @@ func (w *Web) newSession() *Session {
 	return session
 }
 
-func (w *Web) endSession(id int) error {
+// endSession ends the session with the given id if it is owned by "owner".
+func (w *Web) endSession(owner string, id int) error {
 	for index, session := range w.sessionList {
-		if session.id == id {
+		if session.id == id && session.owner == owner {
 			session.end()
 			w.sessionList = removeSession(w.sessionList, index)
 			return nil
@@ -169,9 +177,11 @@ func (w Web) hasSessions() bool {
 	return len(w.sessionList) > 0
 }
 
-func (w Web) lookupSession(id int) *Session {
+// lookupSession returns the session with the given id if it is owned by "owner".
+// Sessions owned by other users are not visible.
+func (w Web) lookupSession(owner string, id int) *Session {
 	for _, session := range w.sessionList {
-		if session.id == id {
+		if session.id == id && session.owner == owner {
 			return session
 		}
 	}
@@ -10,7 +10,7 @@ import (
 )
 
 func TestNewSession(t *testing.T) {
-	session := webTest.newSession()
+	session := webTest.newSession("")
 
 	if session == nil {
 		t.Errorf("Could not create session")
@@ -20,13 +20,13 @@ func TestNewSession(t *testing.T) {
 }
 
 func TestEndSession(t *testing.T) {
-	session := webTest.newSession()
+	session := webTest.newSession("")
 
 	if session == nil {
 		t.Fatalf("Could not create session")
 	}
 
-	err := webTest.endSession(session.id)
+	err := webTest.endSession("", session.id)
 
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
@@ -64,7 +64,7 @@ func TestBeginSessionHandler(t *testing.T) {
 }
 
 func TestEndSessionHandler(t *testing.T) {
-	session := webTest.newSession()
+	session := webTest.newSession("")
 
 	data := []byte(fmt.Sprintf(`{"sessionID":%d}`, session.id))
 
@@ -98,7 +98,7 @@ func TestEndSessionHandler(t *testing.T) {
 // Commented out for now since the CI does not install any frameworks.
 
 // func TestRunModelSessionHandler(t *testing.T) {
-// 	session := webTest.newSession()
+// 	session := webTest.newSession("")
 
 // 	src := `==model==
 // 	name: Test
@@ -150,7 +150,7 @@ func TestEndSessionHandler(t *testing.T) {
 // 		}
 // 	}`
 
-// 	model, err := webTest.loadModel(session.id, src)
+// 	model, err := webTest.loadModel("", session.id, src)
 // 	if err != nil {
 // 		t.Errorf("Unexpected error: %s", err.Error())
 // 		return
@@ -180,7 +180,7 @@ func TestEndSessionHandler(t *testing.T) {
 // 			expected, responseStr)
 // 	}
 
-// 	webTest.endSession(session.id)
+// 	webTest.endSession("", session.id)
 
 // 	if webTest.hasSessions() {
 // 		t.Errorf("Did not remove session from list")
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asmaloney/gactar/amod"
+	"github.com/asmaloney/gactar/framework"
+	"github.com/asmaloney/gactar/sweep"
+
+	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/numbers"
+	"github.com/asmaloney/gactar/util/runstats"
+	"github.com/asmaloney/gactar/util/validate"
+)
+
+// sweepParam is a parameter to sweep. Either "values" or "from", "to", & "step" must be set.
+type sweepParam struct {
+	Name   string    `json:"name"`             // module & parameter name (e.g. "memory.latency_factor")
+	Values []float64 `json:"values,omitempty"` // list of values to use
+	From   *float64  `json:"from,omitempty"`   // first value of a range
+	To     *float64  `json:"to,omitempty"`     // last value of a range (inclusive)
+	Step   *float64  `json:"step,omitempty"`   // step between values in a range
+}
+
+type sweepRequest struct {
+	AMODFile   string       `json:"amod"`                 // text of an amod file
+	Goal       string       `json:"goal"`                 // initial goal
+	Frameworks []string     `json:"frameworks,omitempty"` // list of frameworks to run on (if empty, "all")
+	Params     []sweepParam `json:"params"`               // parameters to sweep
+	Repeat     int          `json:"repeat,omitempty"`     // number of times to run each combination on each framework (default 1)
+	Seed       *uint32      `json:"seed,omitempty"`       // random seed (overrides random_seed in the amod)
+	Format     string       `json:"format,omitempty"`     // "json" (default) or "csv"
+	RunID      string       `json:"runID,omitempty"`      // ID to look up the position in the run queue while waiting (see /api/queue/)
+}
+
+// sweepResult is the stats for one combination of parameter values on one framework.
+type sweepResult struct {
+	Values    map[string]string `json:"values"` // value of each parameter
+	Framework string            `json:"framework"`
+	Stats     *runstats.Stats   `json:"stats"`
+	Errors    []string          `json:"errors,omitempty"` // errors from the runs which failed
+}
+
+type sweepResponse struct {
+	Issues  issues.IssueList `json:"issues,omitempty"`
+	Results []sweepResult    `json:"results,omitempty"`
+}
+
+func initSweep(w *Web) {
+	w.handleFunc("/api/sweep", w.sweepHandler)
+}
+
+// sweepHandler runs a model using each combination of parameter values on each framework and
+// returns the stats for each as JSON or as a CSV file.
+func (w *Web) sweepHandler(rw http.ResponseWriter, req *http.Request) {
+	var data sweepRequest
+	err := decodeBody(req, &data)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	if data.Format != "" && data.Format != "json" && data.Format != "csv" {
+		encodeErrorResponse(rw, fmt.Errorf("invalid format %q - must be 'json' or 'csv'", data.Format))
+		return
+	}
+
+	if data.Repeat > maxRepeat {
+		encodeErrorResponse(rw, fmt.Errorf("repeat must be at most %d", maxRepeat))
+		return
+	}
+
+	data.Frameworks = w.normalizeFrameworkList(data.Frameworks)
+
+	err = w.verifyFrameworkList(data.Frameworks)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	model, log, err := amod.GenerateModel(data.AMODFile)
+	if err != nil {
+		encodeIssueResponse(rw, log)
+		return
+	}
+
+	initialGoal := strings.TrimSpace(data.Goal)
+
+	validate.Goal(model, initialGoal, log)
+
+	s := sweep.Sweep{
+		Source:         data.AMODFile,
+		InitialBuffers: framework.InitialBuffers{"goal": initialGoal},
+		Repeat:         data.Repeat,
+		Seed:           data.Seed,
+	}
+
+	for _, param := range data.Params {
+		var sweepParam sweep.Param
+		sweepParam, err = param.toSweepParam()
+		if err != nil {
+			encodeErrorResponse(rw, err)
+			return
+		}
+
+		s.Params = append(s.Params, sweepParam)
+	}
+
+	results, err := w.runSweep(requestUser(req), s, data.Frameworks, data.RunID)
+	if err != nil {
+		encodeErrorResponse(rw, err)
+		return
+	}
+
+	if data.Format == "csv" {
+		rw.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		sweep.WriteCSV(rw, s.Params, results)
+		return
+	}
+
+	response := sweepResponse{
+		Issues: log.AllIssues(),
+	}
+
+	for _, result := range results {
+		values := make(map[string]string, len(s.Params))
+		for i, param := range s.Params {
+			values[param.Name] = result.Values[i]
+		}
+
+		response.Results = append(response.Results, sweepResult{
+			Values:    values,
+			Framework: result.Framework,
+			Stats:     result.Stats,
+			Errors:    result.Errors,
+		})
+	}
+
+	encodeResponse(rw, response)
+}
+
+// toSweepParam converts the values or range to the values to sweep.
+func (p sweepParam) toSweepParam() (param sweep.Param, err error) {
+	param.Name = p.Name
+
+	if !strings.Contains(p.Name, ".") {
+		err = fmt.Errorf("parameter %q should be in the form 'module.param' (e.g. memory.latency_factor)", p.Name)
+		return
+	}
+
+	if len(p.Values) > 0 {
+		for _, value := range p.Values {
+			param.Values = append(param.Values, numbers.Float64Str(value))
+		}
+		return
+	}
+
+	if p.From == nil || p.To == nil || p.Step == nil {
+		err = fmt.Errorf("parameter %q requires either 'values' or 'from', 'to', and 'step'", p.Name)
+		return
+	}
+
+	param.Values, err = sweep.Range(*p.From, *p.To, *p.Step)
+	if err != nil {
+		err = fmt.Errorf("parameter %q: %w", p.Name, err)
+	}
+
+	return
+}
+
+// runSweep runs each task in the sweep using the run queue and collects the results.
+func (w *Web) runSweep(user string, s sweep.Sweep, frameworkNames []string, runID string) (results []sweep.Result, err error) {
+	// Check the size first since generating the models for a large sweep is a lot of work.
+	numTasks, err := s.NumTasks(len(frameworkNames))
+	if err != nil {
+		return
+	}
+
+	if numTasks > w.queue.capacity() {
+		err = fmt.Errorf("too many runs requested (%d) - this server can accept at most %d at once", numTasks, w.queue.capacity())
+		return
+	}
+
+	points, err := s.Points()
+	if err != nil {
+		return
+	}
+
+	userPath, err := w.createUserTempDir(user)
+	if err != nil {
+		return
+	}
+
+	dir, err := os.MkdirTemp(userPath, "sweep-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	tasks := s.Tasks(points, frameworkNames, dir)
+
+	// Reserve our place in the queue before starting so we can reject the request if we are too busy.
+	key := queueKey(user, runID)
+
+	tickets, err := w.queue.enqueue(len(tasks), key)
+	if err != nil {
+		return
+	}
+	defer w.queue.untrack(key)
+
+	var wg sync.WaitGroup
+
+	for i, task := range tasks {
+		wg.Add(1)
+
+		go func(task *sweep.Task, ticket *queueTicket) {
+			defer wg.Done()
+
+			ticket.wait()
+			defer w.queue.done()
+
+			start := time.Now()
+
+			task.Execute(w.actrFrameworks[task.Framework])
+
+			w.metrics.recordRun(task.Framework, time.Since(start), task.Err != nil)
+		}(task, tickets[i])
+	}
+	wg.Wait()
+
+	return sweep.Collect(points, tasks), nil
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/asmaloney/gactar/sweep"
+)
+
+func TestSweepParam(t *testing.T) {
+	from, to, step := 0.1, 0.3, 0.1
+
+	param, err := sweepParam{Name: "memory.latency_factor", From: &from, To: &to, Step: &step}.toSweepParam()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(param.Values, []string{"0.1", "0.2", "0.3"}) {
+		t.Errorf("Unexpected values from range: %v", param.Values)
+	}
+
+	param, err = sweepParam{Name: "memory.retrieval_threshold", Values: []float64{-1, 0.5}}.toSweepParam()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(param.Values, []string{"-1", "0.5"}) {
+		t.Errorf("Unexpected values: %v", param.Values)
+	}
+
+	_, err = sweepParam{Name: "memory.latency_factor", From: &from}.toSweepParam()
+	if err == nil {
+		t.Errorf("Expected error for incomplete range")
+	}
+
+	_, err = sweepParam{Name: "latency_factor", Values: []float64{1}}.toSweepParam()
+	if err == nil {
+		t.Errorf("Expected error for parameter without a module")
+	}
+}
+
+func TestSweepHandlerInvalidFormat(t *testing.T) {
+	data := []byte(`{"amod":"", "format":"xml"}`)
+
+	request, err := http.NewRequest("POST", "/api/sweep", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(webTest.sweepHandler)
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	expected := `invalid format \"xml\"`
+	if !strings.Contains(responseRecorder.Body.String(), expected) {
+		t.Errorf("handler returned unexpected body: %s", responseRecorder.Body.String())
+	}
+}
+
+func TestRunSweepTooManyRuns(t *testing.T) {
+	values := make([]string, webTest.queue.capacity()+1)
+	for i := range values {
+		values[i] = fmt.Sprint(i)
+	}
+
+	// The source is invalid, so this fails on the size before generating any models.
+	s := sweep.Sweep{Params: []sweep.Param{{Name: "memory.latency_factor", Values: values}}}
+
+	_, err := webTest.runSweep("", s, []string{"ccm"}, "")
+	if err == nil || !strings.Contains(err.Error(), "too many runs requested") {
+		t.Errorf("expected too many runs error, got %v", err)
+	}
+}
@@ -4,14 +4,18 @@ package web
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
@@ -21,7 +25,9 @@ import (
 
 	"github.com/asmaloney/gactar/util/clicontext"
 	"github.com/asmaloney/gactar/util/container"
+	"github.com/asmaloney/gactar/util/filesystem"
 	"github.com/asmaloney/gactar/util/issues"
+	"github.com/asmaloney/gactar/util/runstats"
 	"github.com/asmaloney/gactar/util/validate"
 	"github.com/asmaloney/gactar/util/version"
 )
@@ -44,6 +50,16 @@ type Web struct {
 
 	sessionList      SessionList
 	currentSessionID int
+
+	queue   *runQueue // limits the number of framework processes run at once
+	metrics *metrics  // run counts & durations for "/metrics"
+
+	initErrors map[string]string // frameworks which failed to initialize and why
+
+	auth *authenticator // nil unless the server was started with "--auth-tokens"
+
+	mux    *http.ServeMux // handlers for all our routes
+	routes []string       // API routes registered using handleFunc() & handlePublicFunc()
 }
 
 type frameworkRunResult struct {
@@ -54,17 +70,67 @@ type frameworkRunResult struct {
 	Code     *string `json:"code,omitempty"`     // actual code which was run
 	Output   *string `json:"output,omitempty"`   // output of run (stdout + stderr)
 
+	QueuePosition *int `json:"queuePosition,omitempty"` // position in the run queue if the run had to wait
+
+	Trace    *framework.Trace   `json:"trace,omitempty"`    // summary of the run parsed from the output
+	Measures framework.Measures `json:"measures,omitempty"` // values of the model's measures
+	State    *framework.State   `json:"state,omitempty"`    // buffers & memory at the end (if dumpState was set)
+	Seed     *uint32            `json:"seed,omitempty"`     // random seed the model was run with
+
+	// When using "repeat", the fields above are from the first run.
+	Stats *runstats.Stats `json:"stats,omitempty"` // stats for all the runs
+	Runs  []repeatedRun   `json:"runs,omitempty"`  // result of each run
+
 	SessionID *int `json:"sessionID,omitempty"`
 	ModelID   *int `json:"modelID,omitempty"`
 }
 
 type frameworkRunResultMap map[string]frameworkRunResult
 
+// repeatedRun is the result of one run when using "repeat".
+type repeatedRun struct {
+	Issues   *issues.IssueList  `json:"issues,omitempty"`
+	Output   *string            `json:"output,omitempty"`
+	Trace    *framework.Trace   `json:"trace,omitempty"` // nil if the run failed
+	Measures framework.Measures `json:"measures,omitempty"`
+	State    *framework.State   `json:"state,omitempty"`
+	Seed     *uint32            `json:"seed,omitempty"`
+}
+
 type runResult struct {
 	Issues  issues.IssueList      `json:"issues,omitempty"`
 	Results frameworkRunResultMap `json:"results,omitempty"`
 }
 
+type versionResponse struct {
+	Version string `json:"version"` // current version tag when gactar was built
+}
+
+type frameworksResponse struct {
+	Frameworks framework.InfoList `json:"frameworks"`
+}
+
+type runRequest struct {
+	AMODFile   string   `json:"amod"`                 // text of an amod file
+	Goal       string   `json:"goal"`                 // initial goal
+	Frameworks []string `json:"frameworks,omitempty"` // list of frameworks to run on (if empty, "all")
+	Repeat     int      `json:"repeat,omitempty"`     // number of times to run the model on each framework (default 1)
+	Seed       *uint32  `json:"seed,omitempty"`       // random seed (overrides random_seed in the amod)
+	DumpState  bool     `json:"dumpState,omitempty"`  // include the buffers & memory at the end of each run
+	RunID      string   `json:"runID,omitempty"`      // ID to look up the position in the run queue while waiting (see /api/queue/)
+}
+
+// runOptions are the options common to all run requests.
+type runOptions struct {
+	repeat    int     // number of times to run the model on each framework
+	seed      *uint32 // if set, overrides the model's random seed
+	dumpState bool    // include the buffers & memory at the end of each run
+	runID     string  // if set, the position in the run queue may be looked up using it
+}
+
+// maxRepeat is the most times a request may run a model on each framework.
+const maxRepeat = 100
+
 func Initialize(cli *cli.Context, frameworks framework.List, examples *embed.FS) (w *Web, err error) {
 	w = &Web{
 		context:          cli,
@@ -73,12 +139,17 @@ func Initialize(cli *cli.Context, frameworks framework.List, examples *embed.FS)
 		port:             cli.Int("port"),
 		sessionList:      SessionList{},
 		currentSessionID: 1,
+		queue:            newRunQueue(cli.Int("max-runs"), cli.Int("queue-size")),
+		metrics:          newMetrics(),
+		initErrors:       map[string]string{},
+		mux:              http.NewServeMux(),
 	}
 
 	for name, f := range w.actrFrameworks {
-		err = f.Initialize()
+		err = f.Initialize(os.Stdout)
 		if err != nil {
 			fmt.Println(err.Error())
+			w.initErrors[name] = err.Error()
 			delete(w.actrFrameworks, name)
 			err = nil
 		}
@@ -89,28 +160,54 @@ func Initialize(cli *cli.Context, frameworks framework.List, examples *embed.FS)
 		return w, err
 	}
 
-	http.HandleFunc("/api/version", w.getVersionHandler)
-	http.HandleFunc("/api/frameworks", w.getFrameworksHandler)
-	http.HandleFunc("/api/run", w.runModelHandler)
-	http.HandleFunc("/api/", http.NotFound)
+	if tokensFile := cli.Path("auth-tokens"); tokensFile != "" {
+		w.auth, err = loadAuthTokens(tokensFile, cli.Int64("max-body-size"))
+		if err != nil {
+			return
+		}
+
+		fmt.Printf("Authentication enabled for %d users\n", len(w.auth.users))
+	}
+
+	w.registerRoutes()
+
+	return
+}
 
-	if examples != nil {
+// registerRoutes adds the handlers for all our routes. TestRoutesInOpenAPISpec checks that each
+// API route is described in apiEndpoints.
+func (w *Web) registerRoutes() {
+	w.handleFunc("/api/version", w.getVersionHandler)
+	w.handleFunc("/api/frameworks", w.getFrameworksHandler)
+	w.handleFunc("/api/run", w.runModelHandler)
+	w.handleFunc("/api/openapi.json", w.getOpenAPIHandler)
+	w.handleFunc("/api/status", w.getStatusHandler)
+	w.handleFunc("/api/queue/", w.getQueuePositionHandler)
+
+	// These are used by process supervisors & monitoring, so they don't require authentication.
+	w.handlePublicFunc("/api/health", w.getHealthHandler)
+	w.handlePublicFunc("/metrics", w.getMetricsHandler)
+
+	if w.examples != nil {
 		initExamples(w)
 	}
 
 	initSessions(w)
 	initModels(w)
+	initArchive(w)
+	initSweep(w)
 
-	mainHandler := assetHandler(&mainAssets, "", "build")
-	http.HandleFunc("/", mainHandler.ServeHTTP)
+	// Anything else under /api/ is not found rather than being served by the web interface.
+	w.mux.HandleFunc("/api/", w.auth.wrap(http.NotFound))
 
-	return
+	mainHandler := assetHandler(&mainAssets, "", "build")
+	w.mux.Handle("/", mainHandler)
 }
 
 func (w Web) Start() (err error) {
 	fmt.Printf("Serving gactar on http://localhost:%d\n", w.port)
 
-	err = http.ListenAndServe(fmt.Sprintf(":%d", w.port), nil)
+	err = http.ListenAndServe(fmt.Sprintf(":%d", w.port), w.mux)
 	if err != nil {
 		return
 	}
@@ -119,20 +216,12 @@ func (w Web) Start() (err error) {
 }
 
 func (Web) getVersionHandler(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		Version string `json:"version"`
-	}
-
-	encodeResponse(rw, response{
+	encodeResponse(rw, versionResponse{
 		Version: version.BuildVersion,
 	})
 }
 
 func (w Web) getFrameworksHandler(rw http.ResponseWriter, req *http.Request) {
-	type response struct {
-		Frameworks framework.InfoList `json:"frameworks"`
-	}
-
 	frameworks := framework.InfoList{}
 
 	for _, framework := range w.actrFrameworks {
@@ -144,23 +233,13 @@ func (w Web) getFrameworksHandler(rw http.ResponseWriter, req *http.Request) {
 		return frameworks[i].Name < frameworks[j].Name
 	})
 
-	encodeResponse(rw, response{
+	encodeResponse(rw, frameworksResponse{
 		Frameworks: frameworks,
 	})
 }
 
 func (w Web) runModelHandler(rw http.ResponseWriter, req *http.Request) {
-	type request struct {
-		AMODFile   string   `json:"amod"`                 // text of an amod file
-		Goal       string   `json:"goal"`                 // initial goal
-		Frameworks []string `json:"frameworks,omitempty"` // list of frameworks to run on (if empty, "all")
-	}
-
-	type response struct {
-		Results json.RawMessage `json:"results"`
-	}
-
-	var data request
+	var data runRequest
 	err := decodeBody(req, &data)
 	if err != nil {
 		encodeErrorResponse(rw, err)
@@ -188,20 +267,16 @@ func (w Web) runModelHandler(rw http.ResponseWriter, req *http.Request) {
 
 	validate.Goal(model, initialGoal, log)
 
-	resultMap := w.runModel(model, initialBuffers, data.Frameworks)
-
-	rr := runResult{
-		Issues:  log.AllIssues(),
-		Results: resultMap,
-	}
-
-	results, err := json.Marshal(rr)
+	resultMap, err := w.runModel(requestUser(req), model, initialBuffers, data.Frameworks, runOptions{repeat: data.Repeat, seed: data.Seed, dumpState: data.DumpState, runID: data.RunID})
 	if err != nil {
 		encodeErrorResponse(rw, err)
 		return
 	}
 
-	encodeResponse(rw, json.RawMessage(string(results)))
+	encodeResponse(rw, runResult{
+		Issues:  log.AllIssues(),
+		Results: resultMap,
+	})
 }
 
 // normalizeFrameworkList will look for "all" and replace it with all available
@@ -236,79 +311,203 @@ func (w Web) verifyFrameworkList(list []string) (err error) {
 	return
 }
 
-func (w Web) runModel(model *actr.Model, initialBuffers framework.InitialBuffers, frameworkNames []string) (resultMap frameworkRunResultMap) {
-	// ensure temp dir exists
-	// https://github.com/asmaloney/gactar/issues/103
-	clicontext.CreateTempDir(w.context)
+// runModel runs the model on each framework "repeat" times. When repeating, the runs for each
+// framework are aggregated into stats and each run gets its own seed derived from the base seed.
+func (w Web) runModel(user string, model *actr.Model, initialBuffers framework.InitialBuffers, frameworkNames []string, options runOptions) (resultMap frameworkRunResultMap, err error) {
+	repeat := options.repeat
+	if repeat < 1 {
+		repeat = 1
+	}
 
-	resultMap = make(frameworkRunResultMap, len(frameworkNames))
+	if repeat > maxRepeat {
+		err = fmt.Errorf("repeat must be at most %d", maxRepeat)
+		return
+	}
+
+	numRuns := len(frameworkNames) * repeat
+	if numRuns > w.queue.capacity() {
+		err = fmt.Errorf("too many runs requested (%d) - this server can accept at most %d at once", numRuns, w.queue.capacity())
+		return
+	}
+
+	tmpPath, err := w.createUserTempDir(user)
+	if err != nil {
+		return
+	}
+
+	// Reserve our place in the queue before starting so we can reject the request if we are too busy.
+	key := queueKey(user, options.runID)
+
+	tickets, err := w.queue.enqueue(numRuns, key)
+	if err != nil {
+		return
+	}
+	defer w.queue.untrack(key)
+
+	// results of each run of each framework
+	runs := make(map[string][]frameworkRunResult, len(frameworkNames))
+	for _, name := range frameworkNames {
+		runs[name] = make([]frameworkRunResult, repeat)
+	}
+
+	baseSeed := options.seed
+	if baseSeed == nil && model != nil {
+		baseSeed = model.RandomSeed
+	}
 
 	var wg sync.WaitGroup
 	var mutex = &sync.Mutex{}
 
-	for _, name := range frameworkNames {
-		f := w.actrFrameworks[name]
+	for i, name := range frameworkNames {
+		for run := 0; run < repeat; run++ {
+			// Repeated runs each get their own directory so they may be run at the same time.
+			runPath := tmpPath
+			if repeat > 1 {
+				runPath = filepath.Join(tmpPath, fmt.Sprintf("%s-run-%d", name, run+1))
+			}
+
+			runModel := model
+			if baseSeed != nil && model != nil {
+				runModel = model.WithRandomSeed(actr.DeriveSeed(*baseSeed, run+1))
+			}
+			if options.dumpState && runModel != nil {
+				runModel = runModel.WithDumpState()
+			}
 
-		wg.Add(1)
+			// Use a new instance of the framework for each run so concurrent runs don't share state.
+			f := w.actrFrameworks[name].Clone(runPath)
 
-		go func(wg *sync.WaitGroup, name string, f framework.Framework) {
-			defer wg.Done()
+			wg.Add(1)
 
-			result := &framework.RunResult{}
+			go func(wg *sync.WaitGroup, name string, run int, runPath string, model *actr.Model, f framework.Framework, ticket *queueTicket) {
+				defer wg.Done()
 
-			log := f.ValidateModel(model)
-			if !log.HasError() {
-				r, err := runModelOnFramework(model, initialBuffers, f)
-				if err != nil {
-					log.Error(nil, err.Error())
-				}
-				if r != nil {
-					result = r
+				ticket.wait()
+				defer w.queue.done()
+
+				start := time.Now()
+				result := &framework.RunResult{}
+
+				log := f.ValidateModel(model)
+				if !log.HasError() {
+					r, err := runModelOnFramework(model, initialBuffers, f, runPath)
+					if err != nil {
+						log.Error(nil, err.Error())
+					}
+					if r != nil {
+						result = r
+					}
 				}
-			}
 
-			frameworkResult := frameworkRunResult{
-				ModelName: model.Name,
-			}
+				w.metrics.recordRun(name, time.Since(start), log.HasError())
 
-			mutex.Lock()
+				frameworkResult := frameworkRunResult{
+					ModelName: model.Name,
+					Trace:     result.Trace,
+					Measures:  result.Measures,
+					State:     result.State,
+					Seed:      model.RandomSeed,
+				}
 
-			if log.HasIssues() {
-				all := log.AllIssues()
-				frameworkResult.Issues = &all
-			}
+				if log.HasIssues() {
+					all := log.AllIssues()
+					frameworkResult.Issues = &all
+				}
 
-			if result.FileName != "" {
-				frameworkResult.FilePath = &result.FileName
-			}
+				if result.FileName != "" {
+					frameworkResult.FilePath = &result.FileName
+				}
 
-			if len(result.GeneratedCode) > 0 {
-				codeStr := string(result.GeneratedCode)
-				frameworkResult.Code = &codeStr
+				if len(result.GeneratedCode) > 0 {
+					codeStr := string(result.GeneratedCode)
+					frameworkResult.Code = &codeStr
 
-			}
-			if len(result.Output) > 0 {
-				outputStr := string(result.Output)
-				frameworkResult.Output = &outputStr
+				}
+				if len(result.Output) > 0 {
+					outputStr := string(result.Output)
+					frameworkResult.Output = &outputStr
 
-			}
+				}
 
-			resultMap[name] = frameworkResult
+				if ticket.position > 0 {
+					frameworkResult.QueuePosition = &ticket.position
+				}
 
-			mutex.Unlock()
-		}(&wg, name, f)
+				mutex.Lock()
+				runs[name][run] = frameworkResult
+				mutex.Unlock()
+			}(&wg, name, run, runPath, runModel, f, tickets[i*repeat+run])
+		}
 	}
 	wg.Wait()
 
+	resultMap = make(frameworkRunResultMap, len(frameworkNames))
+
+	for name, results := range runs {
+		resultMap[name] = combineRuns(results)
+	}
+
+	return
+}
+
+// combineRuns returns the first run with the stats & the result of each run added if there was
+// more than one.
+func combineRuns(results []frameworkRunResult) (combined frameworkRunResult) {
+	combined = results[0]
+	if len(results) == 1 {
+		return
+	}
+
+	traces := make([]*framework.Trace, len(results))
+	combined.Runs = make([]repeatedRun, len(results))
+
+	for i, result := range results {
+		traces[i] = result.Trace
+
+		combined.Runs[i] = repeatedRun{
+			Issues:   result.Issues,
+			Output:   result.Output,
+			Trace:    result.Trace,
+			Measures: result.Measures,
+			State:    result.State,
+			Seed:     result.Seed,
+		}
+	}
+
+	combined.Stats = runstats.Compute(traces)
+
+	return
+}
+
+// createUserTempDir ensures the temp dir exists and returns the path to use for this user's files.
+// If authentication is off, user is empty and we use the temp dir itself.
+func (w Web) createUserTempDir(user string) (path string, err error) {
+	// ensure temp dir exists
+	// https://github.com/asmaloney/gactar/issues/103
+	clicontext.CreateTempDir(w.context)
+
+	path = w.context.Path("temp")
+	if user == "" {
+		return
+	}
+
+	path = filepath.Join(path, user)
+
+	err = filesystem.CreateDir(path)
 	return
 }
 
-func runModelOnFramework(model *actr.Model, initialBuffers framework.InitialBuffers, f framework.Framework) (result *framework.RunResult, err error) {
+func runModelOnFramework(model *actr.Model, initialBuffers framework.InitialBuffers, f framework.Framework, path string) (result *framework.RunResult, err error) {
 	if model == nil {
 		err = fmt.Errorf("no model loaded")
 		return
 	}
 
+	err = filesystem.CreateDir(path)
+	if err != nil {
+		return
+	}
+
 	err = f.SetModel(model)
 	if err != nil {
 		return
@@ -343,7 +542,27 @@ func encodeResponse(rw http.ResponseWriter, v interface{}) {
 	json.NewEncoder(rw).Encode(v)
 }
 
+// encodeErrorResponse returns the error as a runResult containing only the issue. If the run queue
+// is full, the status is 503 so clients can tell they should try again later.
 func encodeErrorResponse(rw http.ResponseWriter, err error) {
+	status := http.StatusOK
+
+	var fullErr queueFullError
+	if errors.As(err, &fullErr) {
+		rw.Header().Set("Retry-After", strconv.Itoa(queueFullRetryAfter))
+		status = http.StatusServiceUnavailable
+	}
+
+	encodeErrorStatus(rw, status, err)
+}
+
+// encodeErrorStatus returns the error as a runResult containing only the issue with the given
+// status code. The headers must be set before the status is written, so callers shouldn't call
+// WriteHeader() themselves.
+func encodeErrorStatus(rw http.ResponseWriter, status int, err error) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(status)
+
 	errResponse := runResult{
 		Issues: issues.IssueList{
 			{
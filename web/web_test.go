@@ -40,3 +40,49 @@ func TestMain(m *testing.M) {
 
 	os.Exit(exitVal)
 }
+
+func TestRunModelRepeatLimits(t *testing.T) {
+	_, err := webTest.runModel("", nil, nil, []string{"ccm"}, runOptions{repeat: maxRepeat + 1})
+	if err == nil {
+		t.Errorf("Expected error when repeat is more than %d", maxRepeat)
+	}
+
+	tooMany := webTest.queue.capacity() + 1
+	if tooMany > maxRepeat {
+		t.Skipf("queue capacity (%d) is more than maxRepeat", tooMany-1)
+	}
+
+	_, err = webTest.runModel("", nil, nil, []string{"ccm"}, runOptions{repeat: tooMany})
+	if err == nil {
+		t.Errorf("Expected error when there are more runs than the queue can hold")
+	}
+}
+
+func TestCombineRuns(t *testing.T) {
+	trace := framework.NewTrace()
+	trace.ProductionFired("start", 0.05)
+
+	results := []frameworkRunResult{
+		{ModelName: "test", Trace: trace},
+		{ModelName: "test"}, // failed
+	}
+
+	combined := combineRuns(results)
+
+	if combined.Trace != trace {
+		t.Errorf("Expected the first run's trace")
+	}
+
+	if len(combined.Runs) != 2 {
+		t.Fatalf("Expected 2 runs, got %d", len(combined.Runs))
+	}
+
+	if combined.Stats == nil || combined.Stats.Runs != 1 || combined.Stats.Failures != 1 {
+		t.Errorf("Unexpected stats: %+v", combined.Stats)
+	}
+
+	single := combineRuns(results[:1])
+	if single.Stats != nil || single.Runs != nil {
+		t.Errorf("Expected no stats or runs for a single run")
+	}
+}